@@ -0,0 +1,142 @@
+package dbfly
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const defaultSchemaVersionTableName = "DBFLY_SCHEMA_VERSION"
+
+const (
+	SCHEMA_COLUMN_TABLE_NAME = "TABLE_NAME"
+	SCHEMA_COLUMN_VERSION    = "SCHEMA_VERSION"
+	SCHEMA_COLUMN_UPDATED_AT = "UPDATED_AT"
+)
+
+// tableSchema 变更记录表、锁表等内部表的结构版本
+type tableSchema struct {
+	version int
+	// columns 版本 1 之后新增的列
+	columns []schemaColumn
+}
+
+// schemaColumn 在 since 版本新增的列
+type schemaColumn struct {
+	since  int
+	column *AddColumnColumnNode
+}
+
+// changeLogSchema 变更记录表结构，版本 2 增加执行状态、校验和、耗时、部署标识等列
+var changeLogSchema = tableSchema{
+	version: 2,
+	columns: []schemaColumn{
+		{2, &AddColumnColumnNode{ColumnName: COLUMN_DESCRIPTION, DataType: Varchar, MaxLength: 255}},
+		{2, &AddColumnColumnNode{ColumnName: COLUMN_EXEC_TYPE, DataType: Varchar, MaxLength: 20}},
+		{2, &AddColumnColumnNode{ColumnName: COLUMN_CHECKSUM, DataType: Varchar, MaxLength: 64}},
+		{2, &AddColumnColumnNode{ColumnName: COLUMN_DURATION_MS, DataType: Bigint}},
+		{2, &AddColumnColumnNode{ColumnName: COLUMN_DEPLOYMENT_ID, DataType: Varchar, MaxLength: 64}},
+		{2, &AddColumnColumnNode{ColumnName: COLUMN_TAG, DataType: Varchar, MaxLength: 255}},
+		{2, &AddColumnColumnNode{ColumnName: COLUMN_CONTEXTS, DataType: Varchar, MaxLength: 255}},
+		{2, &AddColumnColumnNode{ColumnName: COLUMN_DBFLY_VERSION, DataType: Varchar, MaxLength: 32}},
+		{2, &AddColumnColumnNode{ColumnName: COLUMN_ERROR_MESSAGE, DataType: Text}},
+	},
+}
+
+// lockSchema 锁表结构
+var lockSchema = tableSchema{version: 1}
+
+// upgradeTable 将已存在的内部表升级到当前结构版本：记录的版本较低时通过 ExistsColumn 检查并补齐缺失的列，
+// 然后记录新版本，升级 dbfly 后无需手工执行 DDL
+func upgradeTable(ctx context.Context, fly *Dbfly, tableName string, schema tableSchema) error {
+	version, err := getSchemaVersion(ctx, fly, tableName)
+	if err != nil {
+		return err
+	}
+	if version >= schema.version {
+		return nil
+	}
+	migratory := fly.Migratory()
+	metaData := migratory.MetaData()
+	driver := fly.Driver()
+	for _, c := range schema.columns {
+		if c.since <= version {
+			continue
+		}
+		exists, _, _, err := metaData.ExistsColumn(ctx, driver, tableName, c.column.ColumnName)
+		if err != nil {
+			return Wrap(err, "check column %s.%s exists failed", tableName, c.column.ColumnName)
+		}
+		if exists {
+			continue
+		}
+		if err = migratory.AddColumn(ctx, driver, tableName, []*AddColumnColumnNode{c.column}, nil); err != nil {
+			// 可能是并发已添加，再次检查
+			if exists, _, _, reErr := metaData.ExistsColumn(ctx, driver, tableName, c.column.ColumnName); reErr != nil || !exists {
+				return Wrap(err, "add column %s.%s failed", tableName, c.column.ColumnName)
+			}
+		}
+		fly.logger.Info("column %s.%s added", tableName, c.column.ColumnName)
+	}
+	return setSchemaVersion(ctx, fly, tableName, schema.version)
+}
+
+// getSchemaVersion 查询内部表记录的结构版本，未记录时返回 0
+func getSchemaVersion(ctx context.Context, fly *Dbfly, tableName string) (int, error) {
+	if err := createSchemaVersionTable(ctx, fly); err != nil {
+		return 0, err
+	}
+	quoter := fly.Migratory().MetaData().Quoter()
+	version, err := doGetScalar[int](ctx, fly.Driver(), fmt.Sprintf("SELECT COALESCE(MAX(%s), 0) FROM %s WHERE %s = ?",
+		quoter.MustQuote(SCHEMA_COLUMN_VERSION),
+		quoter.MustQuote(fly.schemaVersionTableName),
+		quoter.MustQuote(SCHEMA_COLUMN_TABLE_NAME)), tableName)
+	if err != nil {
+		return 0, Wrap(err, "get schema version of %s failed", tableName)
+	}
+	return version, nil
+}
+
+// setSchemaVersion 追加一条版本记录，取最大值为当前版本，不依赖更新语句
+func setSchemaVersion(ctx context.Context, fly *Dbfly, tableName string, version int) error {
+	if err := createSchemaVersionTable(ctx, fly); err != nil {
+		return err
+	}
+	quoter := fly.Migratory().MetaData().Quoter()
+	if _, err := fly.Driver().Execute(ctx, fmt.Sprintf("INSERT INTO %s(%s, %s, %s) VALUES(?, ?, ?)",
+		quoter.MustQuote(fly.schemaVersionTableName),
+		quoter.MustQuote(SCHEMA_COLUMN_TABLE_NAME),
+		quoter.MustQuote(SCHEMA_COLUMN_VERSION),
+		quoter.MustQuote(SCHEMA_COLUMN_UPDATED_AT)), tableName, version, time.Now()); err != nil {
+		return Wrap(err, "set schema version of %s failed", tableName)
+	}
+	fly.logger.Debug("schema version of %s set to %d", tableName, version)
+	return nil
+}
+
+// createSchemaVersionTable 结构版本表不存在时创建
+func createSchemaVersionTable(ctx context.Context, fly *Dbfly) error {
+	migratory := fly.Migratory()
+	metaData := migratory.MetaData()
+	driver := fly.Driver()
+	tableName := fly.schemaVersionTableName
+	exists, _, err := metaData.ExistsTable(ctx, driver, tableName)
+	if err != nil {
+		return Wrap(err, "check schema version table exists failed")
+	}
+	if exists {
+		return nil
+	}
+	columns := []*ColumnNode{
+		{ColumnName: SCHEMA_COLUMN_TABLE_NAME, DataType: Varchar, MaxLength: 255, Notnull: true},
+		{ColumnName: SCHEMA_COLUMN_VERSION, DataType: Int, Notnull: true},
+		{ColumnName: SCHEMA_COLUMN_UPDATED_AT, DataType: Timestamp},
+	}
+	if err = migratory.CreateTable(ctx, driver, tableName, "", columns, nil); err != nil {
+		// 创建失败，可能是并发已创建，再次检查
+		if exists, _, reErr := metaData.ExistsTable(ctx, driver, tableName); reErr != nil || !exists {
+			return Wrap(err, "create schema version table failed")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package dbfly
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// schemaDriver 模拟结构版本表，查询返回 version
+type schemaDriver struct {
+	recordDriver
+	version int
+}
+
+func (d *schemaDriver) Query(context.Context, string, ...interface{}) (Rows, error) {
+	return &valueRows{values: [][]interface{}{{d.version}}}, nil
+}
+
+func TestDbRecorder_Upgrade(t *testing.T) {
+	metaData := &mockMetaData{
+		dbms: "Mock",
+		tables: []*Table{
+			{Name: defaultChangeLogTableName, TableType: "TABLE"},
+			{Name: defaultSchemaVersionTableName, TableType: "TABLE"},
+		},
+		columns: map[string][]*Column{
+			defaultChangeLogTableName: {
+				{Name: COLUMN_CHANGESET_ID}, {Name: COLUMN_AUTHOR}, {Name: COLUMN_FILENAME},
+				{Name: COLUMN_ORDER_EXECUTED}, {Name: COLUMN_IS_SUCCESS}, {Name: COLUMN_CREATED_AT},
+				{Name: COLUMN_UPDATED_AT}, {Name: COLUMN_CHECKSUM},
+			},
+		},
+	}
+	migratory := NewDefaultMigratory("mock", metaData)
+	driver := &schemaDriver{}
+	fly := NewDbfly(&migratory, driver, nil)
+	if err := fly.recorder.InitChangeLogTable(context.Background(), fly); err != nil {
+		t.Fatal(err)
+	}
+	var alters int
+	for _, sql := range driver.sqls {
+		if strings.HasPrefix(sql, "ALTER TABLE") {
+			alters++
+			if strings.Contains(sql, COLUMN_CHECKSUM) {
+				t.Errorf("existing column added again: %s", sql)
+			}
+		}
+	}
+	if alters != len(changeLogSchema.columns)-1 {
+		t.Errorf("alter statements = %d, want %d: %q", alters, len(changeLogSchema.columns)-1, driver.sqls)
+	}
+	last := len(driver.sqls) - 1
+	if !strings.HasPrefix(driver.sqls[last], "INSERT INTO") || driver.args[last][1] != changeLogSchema.version {
+		t.Errorf("schema version not recorded: %q %v", driver.sqls[last], driver.args[last])
+	}
+
+	// 已是最新版本时不再执行任何语句
+	driver = &schemaDriver{version: changeLogSchema.version}
+	fly = NewDbfly(&migratory, driver, nil)
+	if err := fly.recorder.InitChangeLogTable(context.Background(), fly); err != nil {
+		t.Fatal(err)
+	}
+	if len(driver.sqls) != 0 {
+		t.Errorf("up to date table executed %q", driver.sqls)
+	}
+}
+
+func TestSchemaVersionTableName(t *testing.T) {
+	metaData := &mockMetaData{dbms: "Mock", tables: []*Table{{Name: defaultChangeLogTableName, TableType: "TABLE"}}}
+	migratory := NewDefaultMigratory("mock", metaData)
+	driver := &schemaDriver{version: changeLogSchema.version}
+	fly := NewDbfly(&migratory, driver, nil, WithSchemaVersionTableName("APP_SCHEMA_VERSION"))
+	if err := fly.recorder.InitChangeLogTable(context.Background(), fly); err != nil {
+		t.Fatal(err)
+	}
+	joined := strings.Join(driver.sqls, "\n")
+	if !strings.Contains(joined, `CREATE TABLE "APP_SCHEMA_VERSION"`) || strings.Contains(joined, defaultSchemaVersionTableName) {
+		t.Errorf("schema version table not renamed: %q", driver.sqls)
+	}
+}
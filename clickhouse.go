@@ -0,0 +1,824 @@
+package dbfly
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type ClickHouseDatabaseMetaData struct {
+	versionHolder
+	quoter *Quoter
+}
+
+func NewClickHouseDatabaseMetaData() *ClickHouseDatabaseMetaData {
+	return &ClickHouseDatabaseMetaData{
+		quoter: NewQuoter('`', '`', AlwaysReserve),
+	}
+}
+
+func (m *ClickHouseDatabaseMetaData) Dbms() string {
+	return "ClickHouse"
+}
+
+func (m *ClickHouseDatabaseMetaData) Placeholder() PlaceholderStyle {
+	return PlaceholderQuestion
+}
+
+func (m *ClickHouseDatabaseMetaData) DataType(str string) string {
+	switch str {
+	case Varchar, Char, Text, Clob, Time, Blob:
+		return "String"
+	case Boolean:
+		return "Bool"
+	case Tinyint:
+		return "Int8"
+	case Smallint:
+		return "Int16"
+	case Int:
+		return "Int32"
+	case Bigint:
+		return "Int64"
+	case Decimal:
+		return "Decimal"
+	case Date:
+		return "Date"
+	case Timestamp:
+		return "DateTime64(3)"
+	}
+	return str
+}
+
+func (m *ClickHouseDatabaseMetaData) GetTables(ctx context.Context, driver Driver) ([]*Table, error) {
+	sql := `SELECT name,
+       if(engine IN ('View', 'MaterializedView', 'LiveView', 'WindowView'), 'VIEW', 'TABLE') AS table_type,
+       comment
+FROM system.tables
+WHERE database = currentDatabase() AND is_temporary = 0
+ORDER BY name`
+	return doGetSlices[Table](ctx, driver, func(rows Rows, t *Table) error {
+		return rows.Scan(&t.Name, &t.TableType, &t.Comment)
+	}, sql)
+}
+
+func (m *ClickHouseDatabaseMetaData) GetColumns(ctx context.Context, driver Driver, tableName string) ([]string, error) {
+	sql := `SELECT name FROM system.columns WHERE database = currentDatabase() AND table = ? ORDER BY position`
+	return doGetScalars[string](ctx, driver, sql, tableName)
+}
+
+func (m *ClickHouseDatabaseMetaData) GetColumnDetails(ctx context.Context, driver Driver, tableName string) ([]*Column, error) {
+	sql := `SELECT name, type, default_kind, default_expression, comment, position
+FROM system.columns
+WHERE database = currentDatabase() AND table = ?
+ORDER BY position`
+	return doGetSlices[Column](ctx, driver, func(rows Rows, t *Column) error {
+		var (
+			dataType    string
+			defaultKind string
+			expression  string
+			position    uint64
+		)
+		if err := rows.Scan(&t.Name, &dataType, &defaultKind, &expression, &t.Comment, &position); err != nil {
+			return err
+		}
+		var length, scale int
+		t.DataType, length, scale, t.Nullable = parseClickHouseType(dataType)
+		switch t.DataType {
+		case "FixedString":
+			t.MaxLength = length
+		case "Decimal", "DateTime64":
+			t.NumericPrecision = length
+			t.NumericScale = scale
+		}
+		// MATERIALIZED、ALIAS 列不是默认值
+		if defaultKind == "DEFAULT" {
+			t.DefaultValue = expression
+		}
+		t.Ordinal = int(position)
+		return nil
+	}, sql, tableName)
+}
+
+// parseClickHouseType 拆除 Nullable(...)、LowCardinality(...) 包装，返回类型名称、参数和是否可空
+func parseClickHouseType(str string) (string, int, int, bool) {
+	var nullable bool
+	for {
+		switch {
+		case strings.HasPrefix(str, "Nullable(") && strings.HasSuffix(str, ")"):
+			str = str[len("Nullable(") : len(str)-1]
+			nullable = true
+			continue
+		case strings.HasPrefix(str, "LowCardinality(") && strings.HasSuffix(str, ")"):
+			str = str[len("LowCardinality(") : len(str)-1]
+			continue
+		}
+		break
+	}
+	index := strings.IndexByte(str, '(')
+	if index < 0 {
+		return str, 0, 0, nullable
+	}
+	_, length, scale := parseColumnType(str)
+	return str[:index], length, scale, nullable
+}
+
+// GetIndexes 查询数据跳数索引，ClickHouse 没有唯一索引
+func (m *ClickHouseDatabaseMetaData) GetIndexes(ctx context.Context, driver Driver, tableName string) ([]*Index, error) {
+	sql := `SELECT name, expr FROM system.data_skipping_indices WHERE database = currentDatabase() AND table = ? ORDER BY name`
+	var indexes []*Index
+	err := doEach(ctx, driver, func(rows Rows) error {
+		var name, expr string
+		if err := rows.Scan(&name, &expr); err != nil {
+			return err
+		}
+		expr = strings.TrimSpace(expr)
+		if strings.HasPrefix(expr, "(") && strings.HasSuffix(expr, ")") {
+			expr = expr[1 : len(expr)-1]
+		}
+		for i, column := range strings.Split(expr, ",") {
+			indexes = append(indexes, &Index{
+				Name:       name,
+				ColumnName: strings.Trim(strings.TrimSpace(column), "`"),
+				Ordinal:    i + 1,
+			})
+		}
+		return nil
+	}, sql, tableName)
+	return indexes, err
+}
+
+// GetPrimaryKeys 查询主键（排序键前缀）中的列
+func (m *ClickHouseDatabaseMetaData) GetPrimaryKeys(ctx context.Context, driver Driver, tableName string) ([]*PrimaryKey, error) {
+	sql := `SELECT name FROM system.columns WHERE database = currentDatabase() AND table = ? AND is_in_primary_key = 1 ORDER BY position`
+	columns, err := doGetScalars[string](ctx, driver, sql, tableName)
+	if err != nil {
+		return nil, err
+	}
+	primaryKeys := make([]*PrimaryKey, 0, len(columns))
+	for _, column := range columns {
+		primaryKeys = append(primaryKeys, &PrimaryKey{Name: "PRIMARY", ColumnName: column})
+	}
+	return primaryKeys, nil
+}
+
+// GetForeignKeys ClickHouse 不支持外键
+func (m *ClickHouseDatabaseMetaData) GetForeignKeys(context.Context, Driver, string) ([]*ForeignKey, error) {
+	return nil, nil
+}
+
+func (m *ClickHouseDatabaseMetaData) ExistsTable(ctx context.Context, driver Driver, tableName string) (bool, string, error) {
+	return ExistsTable(m.GetTables, ctx, driver, tableName)
+}
+
+func (m *ClickHouseDatabaseMetaData) ExistsColumn(ctx context.Context, driver Driver, tableName, columnName string) (bool, string, string, error) {
+	return ExistsColumn(m.GetTables, m.GetColumns, ctx, driver, tableName, columnName)
+}
+
+func (m *ClickHouseDatabaseMetaData) ExistsIndex(ctx context.Context, driver Driver, tableName, indexName string) (bool, string, string, error) {
+	return ExistsIndex(m.GetTables, m.GetIndexes, ctx, driver, tableName, indexName)
+}
+
+func (m *ClickHouseDatabaseMetaData) ExistsPrimaryKey(ctx context.Context, driver Driver, tableName string) (bool, string, error) {
+	return ExistsPrimaryKey(m.GetTables, m.GetPrimaryKeys, ctx, driver, tableName)
+}
+
+func (m *ClickHouseDatabaseMetaData) Version(ctx context.Context, driver Driver) (string, error) {
+	return m.queryVersion(ctx, driver, "SELECT version()", nil)
+}
+
+func (m *ClickHouseDatabaseMetaData) Quoter() *Quoter {
+	return m.quoter
+}
+
+// ClickHouse 建表子句，按此顺序输出，其余属性追加在后面
+var clickHouseTableClauses = []string{"ENGINE", "PARTITION BY", "PRIMARY KEY", "ORDER BY", "SAMPLE BY", "TTL", "SETTINGS"}
+
+const defaultClickHouseEngine = "MergeTree"
+
+// ClickHouseMigratory ClickHouse迁移实现
+type ClickHouseMigratory struct {
+	DefaultMigratory
+}
+
+// NewClickHouseMigratory 创建一个ClickHouse迁移实现实例
+func NewClickHouseMigratory() Migratory {
+	return &ClickHouseMigratory{
+		DefaultMigratory: NewDefaultMigratory("clickhouse", NewClickHouseDatabaseMetaData()),
+	}
+}
+
+// SavepointSQL ClickHouse 不支持保存点
+func (m *ClickHouseMigratory) SavepointSQL(name string) (string, string, error) {
+	return "", "", Wrap(ErrSavepointUnsupported, "create savepoint %s failed", name)
+}
+
+// CreateTable 表引擎和排序键取自 dbmsAttributes，未配置时使用 MergeTree 并以主键列排序
+func (m *ClickHouseMigratory) CreateTable(ctx context.Context, driver Driver, tableName string, comment string, columns []*ColumnNode, attributes *AttributesNode) error {
+	var builder strings.Builder
+	builder.WriteString("CREATE TABLE ")
+	m.QuoteTo(&builder, tableName)
+	builder.WriteString("\n(\n")
+	var primaryKeys []string
+	for index, column := range columns {
+		if index > 0 {
+			builder.WriteString(",\n")
+		}
+		builder.WriteString("  ")
+		if column.PrimaryKey {
+			primaryKeys = append(primaryKeys, m.Quote(column.ColumnName))
+		}
+		m.writeColumn(&builder, column.ColumnName, &AlterColumnColumnNode{
+			DataType:           column.DataType,
+			MaxLength:          column.MaxLength,
+			NumericScale:       column.NumericScale,
+			Notnull:            column.Notnull || column.PrimaryKey,
+			Unique:             column.Unique,
+			DefaultValue:       column.DefaultValue,
+			DefaultOriginValue: column.DefaultOriginValue,
+			Comment:            column.Comment,
+			ColumnDbms:         column.ColumnDbms,
+		})
+	}
+	builder.WriteString("\n)")
+	for _, clause := range m.tableClauses(attributes, primaryKeys) {
+		builder.WriteString("\n")
+		builder.WriteString(clause)
+	}
+	if comment != "" {
+		builder.WriteString("\nCOMMENT '")
+		builder.WriteString(ReplaceComment(comment))
+		builder.WriteString("'")
+	}
+	_, err := driver.Execute(ctx, builder.String())
+	return err
+}
+
+// tableClauses 生成 ENGINE、ORDER BY 等建表子句
+func (m *ClickHouseMigratory) tableClauses(attributes *AttributesNode, primaryKeys []string) []string {
+	values := make(map[string]string)
+	var names []string
+	if attributes != nil {
+		for _, attr := range attributes.Attributes {
+			if !matchDbms(m.MetaData(), attr.Dbms) {
+				continue
+			}
+			name := strings.Join(strings.Fields(strings.ToUpper(attr.Name)), " ")
+			if _, ok := values[name]; !ok {
+				names = append(names, name)
+			}
+			values[name] = attr.Value
+		}
+	}
+	if values["ENGINE"] == "" {
+		values["ENGINE"] = defaultClickHouseEngine
+	}
+	// MergeTree 系列引擎必须指定排序键，未配置时使用主键列，没有主键时不排序
+	if strings.Contains(values["ENGINE"], "MergeTree") && values["ORDER BY"] == "" && values["PRIMARY KEY"] == "" {
+		switch len(primaryKeys) {
+		case 0:
+			values["ORDER BY"] = "tuple()"
+		case 1:
+			values["ORDER BY"] = primaryKeys[0]
+		default:
+			values["ORDER BY"] = "(" + strings.Join(primaryKeys, ", ") + ")"
+		}
+	}
+
+	var clauses []string
+	for _, name := range clickHouseTableClauses {
+		if value := values[name]; value != "" {
+			if name == "ENGINE" {
+				clauses = append(clauses, "ENGINE = "+value)
+			} else {
+				clauses = append(clauses, name+" "+value)
+			}
+		}
+		delete(values, name)
+	}
+	for _, name := range names {
+		if value, ok := values[name]; ok {
+			clauses = append(clauses, name+" "+value)
+		}
+	}
+	return clauses
+}
+
+// writeColumn 输出列定义，可空列使用 Nullable 包装，ClickHouse 没有唯一约束
+func (m *ClickHouseMigratory) writeColumn(builder *strings.Builder, columnName string, node *AlterColumnColumnNode) {
+	dbmsNode := selectColumnDbms(m.MetaData(), node.ColumnDbms)
+	m.QuoteTo(builder, columnName)
+	builder.WriteString(" ")
+	var defaultValue string
+	if dbmsNode != nil {
+		builder.WriteString(dbmsNode.DataType)
+		if dbmsNode.DefaultOriginValue != "" {
+			defaultValue = dbmsNode.DefaultOriginValue
+		} else if dbmsNode.DefaultValue != "" {
+			defaultValue = fmt.Sprintf("'%s'", strings.ReplaceAll(dbmsNode.DefaultValue, "'", "''"))
+		}
+	} else {
+		builder.WriteString(m.columnType(node))
+		if node.DefaultOriginValue != "" {
+			defaultValue = node.DefaultOriginValue
+		} else if node.DefaultValue != "" {
+			defaultValue = fmt.Sprintf("'%s'", strings.ReplaceAll(node.DefaultValue, "'", "''"))
+		}
+	}
+	if defaultValue != "" {
+		builder.WriteString(" DEFAULT ")
+		builder.WriteString(defaultValue)
+	}
+	if node.Comment != "" {
+		builder.WriteString(" COMMENT '")
+		builder.WriteString(ReplaceComment(node.Comment))
+		builder.WriteString("'")
+	}
+	if node.Unique {
+		m.logger.Warn("unique constraint is not supported by ClickHouse, ignored, column: %q", columnName)
+	}
+}
+
+func (m *ClickHouseMigratory) columnType(node *AlterColumnColumnNode) string {
+	dataType := m.DataType(node.DataType)
+	if node.DataType == Decimal {
+		dataType = fmt.Sprintf("Decimal(%d, %d)", node.MaxLength, node.NumericScale)
+	}
+	if !node.Notnull {
+		return "Nullable(" + dataType + ")"
+	}
+	return dataType
+}
+
+// CreateIndex 创建数据跳数索引，类型和粒度通过 TYPE、GRANULARITY 属性指定，默认为 minmax 和 1
+func (m *ClickHouseMigratory) CreateIndex(ctx context.Context, driver Driver, tableName string, indexName string, unique bool, columns []*IndexColumnNode, attributes *AttributesNode) error {
+	if unique {
+		return New("unique index is not supported by ClickHouse, index: %s", indexName)
+	}
+	indexType, granularity := "minmax", "1"
+	if attributes != nil {
+		for _, attr := range attributes.Attributes {
+			if !matchDbms(m.MetaData(), attr.Dbms) {
+				continue
+			}
+			switch strings.ToUpper(attr.Name) {
+			case "TYPE":
+				indexType = attr.Value
+			case "GRANULARITY":
+				granularity = attr.Value
+			}
+		}
+	}
+	names := make([]string, 0, len(columns))
+	for _, column := range columns {
+		names = append(names, m.Quote(column.Name))
+	}
+	_, err := driver.Execute(ctx, fmt.Sprintf("ALTER TABLE %s ADD INDEX %s (%s) TYPE %s GRANULARITY %s",
+		m.Quote(tableName), m.Quote(indexName), strings.Join(names, ", "), indexType, granularity))
+	return err
+}
+
+// CreatePrimaryKey ClickHouse 的主键只能在建表时指定
+func (m *ClickHouseMigratory) CreatePrimaryKey(_ context.Context, _ Driver, tableName string, _ string, _ []*IndexColumnNode, _ *AttributesNode) error {
+	return New("ClickHouse does not support adding primary key to existing table %s", tableName)
+}
+
+func (m *ClickHouseMigratory) DropIndex(ctx context.Context, driver Driver, tableName, indexName string, _ *AttributesNode) error {
+	_, err := driver.Execute(ctx, fmt.Sprintf("ALTER TABLE %s DROP INDEX %s", m.Quote(tableName), m.Quote(indexName)))
+	return err
+}
+
+func (m *ClickHouseMigratory) AddColumn(ctx context.Context, driver Driver, tableName string, columns []*AddColumnColumnNode, _ *AttributesNode) error {
+	for _, column := range columns {
+		var builder strings.Builder
+		builder.WriteString("ALTER TABLE ")
+		m.QuoteTo(&builder, tableName)
+		builder.WriteString(" ADD COLUMN ")
+		m.writeColumn(&builder, column.ColumnName, &AlterColumnColumnNode{
+			DataType:           column.DataType,
+			MaxLength:          column.MaxLength,
+			NumericScale:       column.NumericScale,
+			Notnull:            column.Notnull,
+			Unique:             column.Unique,
+			DefaultValue:       column.DefaultValue,
+			DefaultOriginValue: column.DefaultOriginValue,
+			Comment:            column.Comment,
+			ColumnDbms:         column.ColumnDbms,
+		})
+		if _, err := driver.Execute(ctx, builder.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *ClickHouseMigratory) AlterColumn(ctx context.Context, driver Driver, tableName string, columnName string, column *AlterColumnColumnNode, _ *AttributesNode) error {
+	var builder strings.Builder
+	builder.WriteString("ALTER TABLE ")
+	m.QuoteTo(&builder, tableName)
+	builder.WriteString(" MODIFY COLUMN ")
+	m.writeColumn(&builder, columnName, column)
+	_, err := driver.Execute(ctx, builder.String())
+	return err
+}
+
+// DropPrimaryKey ClickHouse 的主键不能删除
+func (m *ClickHouseMigratory) DropPrimaryKey(_ context.Context, _ Driver, tableName string, _ *AttributesNode) error {
+	return New("ClickHouse does not support dropping primary key of table %s", tableName)
+}
+
+func (m *ClickHouseMigratory) RenameTable(ctx context.Context, driver Driver, tableName string, newTableName string, _ *AttributesNode) error {
+	_, err := driver.Execute(ctx, fmt.Sprintf("RENAME TABLE %s TO %s", m.Quote(tableName), m.Quote(newTableName)))
+	return err
+}
+
+func (m *ClickHouseMigratory) AlterTableComment(ctx context.Context, driver Driver, tableName string, comment string, _ *AttributesNode) error {
+	_, err := driver.Execute(ctx, fmt.Sprintf("ALTER TABLE %s MODIFY COMMENT '%s'", m.Quote(tableName), ReplaceComment(comment)))
+	return err
+}
+
+// COLUMN_APPEND_SEQ ClickHouse 变更记录的追加序号，同一毫秒内追加的记录以序号区分先后
+const COLUMN_APPEND_SEQ = "APPEND_SEQ"
+
+// clickHouseChangeLogSchema ClickHouse 变更记录表结构，版本 3 增加追加序号
+var clickHouseChangeLogSchema = tableSchema{
+	version: 3,
+	columns: append(append([]schemaColumn{}, changeLogSchema.columns...),
+		schemaColumn{3, &AddColumnColumnNode{ColumnName: COLUMN_APPEND_SEQ, DataType: Bigint}}),
+}
+
+// ClickHouseRecorder ClickHouse变更记录实现，只追加不更新：完成变更时追加一条成功记录，
+// 每条记录的追加序号在服务端按已有最大值递增，以序号最大的记录作为变更集的当前状态
+type ClickHouseRecorder struct {
+	*DbRecorder
+}
+
+func NewClickHouseRecorder(opts ...RecorderOption) *ClickHouseRecorder {
+	return &ClickHouseRecorder{DbRecorder: NewDbRecorder(opts...)}
+}
+
+func (r *ClickHouseRecorder) InitChangeLogTable(ctx context.Context, fly *Dbfly) error {
+	metaData := fly.Migratory().MetaData()
+	driver := fly.Driver()
+	exists, _, err := metaData.ExistsTable(ctx, driver, r.tableName)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return upgradeTable(ctx, fly, r.tableName, clickHouseChangeLogSchema)
+	}
+
+	quoter := metaData.Quoter()
+	sql := fmt.Sprintf("CREATE TABLE %s(%s String, %s String, %s String, %s String, %s Int32, %s UInt8, %s String, %s String, "+
+		"%s Int64, %s String, %s String, %s String, %s String, %s String, %s DateTime64(3), %s DateTime64(3), %s Int64) "+
+		"ENGINE = MergeTree ORDER BY (%s, %s)",
+		quoter.MustQuote(r.tableName),
+		quoter.MustQuote(COLUMN_CHANGESET_ID), quoter.MustQuote(COLUMN_AUTHOR), quoter.MustQuote(COLUMN_FILENAME),
+		quoter.MustQuote(COLUMN_DESCRIPTION), quoter.MustQuote(COLUMN_ORDER_EXECUTED), quoter.MustQuote(COLUMN_IS_SUCCESS),
+		quoter.MustQuote(COLUMN_EXEC_TYPE), quoter.MustQuote(COLUMN_CHECKSUM), quoter.MustQuote(COLUMN_DURATION_MS),
+		quoter.MustQuote(COLUMN_DEPLOYMENT_ID), quoter.MustQuote(COLUMN_TAG), quoter.MustQuote(COLUMN_CONTEXTS),
+		quoter.MustQuote(COLUMN_DBFLY_VERSION), quoter.MustQuote(COLUMN_ERROR_MESSAGE),
+		quoter.MustQuote(COLUMN_CREATED_AT), quoter.MustQuote(COLUMN_UPDATED_AT), quoter.MustQuote(COLUMN_APPEND_SEQ),
+		quoter.MustQuote(COLUMN_CHANGESET_ID), quoter.MustQuote(COLUMN_APPEND_SEQ))
+	if _, err = driver.Execute(ctx, sql); err != nil {
+		return err
+	}
+	fly.logger.Debug("change log table initialized")
+	return setSchemaVersion(ctx, fly, r.tableName, clickHouseChangeLogSchema.version)
+}
+
+// latestOrder 变更集最近一条记录的排序依据，升级前追加的记录没有序号，按更新时间排序
+func latestOrder(quoter *Quoter) string {
+	return fmt.Sprintf("tuple(ifNull(%s, 0), %s)", quoter.MustQuote(COLUMN_APPEND_SEQ), quoter.MustQuote(COLUMN_UPDATED_AT))
+}
+
+func (r *ClickHouseRecorder) GetExecutedChangeSets(ctx context.Context, fly *Dbfly) (map[string]bool, error) {
+	quoter := fly.Migratory().MetaData().Quoter()
+	// 以最近追加的记录为准，回滚后的变更集不再视为已执行
+	changeSetIds, err := doGetScalars[string](ctx, fly.Driver(), fmt.Sprintf("SELECT %s FROM %s GROUP BY %s HAVING argMax(%s, %s) = 1",
+		quoter.MustQuote(COLUMN_CHANGESET_ID), quoter.MustQuote(r.tableName), quoter.MustQuote(COLUMN_CHANGESET_ID),
+		quoter.MustQuote(COLUMN_IS_SUCCESS), latestOrder(quoter)))
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]bool, len(changeSetIds))
+	for _, changeSetId := range changeSetIds {
+		result[changeSetId] = true
+	}
+	return result, nil
+}
+
+// History 每个变更集取最近追加的一条记录
+func (r *ClickHouseRecorder) History(ctx context.Context, fly *Dbfly) ([]ChangeLogEntry, error) {
+	metaData := fly.Migratory().MetaData()
+	// 尚未迁移时变更记录表不存在
+	exists, _, err := metaData.ExistsTable(ctx, fly.Driver(), r.tableName)
+	if err != nil || !exists {
+		return nil, err
+	}
+	quoter := metaData.Quoter()
+	var entries []ChangeLogEntry
+	err = doEach(ctx, fly.Driver(), func(rows Rows) error {
+		var entry ChangeLogEntry
+		if err := scanChangeLogEntry(rows, &entry); err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+		return nil
+	}, fmt.Sprintf("SELECT %s FROM (SELECT * FROM %s ORDER BY %s DESC LIMIT 1 BY %s) ORDER BY %s, %s",
+		quoteColumns(quoter, changeLogColumns), quoter.MustQuote(r.tableName),
+		latestOrder(quoter), quoter.MustQuote(COLUMN_CHANGESET_ID),
+		quoter.MustQuote(COLUMN_ORDER_EXECUTED), quoter.MustQuote(COLUMN_CREATED_AT)))
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// NewChangeLog 追加一条执行中的记录，失败的历史记录保留在表中
+func (r *ClickHouseRecorder) NewChangeLog(ctx context.Context, fly *Dbfly, entry *ChangeLogEntry) error {
+	now := time.Now()
+	entry.Success, entry.ExecType, entry.CreatedAt, entry.UpdatedAt = false, ExecTypeRunning, now, now
+	if err := r.appendChangeLog(ctx, fly, entry); err != nil {
+		return err
+	}
+	fly.logger.Debug("change log created, changeSetId: %q", entry.ChangeSetId)
+	return nil
+}
+
+// CompleteChangeLog 追加一条成功记录
+func (r *ClickHouseRecorder) CompleteChangeLog(ctx context.Context, fly *Dbfly, entry *ChangeLogEntry) error {
+	entry.Success, entry.ExecType, entry.UpdatedAt = true, ExecTypeExecuted, time.Now()
+	if err := r.appendChangeLog(ctx, fly, entry); err != nil {
+		return err
+	}
+	fly.logger.Debug("change log completed, changeSetId: %q", entry.ChangeSetId)
+	return nil
+}
+
+// FailChangeLog 追加一条失败记录
+func (r *ClickHouseRecorder) FailChangeLog(ctx context.Context, fly *Dbfly, entry *ChangeLogEntry) error {
+	entry.UpdatedAt = time.Now()
+	if err := r.appendChangeLog(ctx, fly, entry); err != nil {
+		return err
+	}
+	fly.logger.Debug("change log failed, changeSetId: %q", entry.ChangeSetId)
+	return nil
+}
+
+// RollbackChangeLog 追加一条已回滚记录
+func (r *ClickHouseRecorder) RollbackChangeLog(ctx context.Context, fly *Dbfly, entry *ChangeLogEntry) error {
+	entry.Success, entry.ExecType, entry.UpdatedAt = false, ExecTypeRolledBack, time.Now()
+	if err := r.appendChangeLog(ctx, fly, entry); err != nil {
+		return err
+	}
+	fly.logger.Debug("change log rolled back, changeSetId: %q", entry.ChangeSetId)
+	return nil
+}
+
+// appendChangeLog 追加一条记录，序号为表中最大序号加一；追加在迁移锁内串行执行，序号单调递增
+func (r *ClickHouseRecorder) appendChangeLog(ctx context.Context, fly *Dbfly, entry *ChangeLogEntry) error {
+	quoter := fly.Migratory().MetaData().Quoter()
+	success := 0
+	if entry.Success {
+		success = 1
+	}
+	_, err := fly.Driver().Execute(ctx,
+		fmt.Sprintf("INSERT INTO %s(%s, %s) SELECT ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, (SELECT ifNull(max(%s), 0) + 1 FROM %s)",
+			quoter.MustQuote(r.tableName), quoteColumns(quoter, changeLogColumns), quoter.MustQuote(COLUMN_APPEND_SEQ),
+			quoter.MustQuote(COLUMN_APPEND_SEQ), quoter.MustQuote(r.tableName)),
+		entry.ChangeSetId, entry.Author, entry.Filename, entry.Description, entry.OrderExecuted,
+		success, string(entry.ExecType), entry.Checksum, entry.Duration.Milliseconds(), entry.DeploymentId,
+		entry.Tag, entry.Contexts, entry.DbflyVersion, entry.ErrorMessage, entry.CreatedAt, entry.UpdatedAt)
+	return err
+}
+
+const (
+	LOCK_COLUMN_TOKEN    = "LOCK_TOKEN"
+	LOCK_COLUMN_RELEASED = "IS_RELEASED"
+)
+
+// ClickHouseLocker ClickHouse锁实现，不依赖行级更新：每次加锁追加一条申请记录，
+// 最早且未释放的申请持有锁，释放时追加一条释放记录。申请以最近追加的记录时间作为租约，
+// 等待和持有期间定期追加续期记录，超过租约时长未续期的申请视为已失效
+type ClickHouseLocker struct {
+	*DbLocker
+}
+
+func NewClickHouseLocker(opts ...LockerOption) *ClickHouseLocker {
+	return &ClickHouseLocker{DbLocker: NewDbLocker(opts...)}
+}
+
+func (l *ClickHouseLocker) Lock(ctx context.Context, fly *Dbfly) (Unlock, error) {
+	if err := l.createLockTable(ctx, fly); err != nil {
+		return nil, err
+	}
+	owner := fly.LockOwner()
+	token := fmt.Sprintf("%s-%d", owner, time.Now().UnixNano())
+	quoter := fly.Migratory().MetaData().Quoter()
+	driver := fly.Driver()
+
+	// 申请时间使用服务端时间，避免客户端时钟偏差影响排序
+	insertSQL := fmt.Sprintf("INSERT INTO %s(%s, %s, %s, %s) SELECT ?, ?, now64(6), ?",
+		quoter.MustQuote(l.tableName),
+		quoter.MustQuote(LOCK_COLUMN_TOKEN),
+		quoter.MustQuote(LOCK_COLUMN_LOCKED_BY),
+		quoter.MustQuote(LOCK_COLUMN_LOCK_TIME),
+		quoter.MustQuote(LOCK_COLUMN_RELEASED))
+	claim := func(ctx context.Context) error {
+		_, err := driver.Execute(ctx, insertSQL, token, owner, 0)
+		return err
+	}
+	if err := claim(ctx); err != nil {
+		return nil, Wrap(err, "insert lock claim failed")
+	}
+	release := func(ctx context.Context, fly *Dbfly) error {
+		_, err := fly.Driver().Execute(ctx, insertSQL, token, owner, 1)
+		return err
+	}
+
+	claimed := time.Now()
+	waiter := newLockWaiter(fly, l.retryInterval, l.maxRetryInterval, l.timeout, l.maxRetries)
+	for {
+		holder, err := l.owner(ctx, fly, driver)
+		if err != nil {
+			_ = release(ctx, fly)
+			return nil, Wrap(err, "get lock owner failed")
+		}
+		if holder == token {
+			stop := l.heartbeat(ctx, fly, driver, token, claim)
+			return func(ctx context.Context, fly *Dbfly) error {
+				stop()
+				return release(ctx, fly)
+			}, nil
+		}
+		if err = waiter.wait(ctx, holder); err != nil {
+			// 撤回申请，避免阻塞后续的加锁；上下文可能已取消，使用独立的上下文
+			_ = release(context.Background(), fly)
+			return nil, err
+		}
+		// 等待期间续期申请，避免排队的申请因租约过期被忽略
+		if time.Since(claimed) >= l.heartbeatInterval {
+			if err = claim(ctx); err != nil {
+				return nil, Wrap(err, "renew lock claim failed")
+			}
+			claimed = time.Now()
+		}
+	}
+}
+
+// owner 查询持有锁的申请标识
+func (l *ClickHouseLocker) owner(ctx context.Context, fly *Dbfly, driver Driver) (string, error) {
+	quoter := fly.Migratory().MetaData().Quoter()
+	sql := fmt.Sprintf("SELECT %s FROM %s GROUP BY %s HAVING %s ORDER BY min(%s), %s LIMIT 1",
+		quoter.MustQuote(LOCK_COLUMN_TOKEN),
+		quoter.MustQuote(l.tableName),
+		quoter.MustQuote(LOCK_COLUMN_TOKEN),
+		l.activeClaim(quoter),
+		quoter.MustQuote(LOCK_COLUMN_LOCK_TIME),
+		quoter.MustQuote(LOCK_COLUMN_TOKEN))
+	holder, err := doGetScalar[string](ctx, driver, sql, l.ttl.Microseconds())
+	if errors.Is(err, NoData) {
+		return "", nil
+	}
+	return holder, err
+}
+
+// activeClaim 未释放且租约未过期的申请，租约时长以微秒为参数
+func (l *ClickHouseLocker) activeClaim(quoter *Quoter) string {
+	return fmt.Sprintf("max(%s) = 0 AND max(%s) >= now64(6) - toIntervalMicrosecond(?)",
+		quoter.MustQuote(LOCK_COLUMN_RELEASED),
+		quoter.MustQuote(LOCK_COLUMN_LOCK_TIME))
+}
+
+// heartbeat 后台定期追加申请记录续期租约，返回停止续期的函数
+func (l *ClickHouseLocker) heartbeat(ctx context.Context, fly *Dbfly, driver Driver, token string, claim func(context.Context) error) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(l.heartbeatInterval)
+		defer ticker.Stop()
+		renewed := time.Now()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			now := time.Now()
+			err := claim(ctx)
+			if err == nil {
+				var holder string
+				if holder, err = l.owner(ctx, fly, driver); err == nil && holder != token {
+					fly.logger.Error("lock taken over by %q", holder)
+					fly.abort(Wrap(ErrLockLost, "lock taken over by %q", holder))
+					return
+				}
+			}
+			if err == nil {
+				renewed = now
+				continue
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			fly.logger.Warn("renew lock lease failed: %+v", err)
+			if time.Since(renewed) >= l.ttl {
+				fly.logger.Error("lock lease expired, last renewed at %s", renewed.Format(time.RFC3339))
+				fly.abort(Wrap(ErrLockLost, "lock lease not renewed since %s", renewed.Format(time.RFC3339)))
+				return
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// Status 最早且未释放的申请为锁的持有者
+func (l *ClickHouseLocker) Status(ctx context.Context, fly *Dbfly) (*LockStatus, error) {
+	status, _, err := l.holder(ctx, fly)
+	return status, err
+}
+
+// ForceRelease 为持有锁的申请追加释放记录，等待中的申请不受影响
+func (l *ClickHouseLocker) ForceRelease(ctx context.Context, fly *Dbfly) error {
+	status, token, err := l.holder(ctx, fly)
+	if err != nil || !status.Locked {
+		return err
+	}
+	quoter := fly.Migratory().MetaData().Quoter()
+	sql := fmt.Sprintf("INSERT INTO %s(%s, %s, %s, %s) SELECT ?, ?, now64(6), 1",
+		quoter.MustQuote(l.tableName),
+		quoter.MustQuote(LOCK_COLUMN_TOKEN),
+		quoter.MustQuote(LOCK_COLUMN_LOCKED_BY),
+		quoter.MustQuote(LOCK_COLUMN_LOCK_TIME),
+		quoter.MustQuote(LOCK_COLUMN_RELEASED))
+	if _, err = fly.Driver().Execute(ctx, sql, token, status.Owner); err != nil {
+		return Wrap(err, "force release lock failed")
+	}
+	return nil
+}
+
+// holder 查询持有锁的申请
+func (l *ClickHouseLocker) holder(ctx context.Context, fly *Dbfly) (*LockStatus, string, error) {
+	metaData := fly.Migratory().MetaData()
+	driver := fly.Driver()
+	status := &LockStatus{}
+	exists, _, err := metaData.ExistsTable(ctx, driver, l.tableName)
+	if err != nil || !exists {
+		return status, "", err
+	}
+	quoter := metaData.Quoter()
+	sql := fmt.Sprintf("SELECT %s, any(%s), min(%s) FROM %s GROUP BY %s HAVING %s ORDER BY min(%s), %s LIMIT 1",
+		quoter.MustQuote(LOCK_COLUMN_TOKEN),
+		quoter.MustQuote(LOCK_COLUMN_LOCKED_BY),
+		quoter.MustQuote(LOCK_COLUMN_LOCK_TIME),
+		quoter.MustQuote(l.tableName),
+		quoter.MustQuote(LOCK_COLUMN_TOKEN),
+		l.activeClaim(quoter),
+		quoter.MustQuote(LOCK_COLUMN_LOCK_TIME),
+		quoter.MustQuote(LOCK_COLUMN_TOKEN))
+	var token string
+	err = doEach(ctx, driver, func(rows Rows) error {
+		var since lockTime
+		if err := rows.Scan(&token, &status.Owner, &since); err != nil {
+			return err
+		}
+		status.Locked = true
+		status.Since = since.Time
+		return nil
+	}, sql, l.ttl.Microseconds())
+	if err != nil {
+		return nil, "", Wrap(err, "get lock status failed")
+	}
+	return status, token, nil
+}
+
+func (l *ClickHouseLocker) createLockTable(ctx context.Context, fly *Dbfly) error {
+	metaData := fly.Migratory().MetaData()
+	driver := fly.Driver()
+	exists, _, err := metaData.ExistsTable(ctx, driver, l.tableName)
+	if err != nil {
+		return Wrap(err, "check lock table exists failed")
+	}
+	if exists {
+		return upgradeTable(ctx, fly, l.tableName, lockSchema)
+	}
+	quoter := metaData.Quoter()
+	sql := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s(%s String, %s String, %s DateTime64(6), %s UInt8) ENGINE = MergeTree ORDER BY (%s, %s)",
+		quoter.MustQuote(l.tableName),
+		quoter.MustQuote(LOCK_COLUMN_TOKEN),
+		quoter.MustQuote(LOCK_COLUMN_LOCKED_BY),
+		quoter.MustQuote(LOCK_COLUMN_LOCK_TIME),
+		quoter.MustQuote(LOCK_COLUMN_RELEASED),
+		quoter.MustQuote(LOCK_COLUMN_LOCK_TIME),
+		quoter.MustQuote(LOCK_COLUMN_TOKEN))
+	if _, err = driver.Execute(ctx, sql); err != nil {
+		return Wrap(err, "create lock table failed")
+	}
+	return setSchemaVersion(ctx, fly, l.tableName, lockSchema.version)
+}
@@ -0,0 +1,287 @@
+package dbfly
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordDriver 记录执行的SQL，不连接数据库
+type recordDriver struct {
+	sqls []string
+	args [][]interface{}
+}
+
+func (d *recordDriver) Execute(_ context.Context, sql string, args ...interface{}) (sql.Result, error) {
+	d.sqls = append(d.sqls, sql)
+	d.args = append(d.args, args)
+	return nil, nil
+}
+
+func (d *recordDriver) Query(context.Context, string, ...interface{}) (Rows, error) {
+	return nil, New("query is not supported")
+}
+
+func (d *recordDriver) BeginTx(context.Context) (Tx, error) {
+	return nil, New("transaction is not supported")
+}
+
+func TestParseClickHouseType(t *testing.T) {
+	tests := []struct {
+		str, dataType string
+		length, scale int
+		nullable      bool
+	}{
+		{"String", "String", 0, 0, false},
+		{"Nullable(Int32)", "Int32", 0, 0, true},
+		{"LowCardinality(Nullable(String))", "String", 0, 0, true},
+		{"Decimal(18, 4)", "Decimal", 18, 4, false},
+		{"Nullable(DateTime64(3))", "DateTime64", 3, 0, true},
+	}
+	for _, tt := range tests {
+		dataType, length, scale, nullable := parseClickHouseType(tt.str)
+		if dataType != tt.dataType || length != tt.length || scale != tt.scale || nullable != tt.nullable {
+			t.Errorf("parseClickHouseType(%q) = %s %d %d %v", tt.str, dataType, length, scale, nullable)
+		}
+	}
+}
+
+func TestClickHouseMigratory_CreateTable(t *testing.T) {
+	m := NewClickHouseMigratory()
+	driver := &recordDriver{}
+	columns := []*ColumnNode{
+		{ColumnName: "id", DataType: Bigint, PrimaryKey: true},
+		{ColumnName: "name", DataType: Varchar, MaxLength: 50, Comment: "名称"},
+		{ColumnName: "amount", DataType: Decimal, MaxLength: 18, NumericScale: 2, Notnull: true, DefaultOriginValue: "0"},
+	}
+	if err := m.CreateTable(context.Background(), driver, "t_event", "事件", columns, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := "CREATE TABLE `t_event`\n(\n" +
+		"  `id` Int64,\n" +
+		"  `name` Nullable(String) COMMENT '名称',\n" +
+		"  `amount` Decimal(18, 2) DEFAULT 0\n" +
+		")\nENGINE = MergeTree\nORDER BY `id`\nCOMMENT '事件'"
+	if len(driver.sqls) != 1 || driver.sqls[0] != want {
+		t.Errorf("CreateTable() = %q, want %q", driver.sqls, want)
+	}
+
+	driver = &recordDriver{}
+	attributes := &AttributesNode{Attributes: []*AttributeNode{
+		{Dbms: "ClickHouse", Name: "order by", Value: "(id, created_at)"},
+		{Dbms: "ClickHouse", Name: "ENGINE", Value: "ReplacingMergeTree(version)"},
+		{Dbms: "ClickHouse", Name: "PARTITION BY", Value: "toYYYYMM(created_at)"},
+		{Dbms: "MySQL", Name: "ENGINE", Value: "InnoDB"},
+	}}
+	if err := m.CreateTable(context.Background(), driver, "t_event", "", columns[:1], attributes); err != nil {
+		t.Fatal(err)
+	}
+	want = "CREATE TABLE `t_event`\n(\n  `id` Int64\n)\n" +
+		"ENGINE = ReplacingMergeTree(version)\nPARTITION BY toYYYYMM(created_at)\nORDER BY (id, created_at)"
+	if len(driver.sqls) != 1 || driver.sqls[0] != want {
+		t.Errorf("CreateTable() = %q, want %q", driver.sqls, want)
+	}
+}
+
+func TestClickHouseMigratory_AlterColumn(t *testing.T) {
+	m := NewClickHouseMigratory()
+	driver := &recordDriver{}
+	column := &AlterColumnColumnNode{DataType: Timestamp, Notnull: true, DefaultOriginValue: "now64(3)"}
+	if err := m.AlterColumn(context.Background(), driver, "t_event", "created_at", column, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := "ALTER TABLE `t_event` MODIFY COLUMN `created_at` DateTime64(3) DEFAULT now64(3)"
+	if len(driver.sqls) != 1 || driver.sqls[0] != want {
+		t.Errorf("AlterColumn() = %q, want %q", driver.sqls, want)
+	}
+}
+
+// lockClaim ClickHouse 锁表中同一申请的聚合结果
+type lockClaim struct {
+	token, owner string
+	first, last  time.Time
+	released     bool
+}
+
+// claimDriver 模拟只追加的 ClickHouse 锁表，LOCK_TIME 取当前时间
+type claimDriver struct {
+	mu     sync.Mutex
+	claims []*lockClaim
+}
+
+func (d *claimDriver) Execute(_ context.Context, sql string, args ...interface{}) (sql.Result, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !strings.Contains(sql, LOCK_COLUMN_TOKEN) || len(args) < 2 {
+		return nil, nil
+	}
+	token, owner := args[0].(string), args[1].(string)
+	released := len(args) < 3 || args[2] == 1
+	now := time.Now()
+	for _, c := range d.claims {
+		if c.token == token {
+			c.last, c.released = now, c.released || released
+			return nil, nil
+		}
+	}
+	d.claims = append(d.claims, &lockClaim{token: token, owner: owner, first: now, last: now, released: released})
+	return nil, nil
+}
+
+func (d *claimDriver) Query(_ context.Context, sql string, args ...interface{}) (Rows, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if strings.Contains(sql, defaultSchemaVersionTableName) {
+		return &valueRows{values: [][]interface{}{{lockSchema.version}}}, nil
+	}
+	// 最早且未释放、租约未过期的申请
+	ttl := time.Duration(args[0].(int64)) * time.Microsecond
+	var holder *lockClaim
+	for _, c := range d.claims {
+		if !c.released && time.Since(c.last) <= ttl && (holder == nil || c.first.Before(holder.first)) {
+			holder = c
+		}
+	}
+	if holder == nil {
+		return &valueRows{}, nil
+	}
+	if strings.Contains(sql, "any(") {
+		return &valueRows{values: [][]interface{}{{holder.token, holder.owner, holder.first}}}, nil
+	}
+	return &valueRows{values: [][]interface{}{{holder.token}}}, nil
+}
+
+func (d *claimDriver) BeginTx(context.Context) (Tx, error) {
+	return nil, New("transaction is not supported")
+}
+
+func (d *claimDriver) last(owner string) time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, c := range d.claims {
+		if c.owner == owner && !c.released {
+			return c.last
+		}
+	}
+	return time.Time{}
+}
+
+func newClaimFly(driver Driver, owner string, opts ...LockerOption) *Dbfly {
+	metaData := &mockMetaData{dbms: "Mock", tables: leaseTables()}
+	migratory := NewDefaultMigratory("mock", metaData)
+	return NewDbfly(&migratory, driver, nil, WithLocker(NewClickHouseLocker(opts...)), WithLockOwner(owner))
+}
+
+func TestClickHouseLocker_Lease(t *testing.T) {
+	ctx := context.Background()
+	driver := &claimDriver{}
+	opts := []LockerOption{WithLockTimeout(50 * time.Millisecond), WithLockRetryInterval(10 * time.Millisecond), WithLockTTL(time.Minute)}
+
+	// 持有者异常退出后遗留的申请超过租约时长，不再阻塞加锁
+	past := time.Now().Add(-time.Hour)
+	driver.claims = append(driver.claims, &lockClaim{token: "dead-pod-1", owner: "dead-pod", first: past, last: past})
+	fly := newClaimFly(driver, "pod-a", opts...)
+	unlock, err := fly.locker.Lock(ctx, fly)
+	if err != nil {
+		t.Fatalf("stale claim should be ignored: %v", err)
+	}
+	status, err := fly.LockStatus(ctx)
+	if err != nil || !status.Locked || status.Owner != "pod-a" {
+		t.Errorf("LockStatus() = %+v, %v", status, err)
+	}
+
+	// 租约有效期内其他进程无法加锁
+	other := newClaimFly(driver, "pod-b", opts...)
+	if _, err = other.locker.Lock(ctx, other); err == nil {
+		t.Fatal("lock should be held by the first locker")
+	}
+	if err = unlock(ctx, fly); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err = other.locker.Lock(ctx, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = unlock(ctx, other)
+}
+
+func TestClickHouseLocker_Heartbeat(t *testing.T) {
+	driver := &claimDriver{}
+	fly := newClaimFly(driver, "pod-a", WithLockTTL(150*time.Millisecond), WithLockHeartbeatInterval(20*time.Millisecond))
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	fly.cancel = cancel
+
+	unlock, err := fly.locker.Lock(ctx, fly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acquired := driver.last("pod-a")
+	time.Sleep(80 * time.Millisecond)
+	if renewed := driver.last("pod-a"); !renewed.After(acquired) {
+		t.Error("lease should be renewed by heartbeat")
+	}
+
+	// 被强制释放后中止迁移
+	if err = fly.ForceReleaseLock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("migration should be aborted when lock is released by others")
+	}
+	if !errors.Is(context.Cause(ctx), ErrLockLost) {
+		t.Errorf("cause = %v, want ErrLockLost", context.Cause(ctx))
+	}
+	_ = unlock(context.Background(), fly)
+}
+
+func TestClickHouseRecorder_AppendSeq(t *testing.T) {
+	ctx := context.Background()
+	columns := []*Column{{Name: COLUMN_CHANGESET_ID}, {Name: COLUMN_IS_SUCCESS}, {Name: COLUMN_UPDATED_AT}}
+	for _, c := range changeLogSchema.columns {
+		columns = append(columns, &Column{Name: c.column.ColumnName})
+	}
+	metaData := &mockMetaData{
+		dbms:    "Mock",
+		tables:  []*Table{{Name: defaultChangeLogTableName, TableType: "TABLE"}, {Name: defaultSchemaVersionTableName, TableType: "TABLE"}},
+		columns: map[string][]*Column{defaultChangeLogTableName: columns},
+	}
+	migratory := NewDefaultMigratory("mock", metaData)
+	recorder := NewClickHouseRecorder()
+
+	// 升级已有的变更记录表，只补齐追加序号列
+	driver := &schemaDriver{version: changeLogSchema.version}
+	fly := NewDbfly(&migratory, driver, nil, WithRecorder(recorder))
+	if err := recorder.InitChangeLogTable(ctx, fly); err != nil {
+		t.Fatal(err)
+	}
+	if len(driver.sqls) != 2 || !strings.Contains(driver.sqls[0], COLUMN_APPEND_SEQ) || driver.args[1][1] != clickHouseChangeLogSchema.version {
+		t.Errorf("upgrade statements = %q %v", driver.sqls, driver.args)
+	}
+
+	// 追加序号在服务端递增，当前状态以序号最大的记录为准
+	if err := recorder.NewChangeLog(ctx, fly, &ChangeLogEntry{ChangeSetId: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if sql := driver.sqls[len(driver.sqls)-1]; !strings.Contains(sql, `(SELECT ifNull(max("APPEND_SEQ"), 0) + 1 FROM "DBFLY_CHANGE_LOG")`) {
+		t.Errorf("append statement = %s", sql)
+	}
+	query := &dataDriver{}
+	fly = NewDbfly(&migratory, query, nil, WithRecorder(recorder))
+	if _, err := recorder.GetExecutedChangeSets(ctx, fly); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := recorder.History(ctx, fly); err != nil {
+		t.Fatal(err)
+	}
+	order := `tuple(ifNull("APPEND_SEQ", 0), "UPDATED_AT")`
+	if len(query.sqls) != 2 || !strings.Contains(query.sqls[0], "argMax(\"IS_SUCCESS\", "+order+")") || !strings.Contains(query.sqls[1], "ORDER BY "+order+" DESC") {
+		t.Errorf("queries = %q", query.sqls)
+	}
+}
@@ -3,15 +3,23 @@ package dbfly
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/xml"
+	"errors"
 	"io"
+	"os"
 	"regexp"
 	"strings"
+	"time"
 )
 
 const defaultEntrypoint = "dbfly.xml"
 
+// Version dbfly 版本，记录在变更记录中
+const Version = "1.0.0"
+
 type Dbfly struct {
 	entrypoint string
 	migratory  Migratory
@@ -22,8 +30,32 @@ type Dbfly struct {
 	tx         Tx // 当前事务上下文
 	logger     Logger
 	logSQLMode LogSQLMode
+	// properties 通过选项设置的属性，优先于 changelog 中定义的属性
+	properties          map[string]string
+	changelogProperties map[string]string
+	contexts            []string
+	checks              map[string]CheckFunc
+	// nativeLocker 未指定锁时使用数据库原生的锁
+	nativeLocker     bool
+	nativeLockerOpts []NativeLockerOption
+	// lockOwner 加锁时记录的持有者标识
+	lockOwner  string
+	onLockWait LockWaitFunc
+	// tag 记录在本次执行的变更记录中的标签，如发布版本号
+	tag string
+	// deploymentId 通过选项指定的部署标识，未指定时每次迁移自动生成
+	deploymentId string
+	// deployment 最近一次迁移的部署标识
+	deployment string
+	// cancel 取消当前迁移，由锁在租约丢失时调用
+	cancel context.CancelCauseFunc
+	// schemaVersionTableName 记录内部表结构版本的表
+	schemaVersionTableName string
 }
 
+// CheckFunc 自定义检查函数，通过 goCheck 条件引用
+type CheckFunc func(context.Context, *Dbfly) (bool, error)
+
 type DbflyOption func(*Dbfly)
 
 func WithEntrypoint(entrypoint string) DbflyOption {
@@ -32,6 +64,13 @@ func WithEntrypoint(entrypoint string) DbflyOption {
 	}
 }
 
+// WithSchemaVersionTableName 设置记录内部表结构版本的表名，默认为 DBFLY_SCHEMA_VERSION
+func WithSchemaVersionTableName(tableName string) DbflyOption {
+	return func(db *Dbfly) {
+		db.schemaVersionTableName = tableName
+	}
+}
+
 func WithRecorder(recorder Recorder) DbflyOption {
 	return func(db *Dbfly) {
 		db.recorder = recorder
@@ -44,6 +83,44 @@ func WithLocker(locker Locker) DbflyOption {
 	}
 }
 
+// WithTag 设置记录在本次执行的变更记录中的标签，如发布版本号
+func WithTag(tag string) DbflyOption {
+	return func(db *Dbfly) {
+		db.tag = tag
+	}
+}
+
+// WithDeploymentID 指定迁移的部署标识，记录在本次执行的变更记录中，未指定时每次迁移自动生成
+func WithDeploymentID(id string) DbflyOption {
+	return func(f *Dbfly) {
+		f.deploymentId = id
+	}
+}
+
+// WithOnLockWait 设置等待锁时的回调，每次加锁失败、等待重试前调用
+func WithOnLockWait(fn LockWaitFunc) DbflyOption {
+	return func(db *Dbfly) {
+		db.onLockWait = fn
+	}
+}
+
+// WithLockOwner 设置加锁时记录的持有者标识，默认为 主机名:进程号:实例标识，
+// 自定义的标识需在并发迁移的实例间保持唯一
+func WithLockOwner(owner string) DbflyOption {
+	return func(db *Dbfly) {
+		db.lockOwner = owner
+	}
+}
+
+// WithNativeLocker 按迁移器使用数据库原生的锁，会话级的锁需要驱动实现 SessionDriver，
+// 没有原生锁的数据库仍使用锁表
+func WithNativeLocker(opts ...NativeLockerOption) DbflyOption {
+	return func(db *Dbfly) {
+		db.nativeLocker = true
+		db.nativeLockerOpts = opts
+	}
+}
+
 func WithLogger(logger Logger) DbflyOption {
 	return func(db *Dbfly) {
 		db.logger = logger
@@ -56,10 +133,46 @@ func WithLogSQL(mode LogSQLMode) DbflyOption {
 	}
 }
 
+// WithProperty 设置属性，供 propertyEquals、propertyDefined 条件使用
+func WithProperty(name, value string) DbflyOption {
+	return func(db *Dbfly) {
+		if db.properties == nil {
+			db.properties = make(map[string]string)
+		}
+		db.properties[name] = value
+	}
+}
+
+// WithProperties 批量设置属性
+func WithProperties(properties map[string]string) DbflyOption {
+	return func(db *Dbfly) {
+		for name, value := range properties {
+			WithProperty(name, value)(db)
+		}
+	}
+}
+
+// WithContexts 设置当前运行的上下文，供 context 条件使用
+func WithContexts(contexts ...string) DbflyOption {
+	return func(db *Dbfly) {
+		db.contexts = append(db.contexts, contexts...)
+	}
+}
+
+// WithCheck 注册自定义检查函数，供 goCheck 条件按名称引用
+func WithCheck(name string, check CheckFunc) DbflyOption {
+	return func(db *Dbfly) {
+		if db.checks == nil {
+			db.checks = make(map[string]CheckFunc)
+		}
+		db.checks[name] = check
+	}
+}
+
 func NewDbfly(migratory Migratory, driver Driver, source Source, opts ...DbflyOption) *Dbfly {
 	fly := &Dbfly{
 		migratory: migratory,
-		driver:    driver,
+		driver:    bindDriver(driver, migratory.MetaData()),
 		source:    source,
 		logger:    nopLogger{},
 	}
@@ -69,11 +182,32 @@ func NewDbfly(migratory Migratory, driver Driver, source Source, opts ...DbflyOp
 	if fly.entrypoint == "" {
 		fly.entrypoint = defaultEntrypoint
 	}
+	if fly.schemaVersionTableName == "" {
+		fly.schemaVersionTableName = defaultSchemaVersionTableName
+	}
+	if fly.lockOwner == "" {
+		fly.lockOwner = defaultLockOwner()
+	}
+	// ClickHouse 不支持行级更新，默认使用只追加的记录器和锁
+	_, clickHouse := migratory.(*ClickHouseMigratory)
 	if fly.recorder == nil {
-		fly.recorder = NewDbRecorder()
+		if clickHouse {
+			fly.recorder = NewClickHouseRecorder()
+		} else {
+			fly.recorder = NewDbRecorder()
+		}
+	}
+	if fly.locker == nil && fly.nativeLocker {
+		if locker := NewNativeLocker(migratory, fly.nativeLockerOpts...); locker != nil {
+			fly.locker = locker
+		}
 	}
 	if fly.locker == nil {
-		fly.locker = NewDbLocker()
+		if clickHouse {
+			fly.locker = NewClickHouseLocker()
+		} else {
+			fly.locker = NewDbLocker()
+		}
 	}
 	return fly
 }
@@ -82,7 +216,11 @@ func (f *Dbfly) Migratory() Migratory {
 	return f.migratory
 }
 
+// Driver 执行 SQL 的驱动，处于事务中时通过当前事务执行
 func (f *Dbfly) Driver() Driver {
+	if f.tx != nil {
+		return &txDriver{tx: f.tx, migratory: f.migratory}
+	}
 	return f.driver
 }
 
@@ -94,16 +232,93 @@ func (f *Dbfly) Execute(ctx context.Context, sql string, args ...any) (sql.Resul
 	return f.driver.Execute(ctx, sql, args...)
 }
 
+// abort 以指定原因取消正在进行的迁移
+func (f *Dbfly) abort(cause error) {
+	if cancel := f.cancel; cancel != nil {
+		cancel(cause)
+	}
+}
+
+// History 按执行顺序返回全部变更记录
+func (f *Dbfly) History(ctx context.Context) ([]ChangeLogEntry, error) {
+	return f.recorder.History(ctx, f)
+}
+
+// LockOwner 加锁时记录的持有者标识
+func (f *Dbfly) LockOwner() string {
+	return f.lockOwner
+}
+
+// LockStatus 查询迁移锁的状态，锁需实现 LockAdmin
+func (f *Dbfly) LockStatus(ctx context.Context) (*LockStatus, error) {
+	admin, err := f.lockAdmin()
+	if err != nil {
+		return nil, err
+	}
+	return admin.Status(ctx, f)
+}
+
+// ForceReleaseLock 强制释放迁移锁，用于持有者异常退出后人工解锁，
+// 持有者仍在运行时会在下一次续期时因 ErrLockLost 中止
+func (f *Dbfly) ForceReleaseLock(ctx context.Context) error {
+	admin, err := f.lockAdmin()
+	if err != nil {
+		return err
+	}
+	status, err := admin.Status(ctx, f)
+	if err != nil {
+		return err
+	}
+	if !status.Locked {
+		return nil
+	}
+	f.logger.Warn("force release lock held by %q since %s", status.Owner, status.Since.Format(time.RFC3339))
+	return admin.ForceRelease(ctx, f)
+}
+
+func (f *Dbfly) lockAdmin() (LockAdmin, error) {
+	admin, ok := f.locker.(LockAdmin)
+	if !ok {
+		return nil, Wrap(ErrLockAdminUnsupported, "locker %T does not implement LockAdmin", f.locker)
+	}
+	return admin, nil
+}
+
 func (f *Dbfly) Source() Source {
 	return f.source
 }
 
+// Property 查找属性，依次查找选项设置的属性、changelog 中定义的属性和环境变量
+func (f *Dbfly) Property(name string) (string, bool) {
+	if value, ok := f.properties[name]; ok {
+		return value, true
+	}
+	if value, ok := f.changelogProperties[name]; ok {
+		return value, true
+	}
+	return os.LookupEnv(name)
+}
+
+// Contexts 当前运行的上下文
+func (f *Dbfly) Contexts() []string {
+	return f.contexts
+}
+
 // Migrate 迁移操作
 func (f *Dbfly) Migrate() error {
 	return f.MigrateContext(context.Background())
 }
 
-func (f *Dbfly) MigrateContext(ctx context.Context) (err error) {
+func (f *Dbfly) MigrateContext(ctx context.Context) error {
+	f.deployment = f.deploymentId
+	if f.deployment == "" {
+		f.deployment = newDeploymentId()
+	}
+	return f.run(ctx, "migrate", f.migrate)
+}
+
+// run 解析 changelog、获取锁并初始化变更记录表后执行 fn，结束时释放锁
+func (f *Dbfly) run(ctx context.Context, name string, fn func(context.Context, ChangeSets) error) (err error) {
 	// 同步日志配置到 Migratory
 	if m, ok := f.migratory.(*DefaultMigratory); ok {
 		m.SetLogger(f.logger)
@@ -118,7 +333,15 @@ func (f *Dbfly) MigrateContext(ctx context.Context) (err error) {
 		defer func() { f.driver = origDriver }()
 	}
 
-	f.logger.Info("migrate started, entrypoint: %s", f.entrypoint)
+	f.logger.Info("%s started, entrypoint: %s", name, f.entrypoint)
+
+	// 锁租约丢失时取消迁移
+	ctx, cancel := context.WithCancelCause(ctx)
+	f.cancel = cancel
+	defer func() {
+		f.cancel = nil
+		cancel(nil)
+	}()
 
 	var unlock Unlock
 	defer func() {
@@ -132,13 +355,21 @@ func (f *Dbfly) MigrateContext(ctx context.Context) (err error) {
 		}
 
 		if unlock != nil {
-			_ = unlock(ctx, f)
+			unlockCtx := ctx
+			if ctx.Err() != nil {
+				// 迁移已取消时仍需释放锁
+				unlockCtx = context.Background()
+			}
+			_ = unlock(unlockCtx, f)
 			f.logger.Debug("lock released")
 		}
+		if err != nil && errors.Is(context.Cause(ctx), ErrLockLost) {
+			err = context.Cause(ctx)
+		}
 	}()
 
 	// 解析 changelog
-	changeSets, err := f.parseChangelog(f.entrypoint, make(map[string]bool))
+	changeSets, err := f.parseEntrypoint()
 	if err != nil {
 		return err
 	}
@@ -154,6 +385,11 @@ func (f *Dbfly) MigrateContext(ctx context.Context) (err error) {
 		changeSetIds[cs.Id] = true
 	}
 
+	// 探测服务端类型与版本，供方言选择使用
+	if err = detectServer(ctx, f.driver, f.migratory.MetaData()); err != nil {
+		return err
+	}
+
 	// 获取锁
 	if f.locker != nil {
 		if unlock, err = f.locker.Lock(ctx, f); err != nil {
@@ -166,24 +402,43 @@ func (f *Dbfly) MigrateContext(ctx context.Context) (err error) {
 	if err = f.recorder.InitChangeLogTable(ctx, f); err != nil {
 		return err
 	}
+	return fn(ctx, changeSets)
+}
 
+// migrate 按顺序执行未执行的 changeSet
+func (f *Dbfly) migrate(ctx context.Context, changeSets ChangeSets) error {
 	// 获取已执行的 changeSet ID 集合
 	executedChangeSets, err := f.recorder.GetExecutedChangeSets(ctx, f)
 	if err != nil {
 		return err
 	}
 
-	// 按顺序执行未执行的 changeSet
+	// 执行顺序在多次迁移间全局递增
+	history, err := f.recorder.History(ctx, f)
+	if err != nil {
+		return err
+	}
 	orderExecuted := 0
+	for _, entry := range history {
+		if entry.OrderExecuted > orderExecuted {
+			orderExecuted = entry.OrderExecuted
+		}
+	}
+
+	// 按顺序执行未执行的 changeSet
+	executedCount := 0
 	skippedCount := 0
 	for _, cs := range changeSets {
-		// 获取已执行的最大 orderExecuted
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
 		if executedChangeSets[cs.Id] {
 			skippedCount++
 			continue
 		}
 
 		orderExecuted++
+		executedCount++
 		f.logger.Info("execute change set, id: %s, author: %s", cs.Id, cs.Author)
 		if err = f.executeChangeSet(ctx, cs, orderExecuted); err != nil && "SKIP" != cs.OnFail {
 			return err
@@ -193,25 +448,123 @@ func (f *Dbfly) MigrateContext(ctx context.Context) (err error) {
 		}
 	}
 
-	f.logger.Info("migrate completed, executed: %d, skipped: %d", orderExecuted, skippedCount)
+	f.logger.Info("migrate completed, deployment: %s, executed: %d, skipped: %d", f.deployment, executedCount, skippedCount)
 	return nil
 }
 
 func (f *Dbfly) executeChangeSet(ctx context.Context, cs ChangeSet, orderExecuted int) error {
+	entry := &ChangeLogEntry{
+		ChangeSetId:   cs.Id,
+		Author:        cs.Author,
+		Filename:      cs.Filename,
+		Description:   cs.Description,
+		OrderExecuted: orderExecuted,
+		Checksum:      cs.Checksum,
+		DeploymentId:  f.deployment,
+		Tag:           f.tag,
+		Contexts:      strings.Join(f.contexts, ","),
+		DbflyVersion:  Version,
+	}
+	if f.runInTransaction(cs) {
+		return f.executeChangeSetInTx(ctx, cs, entry)
+	}
+
 	// 创建变更记录
-	if err := f.recorder.NewChangeLog(ctx, f, cs.Id, cs.Author, cs.Filename, orderExecuted); err != nil {
+	if err := f.recorder.NewChangeLog(ctx, f, entry); err != nil {
 		return err
 	}
 
 	// 执行所有 DDL
+	start := time.Now()
 	for _, ddl := range cs.DDLs {
 		if err := ddl.Execute(ctx, f); err != nil {
+			entry.Duration = time.Since(start)
+			f.recordFailure(ctx, entry, err, true)
 			return err
 		}
 	}
+	entry.Duration = time.Since(start)
 
 	// 完成变更记录
-	return f.recorder.CompleteChangeLog(ctx, f, cs.Id)
+	return f.recorder.CompleteChangeLog(ctx, f, entry)
+}
+
+// runInTransaction 判断变更集是否在事务中执行
+func (f *Dbfly) runInTransaction(cs ChangeSet) bool {
+	switch cs.RunInTransaction {
+	case "true":
+		if !f.migratory.SupportsTransactionalDDL() {
+			f.logger.Warn("%s does not support transactional DDL, changeSet %s may be partially committed", f.migratory.Name(), cs.Id)
+		}
+		return true
+	case "auto":
+		return f.migratory.SupportsTransactionalDDL()
+	}
+	return false
+}
+
+// executeChangeSetInTx 在一个事务中执行变更集的全部操作及变更记录，失败时回滚并在事务外记录失败
+func (f *Dbfly) executeChangeSetInTx(ctx context.Context, cs ChangeSet, entry *ChangeLogEntry) error {
+	tx, err := f.driver.BeginTx(ctx)
+	if err != nil {
+		return Wrap(err, "failed to begin transaction of changeSet %s", cs.Id)
+	}
+	f.logger.Debug("changeSet %s runs in transaction", cs.Id)
+	start := time.Now()
+	err = func() error {
+		// 变更集中的操作与变更记录均通过事务执行
+		f.tx = tx
+		defer func() { f.tx = nil }()
+
+		if err := f.recorder.NewChangeLog(ctx, f, entry); err != nil {
+			return err
+		}
+		for _, ddl := range cs.DDLs {
+			if err := ddl.Execute(ctx, f); err != nil {
+				return err
+			}
+		}
+		entry.Duration = time.Since(start)
+		return f.recorder.CompleteChangeLog(ctx, f, entry)
+	}()
+	if err == nil {
+		if err = tx.Commit(); err == nil {
+			return nil
+		}
+		err = Wrap(err, "failed to commit transaction of changeSet %s", cs.Id)
+	} else if rbErr := tx.Rollback(); rbErr != nil {
+		f.logger.Error("changeSet %s failed: %+v, rollback also failed: %+v", cs.Id, err, rbErr)
+	} else {
+		f.logger.Debug("transaction of changeSet %s rolled back", cs.Id)
+	}
+	entry.Duration = time.Since(start)
+	// 事务中的变更记录已回滚，重新创建后记录失败
+	f.recordFailure(ctx, entry, err, false)
+	return err
+}
+
+// recordFailure 记录变更集执行失败，created 为 false 时先创建变更记录，迁移已取消时仍需记录
+func (f *Dbfly) recordFailure(ctx context.Context, entry *ChangeLogEntry, err error, created bool) {
+	if ctx.Err() != nil {
+		ctx = context.Background()
+	}
+	if !created {
+		if recordErr := f.recorder.NewChangeLog(ctx, f, entry); recordErr != nil {
+			f.logger.Warn("record change set failure failed, id: %s, error: %+v", entry.ChangeSetId, recordErr)
+			return
+		}
+	}
+	entry.fail(err)
+	if recordErr := f.recorder.FailChangeLog(ctx, f, entry); recordErr != nil {
+		f.logger.Warn("record change set failure failed, id: %s, error: %+v", entry.ChangeSetId, recordErr)
+	}
+}
+
+// parseEntrypoint 从入口文件开始解析 changelog，每次解析前清空上次解析得到的属性，
+// 使 changelog 修改后的属性定义生效
+func (f *Dbfly) parseEntrypoint() (ChangeSets, error) {
+	f.changelogProperties = nil
+	return f.parseChangelog(f.entrypoint, make(map[string]bool))
 }
 
 // parseChangelog 递归解析 changelog 文件
@@ -244,6 +597,8 @@ func (f *Dbfly) parseXmlContent(filename string, content []byte, visited map[str
 	var currentChangeSet *ChangeSet
 
 	for {
+		// 元素开始前的偏移，用于截取 changeSet 的原始内容计算校验和
+		offset := decoder.InputOffset()
 		token, err := decoder.Token()
 		if err != nil {
 			if err == io.EOF {
@@ -258,6 +613,21 @@ func (f *Dbfly) parseXmlContent(filename string, content []byte, visited map[str
 			switch name {
 			case "dbfly":
 				// 根元素，继续解析子元素
+			case "property":
+				var property PropertyNode
+				if err = decoder.DecodeElement(&property, &ele); err != nil {
+					return nil, err
+				}
+				if property.Name == "" {
+					return nil, New("property name is required")
+				}
+				// 先定义的属性生效
+				if f.changelogProperties == nil {
+					f.changelogProperties = make(map[string]string)
+				}
+				if _, ok := f.changelogProperties[property.Name]; !ok {
+					f.changelogProperties[property.Name] = property.Value
+				}
 			case "changeSet":
 				// 解析 changeSet 元素
 				cs := &ChangeSetNode{}
@@ -272,12 +642,23 @@ func (f *Dbfly) parseXmlContent(filename string, content []byte, visited map[str
 				if cs.OnFail == "" {
 					cs.OnFail = "HALT"
 				}
+				switch cs.RunInTransaction {
+				case "":
+					cs.RunInTransaction = "false"
+				case "true", "false", "auto":
+				default:
+					return nil, New("invalid runInTransaction of changeSet %s: %s (allowed: true, false, auto)", cs.Id, cs.RunInTransaction)
+				}
 				currentChangeSet = &ChangeSet{
-					Id:       cs.Id,
-					Author:   cs.Author,
-					OnFail:   cs.OnFail,
-					Filename: filename,
-					DDLs:     cs.DDLs,
+					Id:               cs.Id,
+					Author:           cs.Author,
+					OnFail:           cs.OnFail,
+					Filename:         filename,
+					Description:      cs.description(),
+					Checksum:         checksum(content[offset:decoder.InputOffset()]),
+					RunInTransaction: cs.RunInTransaction,
+					DDLs:             cs.DDLs,
+					Rollback:         cs.Rollback,
 				}
 				changeSets = append(changeSets, *currentChangeSet)
 			case "include":
@@ -314,6 +695,13 @@ func (f *Dbfly) parseXmlContent(filename string, content []byte, visited map[str
 	return changeSets, nil
 }
 
+// checksum 计算 changeSet 定义的校验和，忽略换行符差异
+func checksum(content []byte) string {
+	content = bytes.ReplaceAll(bytes.TrimSpace(content), []byte("\r\n"), []byte("\n"))
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
 // isValidChangeSetId 验证 changeSet id 格式
 func isValidChangeSetId(id string) bool {
 	if id == "" {
@@ -340,10 +728,13 @@ func isDDLElement(name string) bool {
 		"alterTableComment": true,
 		"sqlFile":           true,
 		"insert":            true,
+		"loadData":          true,
+		"loadUpdateData":    true,
 		"update":            true,
 		"delete":            true,
 		"sqlInline":         true,
 		"transaction":       true,
+		"savepoint":         true,
 	}
 	return ddlElements[name]
 }
@@ -0,0 +1,158 @@
+package dbfly
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// ErrDeploymentNotFound 部署不存在或没有可回滚的变更集
+var ErrDeploymentNotFound = errors.New("deployment not found")
+
+// Deployment 一次迁移执行的变更集
+type Deployment struct {
+	Id  string
+	Tag string
+	// StartedAt 第一个变更集的开始时间
+	StartedAt time.Time
+	// FinishedAt 最后一条变更记录的更新时间
+	FinishedAt time.Time
+	// Entries 按执行顺序排列的变更记录
+	Entries []ChangeLogEntry
+}
+
+// Executed 部署中仍处于成功状态、可以回滚的变更记录
+func (d *Deployment) Executed() []ChangeLogEntry {
+	var entries []ChangeLogEntry
+	for _, entry := range d.Entries {
+		if entry.ExecType == ExecTypeExecuted {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// MigrationStatus 按部署分组的迁移状态
+type MigrationStatus struct {
+	// Deployments 按执行顺序排列的部署，早期版本未记录部署标识的变更归入标识为空的部署
+	Deployments []Deployment
+	// Pending changelog 中尚未执行的变更集
+	Pending ChangeSets
+}
+
+// newDeploymentId 生成部署标识：执行时间加随机后缀
+func newDeploymentId() string {
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return time.Now().Format("20060102150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// groupDeployments 将按执行顺序排列的变更记录按部署标识分组
+func groupDeployments(history []ChangeLogEntry) []Deployment {
+	var deployments []Deployment
+	index := make(map[string]int)
+	for _, entry := range history {
+		i, ok := index[entry.DeploymentId]
+		if !ok {
+			i = len(deployments)
+			index[entry.DeploymentId] = i
+			deployments = append(deployments, Deployment{Id: entry.DeploymentId, Tag: entry.Tag, StartedAt: entry.CreatedAt})
+		}
+		deployment := &deployments[i]
+		deployment.Entries = append(deployment.Entries, entry)
+		if entry.CreatedAt.Before(deployment.StartedAt) {
+			deployment.StartedAt = entry.CreatedAt
+		}
+		if entry.UpdatedAt.After(deployment.FinishedAt) {
+			deployment.FinishedAt = entry.UpdatedAt
+		}
+	}
+	return deployments
+}
+
+// DeploymentID 返回最近一次迁移的部署标识
+func (f *Dbfly) DeploymentID() string {
+	return f.deployment
+}
+
+// Status 查询按部署分组的执行历史及尚未执行的变更集
+func (f *Dbfly) Status(ctx context.Context) (*MigrationStatus, error) {
+	changeSets, err := f.parseEntrypoint()
+	if err != nil {
+		return nil, err
+	}
+	history, err := f.recorder.History(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	executed := make(map[string]bool)
+	for _, entry := range history {
+		executed[entry.ChangeSetId] = entry.Success
+	}
+	status := &MigrationStatus{Deployments: groupDeployments(history)}
+	for _, cs := range changeSets {
+		if !executed[cs.Id] {
+			status.Pending = append(status.Pending, cs)
+		}
+	}
+	return status, nil
+}
+
+// RollbackDeployment 按执行顺序的逆序执行部署中各变更集的回滚操作，并将变更记录标记为已回滚，
+// 回滚后的变更集在下次迁移时重新执行。id 为空时回滚最近一次仍有成功变更集的部署。
+// 任一变更集未定义回滚操作时不执行任何回滚
+func (f *Dbfly) RollbackDeployment(ctx context.Context, id string) error {
+	return f.run(ctx, "rollback", func(ctx context.Context, changeSets ChangeSets) error {
+		history, err := f.recorder.History(ctx, f)
+		if err != nil {
+			return err
+		}
+		var entries []ChangeLogEntry
+		deployments := groupDeployments(history)
+		for i := len(deployments) - 1; i >= 0; i-- {
+			deployment := &deployments[i]
+			if (id == "" && deployment.Id != "") || (id != "" && deployment.Id == id) {
+				if entries = deployment.Executed(); len(entries) > 0 {
+					id = deployment.Id
+					break
+				}
+			}
+		}
+		if len(entries) == 0 {
+			return New("%w: %q", ErrDeploymentNotFound, id)
+		}
+
+		// 先检查全部变更集都定义了回滚操作
+		definitions := make(map[string]ChangeSet, len(changeSets))
+		for _, cs := range changeSets {
+			definitions[cs.Id] = cs
+		}
+		for _, entry := range entries {
+			cs, ok := definitions[entry.ChangeSetId]
+			if !ok {
+				return New("changeSet %s of deployment %s is not defined in changelog", entry.ChangeSetId, id)
+			}
+			if cs.Rollback == nil {
+				return New("changeSet %s of deployment %s has no rollback defined", entry.ChangeSetId, id)
+			}
+		}
+
+		for i := len(entries) - 1; i >= 0; i-- {
+			if ctx.Err() != nil {
+				return context.Cause(ctx)
+			}
+			entry := entries[i]
+			f.logger.Info("rollback change set, id: %s, deployment: %s", entry.ChangeSetId, id)
+			if err = definitions[entry.ChangeSetId].Rollback.Execute(ctx, f); err != nil {
+				return Wrap(err, "rollback changeSet %s failed", entry.ChangeSetId)
+			}
+			if err = f.recorder.RollbackChangeLog(ctx, f, &entry); err != nil {
+				return err
+			}
+		}
+		f.logger.Info("rollback completed, deployment: %s, rolled back: %d", id, len(entries))
+		return nil
+	})
+}
@@ -0,0 +1,120 @@
+package dbfly
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func deploymentChangelog(changeSets ...string) []byte {
+	var builder strings.Builder
+	builder.WriteString("<dbfly>")
+	for _, id := range changeSets {
+		builder.WriteString(`<changeSet id="` + id + `"><sqlInline><default>CREATE TABLE ` + id + `(id INT)</default></sqlInline>`)
+		if id != "norollback" {
+			builder.WriteString(`<rollback><sqlInline><default>DROP TABLE ` + id + `</default></sqlInline></rollback>`)
+		}
+		builder.WriteString("</changeSet>")
+	}
+	builder.WriteString("</dbfly>")
+	return []byte(builder.String())
+}
+
+// dropped 返回执行过的 DROP 语句
+func dropped(driver *recordDriver) []string {
+	var sqls []string
+	for _, sql := range driver.sqls {
+		if strings.HasPrefix(sql, "DROP") {
+			sqls = append(sqls, sql)
+		}
+	}
+	return sqls
+}
+
+func TestDbfly_RollbackDeployment(t *testing.T) {
+	ctx := context.Background()
+	fs := fstest.MapFS{"dbfly.xml": {Data: deploymentChangelog("a", "b")}}
+	migratory := NewDefaultMigratory("mock", &mockMetaData{dbms: "Mock"})
+	driver := &recordDriver{}
+	recorder := &mockRecorder{}
+	fly := NewDbfly(&migratory, driver, NewFSSource(fs), WithRecorder(recorder), WithLocker(NewMutexLocker()))
+
+	if err := fly.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	first := fly.DeploymentID()
+	fs["dbfly.xml"] = &fstest.MapFile{Data: deploymentChangelog("a", "b", "c")}
+	if err := fly.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	second := fly.DeploymentID()
+	if first == "" || first == second {
+		t.Fatalf("deployment ids = %q, %q", first, second)
+	}
+
+	status, err := fly.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Deployments) != 2 || len(status.Pending) != 0 ||
+		status.Deployments[0].Id != first || len(status.Deployments[0].Entries) != 2 ||
+		status.Deployments[1].Id != second || status.Deployments[1].Entries[0].ChangeSetId != "c" {
+		t.Fatalf("Status() = %+v", status)
+	}
+
+	// 未指定标识时回滚最近一次部署
+	if err = fly.RollbackDeployment(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := dropped(driver); !reflect.DeepEqual(got, []string{"DROP TABLE c"}) {
+		t.Errorf("rollback last deployment executed %q", got)
+	}
+	if status, _ = fly.Status(ctx); len(status.Pending) != 1 || status.Pending[0].Id != "c" ||
+		status.Deployments[1].Entries[0].ExecType != ExecTypeRolledBack {
+		t.Errorf("Status() after rollback = %+v", status)
+	}
+
+	// 按执行顺序的逆序回滚
+	driver.sqls = nil
+	if err = fly.RollbackDeployment(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	if got := dropped(driver); !reflect.DeepEqual(got, []string{"DROP TABLE b", "DROP TABLE a"}) {
+		t.Errorf("rollback first deployment executed %q", got)
+	}
+	if err = fly.RollbackDeployment(ctx, first); !errors.Is(err, ErrDeploymentNotFound) {
+		t.Errorf("rollback twice error = %v, want ErrDeploymentNotFound", err)
+	}
+
+	// 回滚后重新迁移
+	if err = fly.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.executed) != 3 {
+		t.Errorf("executed after re-migrate = %v", recorder.executed)
+	}
+}
+
+func TestDbfly_RollbackDeploymentWithoutRollback(t *testing.T) {
+	fs := fstest.MapFS{"dbfly.xml": {Data: deploymentChangelog("a", "norollback")}}
+	migratory := NewDefaultMigratory("mock", &mockMetaData{dbms: "Mock"})
+	driver := &recordDriver{}
+	fly := NewDbfly(&migratory, driver, NewFSSource(fs), WithRecorder(&mockRecorder{}), WithLocker(NewMutexLocker()),
+		WithDeploymentID("release-1"))
+	if err := fly.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	if fly.DeploymentID() != "release-1" {
+		t.Errorf("DeploymentID() = %q", fly.DeploymentID())
+	}
+	err := fly.RollbackDeployment(context.Background(), "release-1")
+	if err == nil || !strings.Contains(err.Error(), "no rollback defined") {
+		t.Errorf("RollbackDeployment() error = %v", err)
+	}
+	if got := dropped(driver); len(got) != 0 {
+		t.Errorf("nothing should be rolled back, executed %q", got)
+	}
+}
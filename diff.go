@@ -0,0 +1,462 @@
+package dbfly
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// DiffKind 差异类型
+type DiffKind string
+
+const (
+	DiffMissing    DiffKind = "MISSING"    // 目标库缺少的对象
+	DiffUnexpected DiffKind = "UNEXPECTED" // 目标库多出的对象
+	DiffChanged    DiffKind = "CHANGED"    // 目标库中定义不一致的对象
+)
+
+// 差异对象类型
+const (
+	DiffObjectTable      = "TABLE"
+	DiffObjectView       = "VIEW"
+	DiffObjectColumn     = "COLUMN"
+	DiffObjectIndex      = "INDEX"
+	DiffObjectPrimaryKey = "PRIMARY_KEY"
+	DiffObjectForeignKey = "FOREIGN_KEY"
+)
+
+// Difference 单个结构差异
+type Difference struct {
+	Kind      DiffKind `json:"kind" yaml:"kind"`
+	Object    string   `json:"object" yaml:"object"`
+	TableName string   `json:"tableName" yaml:"tableName"`
+	Name      string   `json:"name,omitempty" yaml:"name,omitempty"`
+	Expected  string   `json:"expected,omitempty" yaml:"expected,omitempty"`
+	Actual    string   `json:"actual,omitempty" yaml:"actual,omitempty"`
+}
+
+func (d *Difference) String() string {
+	var sign string
+	switch d.Kind {
+	case DiffMissing:
+		sign = "+"
+	case DiffUnexpected:
+		sign = "-"
+	default:
+		sign = "~"
+	}
+	name := d.TableName
+	if d.Name != "" {
+		name += "." + d.Name
+	}
+	str := fmt.Sprintf("%s %s %s", sign, d.Object, name)
+	switch d.Kind {
+	case DiffMissing:
+		str += ": missing"
+	case DiffUnexpected:
+		str += ": unexpected"
+	}
+	if d.Expected != "" || d.Actual != "" {
+		str += fmt.Sprintf(": %s -> %s", d.Actual, d.Expected)
+	}
+	return str
+}
+
+// DiffResult 结构对比结果，Changelog 应用到目标库后可使其与源库一致
+type DiffResult struct {
+	Differences []*Difference
+	Changelog   *ChangelogNode
+}
+
+// HasDifferences 是否存在差异
+func (r *DiffResult) HasDifferences() bool {
+	return len(r.Differences) > 0
+}
+
+// Report 输出可读的差异报告
+func (r *DiffResult) Report() string {
+	if !r.HasDifferences() {
+		return "no differences"
+	}
+	var builder strings.Builder
+	for _, difference := range r.Differences {
+		builder.WriteString(difference.String())
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+// DiffDatabase 参与结构对比的数据库
+type DiffDatabase struct {
+	Driver   Driver
+	MetaData DatabaseMetaData
+}
+
+type diffOptions struct {
+	author     string
+	idPrefix   string
+	dropTables bool
+	// internalTables 除默认名称外不参与对比的 dbfly 内部表
+	internalTables []string
+}
+
+type DiffOption func(*diffOptions)
+
+// WithDiffAuthor 设置生成的changeSet作者
+func WithDiffAuthor(author string) DiffOption {
+	return func(o *diffOptions) {
+		o.author = author
+	}
+}
+
+// WithDiffIdPrefix 设置生成的changeSet标识前缀
+func WithDiffIdPrefix(prefix string) DiffOption {
+	return func(o *diffOptions) {
+		o.idPrefix = prefix
+	}
+}
+
+// WithDiffDropTables 为目标库多出的表生成dropTable节点，默认仅在报告中列出
+func WithDiffDropTables() DiffOption {
+	return func(o *diffOptions) {
+		o.dropTables = true
+	}
+}
+
+// WithDiffInternalTables 设置修改过名称的记录表、锁表和结构版本表，与默认名称的内部表一样不参与对比
+func WithDiffInternalTables(tables ...string) DiffOption {
+	return func(o *diffOptions) {
+		o.internalTables = append(o.internalTables, tables...)
+	}
+}
+
+func newDiffOptions(opts []DiffOption) *diffOptions {
+	options := &diffOptions{idPrefix: "diff-"}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// Diff 对比源库与目标库的结构，生成使目标库与源库一致的changelog和差异报告，两个数据库可以是不同方言
+func Diff(ctx context.Context, source, target *DiffDatabase, opts ...DiffOption) (*DiffResult, error) {
+	sourceSnapshot, err := Snapshot(ctx, source.Driver, source.MetaData)
+	if err != nil {
+		return nil, Wrap(err, "snapshot source database failed")
+	}
+	targetSnapshot, err := Snapshot(ctx, target.Driver, target.MetaData)
+	if err != nil {
+		return nil, Wrap(err, "snapshot target database failed")
+	}
+	internalTables := newDiffOptions(opts).internalTables
+	sourceSnapshot.Tables = excludeDbflyTables(sourceSnapshot.Tables, internalTables)
+	targetSnapshot.Tables = excludeDbflyTables(targetSnapshot.Tables, internalTables)
+	return diffSnapshots(source.MetaData, sourceSnapshot, target.MetaData, targetSnapshot, opts...), nil
+}
+
+// excludeDbflyTables 排除 dbfly 默认的记录表、锁表、结构版本表以及 internalTables 中的表
+func excludeDbflyTables(tables []*TableSnapshot, internalTables []string) []*TableSnapshot {
+	result := tables[:0]
+	for _, table := range tables {
+		if !isDbflyTable(table.Name, internalTables) {
+			result = append(result, table)
+		}
+	}
+	return result
+}
+
+func diffSnapshots(sourceMeta DatabaseMetaData, source *SchemaSnapshot, targetMeta DatabaseMetaData, target *SchemaSnapshot, opts ...DiffOption) *DiffResult {
+	options := newDiffOptions(opts)
+	d := &differ{
+		sourceMeta: sourceMeta,
+		targetMeta: targetMeta,
+		sameDbms:   sourceMeta.Dbms() == targetMeta.Dbms(),
+		options:    options,
+		result:     &DiffResult{Changelog: NewChangelogNode()},
+	}
+	for _, table := range source.Tables {
+		if targetTable := target.Table(table.Name); targetTable == nil {
+			d.add(&Difference{Kind: DiffMissing, Object: DiffObjectTable, TableName: table.Name})
+			d.addChangeSet(table.Name, createTableDDLs(sourceMeta, table))
+		} else {
+			d.addChangeSet(table.Name, d.diffTable(table, targetTable))
+		}
+	}
+	for _, table := range target.Tables {
+		if source.Table(table.Name) != nil {
+			continue
+		}
+		d.add(&Difference{Kind: DiffUnexpected, Object: DiffObjectTable, TableName: table.Name})
+		if options.dropTables {
+			d.addChangeSet(table.Name, []DDL{&DropTableNode{TableName: table.Name}})
+		}
+	}
+	d.diffViews(source.Views, target.Views)
+	return d.result
+}
+
+type differ struct {
+	sourceMeta DatabaseMetaData
+	targetMeta DatabaseMetaData
+	sameDbms   bool
+	options    *diffOptions
+	result     *DiffResult
+}
+
+func (d *differ) add(difference *Difference) {
+	d.result.Differences = append(d.result.Differences, difference)
+}
+
+func (d *differ) addChangeSet(tableName string, ddls []DDL) {
+	if len(ddls) == 0 {
+		return
+	}
+	d.result.Changelog.ChangeSets = append(d.result.Changelog.ChangeSets, &ChangeSetNode{
+		Id:     d.options.idPrefix + sanitizeChangeSetId(tableName),
+		Author: d.options.author,
+		DDLs:   ddls,
+	})
+}
+
+// diffTable 对比同名表，按删除索引、删除主键、增改删列、创建主键、创建索引的顺序生成节点
+func (d *differ) diffTable(source, target *TableSnapshot) []DDL {
+	var dropIndexes, columns, createIndexes []DDL
+	var dropPk, createPk DDL
+
+	for _, column := range source.Columns {
+		node := newColumnNode(d.sourceMeta, column)
+		targetColumn := target.Column(column.Name)
+		if targetColumn == nil {
+			d.add(&Difference{Kind: DiffMissing, Object: DiffObjectColumn, TableName: target.Name, Name: column.Name})
+			columns = append(columns, &AddColumnNode{TableName: target.Name, Columns: []*AddColumnColumnNode{toAddColumnColumnNode(node)}})
+			continue
+		}
+		targetNode := newColumnNode(d.targetMeta, targetColumn)
+		if d.sameColumn(node, targetNode) {
+			continue
+		}
+		d.add(&Difference{Kind: DiffChanged, Object: DiffObjectColumn, TableName: target.Name, Name: targetColumn.Name,
+			Expected: describeColumnNode(node), Actual: describeColumnNode(targetNode)})
+		columns = append(columns, &AlterColumnNode{TableName: target.Name, ColumnName: targetColumn.Name, Column: toAlterColumnColumnNode(node)})
+	}
+	for _, column := range target.Columns {
+		if source.Column(column.Name) == nil {
+			d.add(&Difference{Kind: DiffUnexpected, Object: DiffObjectColumn, TableName: target.Name, Name: column.Name})
+			columns = append(columns, &DropColumnNode{TableName: target.Name, ColumnName: column.Name})
+		}
+	}
+
+	switch {
+	case source.PrimaryKey == nil && target.PrimaryKey != nil:
+		d.add(&Difference{Kind: DiffUnexpected, Object: DiffObjectPrimaryKey, TableName: target.Name, Name: target.PrimaryKey.Name})
+		dropPk = &DropPrimaryKeyNode{TableName: target.Name}
+	case source.PrimaryKey != nil && target.PrimaryKey == nil:
+		d.add(&Difference{Kind: DiffMissing, Object: DiffObjectPrimaryKey, TableName: target.Name, Name: source.PrimaryKey.Name})
+		createPk = newCreatePrimaryKeyNode(target.Name, source.PrimaryKey)
+	case source.PrimaryKey != nil && !equalFoldSlice(source.PrimaryKey.Columns, target.PrimaryKey.Columns):
+		d.add(&Difference{Kind: DiffChanged, Object: DiffObjectPrimaryKey, TableName: target.Name, Name: target.PrimaryKey.Name,
+			Expected: strings.Join(source.PrimaryKey.Columns, ", "), Actual: strings.Join(target.PrimaryKey.Columns, ", ")})
+		dropPk = &DropPrimaryKeyNode{TableName: target.Name}
+		createPk = newCreatePrimaryKeyNode(target.Name, source.PrimaryKey)
+	}
+
+	for _, index := range source.Indexes {
+		targetIndex := target.Index(index.Name)
+		if targetIndex == nil {
+			d.add(&Difference{Kind: DiffMissing, Object: DiffObjectIndex, TableName: target.Name, Name: index.Name})
+		} else if targetIndex.Unique != index.Unique || !equalFoldSlice(targetIndex.Columns, index.Columns) {
+			d.add(&Difference{Kind: DiffChanged, Object: DiffObjectIndex, TableName: target.Name, Name: targetIndex.Name,
+				Expected: describeIndex(index), Actual: describeIndex(targetIndex)})
+			dropIndexes = append(dropIndexes, &DropIndexNode{TableName: target.Name, IndexName: targetIndex.Name})
+		} else {
+			continue
+		}
+		createIndexes = append(createIndexes, &CreateIndexNode{TableName: target.Name, IndexName: index.Name, Unique: index.Unique, Columns: indexColumnNodes(index.Columns)})
+	}
+	for _, index := range target.Indexes {
+		if source.Index(index.Name) == nil {
+			d.add(&Difference{Kind: DiffUnexpected, Object: DiffObjectIndex, TableName: target.Name, Name: index.Name})
+			dropIndexes = append(dropIndexes, &DropIndexNode{TableName: target.Name, IndexName: index.Name})
+		}
+	}
+
+	// 外键没有对应的节点，仅在报告中列出
+	d.diffForeignKeys(source, target)
+
+	ddls := dropIndexes
+	if dropPk != nil {
+		ddls = append(ddls, dropPk)
+	}
+	ddls = append(ddls, columns...)
+	if createPk != nil {
+		ddls = append(ddls, createPk)
+	}
+	ddls = append(ddls, createIndexes...)
+	if source.Comment != target.Comment {
+		d.add(&Difference{Kind: DiffChanged, Object: DiffObjectTable, TableName: target.Name, Expected: source.Comment, Actual: target.Comment})
+		ddls = append(ddls, &AlterTableCommentNode{TableName: target.Name, Comment: source.Comment})
+	}
+	return ddls
+}
+
+func (d *differ) diffForeignKeys(source, target *TableSnapshot) {
+	find := func(foreignKeys []*ForeignKeySnapshot, name string) *ForeignKeySnapshot {
+		for _, fk := range foreignKeys {
+			if strings.EqualFold(fk.Name, name) {
+				return fk
+			}
+		}
+		return nil
+	}
+	for _, fk := range source.ForeignKeys {
+		targetFk := find(target.ForeignKeys, fk.Name)
+		if targetFk == nil {
+			d.add(&Difference{Kind: DiffMissing, Object: DiffObjectForeignKey, TableName: target.Name, Name: fk.Name})
+		} else if !strings.EqualFold(fk.RefTable, targetFk.RefTable) || !equalFoldSlice(fk.Columns, targetFk.Columns) || !equalFoldSlice(fk.RefColumns, targetFk.RefColumns) {
+			d.add(&Difference{Kind: DiffChanged, Object: DiffObjectForeignKey, TableName: target.Name, Name: targetFk.Name,
+				Expected: describeForeignKey(fk), Actual: describeForeignKey(targetFk)})
+		}
+	}
+	for _, fk := range target.ForeignKeys {
+		if find(source.ForeignKeys, fk.Name) == nil {
+			d.add(&Difference{Kind: DiffUnexpected, Object: DiffObjectForeignKey, TableName: target.Name, Name: fk.Name})
+		}
+	}
+}
+
+// diffViews 视图没有对应的节点，仅在报告中列出
+func (d *differ) diffViews(source, target []*ViewSnapshot) {
+	find := func(views []*ViewSnapshot, name string) *ViewSnapshot {
+		for _, view := range views {
+			if strings.EqualFold(view.Name, name) {
+				return view
+			}
+		}
+		return nil
+	}
+	for _, view := range source {
+		if find(target, view.Name) == nil {
+			d.add(&Difference{Kind: DiffMissing, Object: DiffObjectView, TableName: view.Name})
+		}
+	}
+	for _, view := range target {
+		if find(source, view.Name) == nil {
+			d.add(&Difference{Kind: DiffUnexpected, Object: DiffObjectView, TableName: view.Name})
+		}
+	}
+}
+
+// sameColumn 比较两个列定义，跨数据库时仅比较标准类型，不比较无法移植的默认值
+func (d *differ) sameColumn(source, target *ColumnNode) bool {
+	if source.Notnull != target.Notnull || source.Comment != target.Comment {
+		return false
+	}
+	sourceDbms, targetDbms := firstColumnDbms(source), firstColumnDbms(target)
+	if d.sameDbms && (sourceDbms != nil || targetDbms != nil) {
+		// 同一数据库存在无法映射的定义时比较原生定义
+		return sourceDbms != nil && targetDbms != nil && sourceDbms.DataType == targetDbms.DataType &&
+			defaultText(source) == defaultText(target) &&
+			sourceDbms.DefaultValue+sourceDbms.DefaultOriginValue == targetDbms.DefaultValue+targetDbms.DefaultOriginValue
+	}
+	if source.DataType != target.DataType || source.MaxLength != target.MaxLength || source.NumericScale != target.NumericScale {
+		return false
+	}
+	if sourceDbms == nil && targetDbms == nil {
+		return defaultText(source) == defaultText(target)
+	}
+	return true
+}
+
+// defaultText 默认值的文本，数值等字面量在不同数据库中可能带引号也可能不带
+func defaultText(node *ColumnNode) string {
+	if node.DefaultValue != "" {
+		return node.DefaultValue
+	}
+	return node.DefaultOriginValue
+}
+
+func firstColumnDbms(node *ColumnNode) *ColumnDbmsNode {
+	if len(node.ColumnDbms) == 0 {
+		return nil
+	}
+	return node.ColumnDbms[0]
+}
+
+func newCreatePrimaryKeyNode(tableName string, pk *PrimaryKeySnapshot) *CreatePrimaryKeyNode {
+	keyName := pk.Name
+	if keyName == "" {
+		keyName = "pk_" + tableName
+	}
+	return &CreatePrimaryKeyNode{TableName: tableName, KeyName: keyName, Columns: indexColumnNodes(pk.Columns)}
+}
+
+func toAddColumnColumnNode(node *ColumnNode) *AddColumnColumnNode {
+	return &AddColumnColumnNode{
+		ColumnName:         node.ColumnName,
+		DataType:           node.DataType,
+		MaxLength:          node.MaxLength,
+		NumericScale:       node.NumericScale,
+		Notnull:            node.Notnull,
+		DefaultValue:       node.DefaultValue,
+		DefaultOriginValue: node.DefaultOriginValue,
+		Comment:            node.Comment,
+		ColumnDbms:         node.ColumnDbms,
+	}
+}
+
+func toAlterColumnColumnNode(node *ColumnNode) *AlterColumnColumnNode {
+	return &AlterColumnColumnNode{
+		DataType:           node.DataType,
+		MaxLength:          node.MaxLength,
+		NumericScale:       node.NumericScale,
+		Notnull:            node.Notnull,
+		DefaultValue:       node.DefaultValue,
+		DefaultOriginValue: node.DefaultOriginValue,
+		Comment:            node.Comment,
+		ColumnDbms:         node.ColumnDbms,
+	}
+}
+
+// describeColumnNode 输出列定义的简要描述，用于差异报告
+func describeColumnNode(node *ColumnNode) string {
+	var builder strings.Builder
+	if dbms := firstColumnDbms(node); dbms != nil {
+		builder.WriteString(dbms.DataType)
+	} else {
+		builder.WriteString(node.DataType)
+		switch {
+		case node.NumericScale > 0:
+			fmt.Fprintf(&builder, "(%d, %d)", node.MaxLength, node.NumericScale)
+		case node.MaxLength > 0:
+			fmt.Fprintf(&builder, "(%d)", node.MaxLength)
+		}
+	}
+	if node.Notnull {
+		builder.WriteString(" NOT NULL")
+	} else {
+		builder.WriteString(" NULL")
+	}
+	if node.DefaultValue != "" {
+		fmt.Fprintf(&builder, " DEFAULT '%s'", node.DefaultValue)
+	} else if node.DefaultOriginValue != "" {
+		fmt.Fprintf(&builder, " DEFAULT %s", node.DefaultOriginValue)
+	} else if dbms := firstColumnDbms(node); dbms != nil && dbms.DefaultOriginValue != "" {
+		fmt.Fprintf(&builder, " DEFAULT %s", dbms.DefaultOriginValue)
+	}
+	if node.Comment != "" {
+		fmt.Fprintf(&builder, " COMMENT '%s'", node.Comment)
+	}
+	return builder.String()
+}
+
+func describeIndex(index *IndexSnapshot) string {
+	str := "(" + strings.Join(index.Columns, ", ") + ")"
+	if index.Unique {
+		return "UNIQUE " + str
+	}
+	return str
+}
+
+func describeForeignKey(fk *ForeignKeySnapshot) string {
+	return fmt.Sprintf("(%s) REFERENCES %s(%s)", strings.Join(fk.Columns, ", "), fk.RefTable, strings.Join(fk.RefColumns, ", "))
+}
@@ -0,0 +1,58 @@
+package dbfly
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	source := newMockMetaData()
+	source.tables = append(source.tables, &Table{Name: "logs", TableType: "TABLE"})
+	source.columns["logs"] = []*Column{{Name: "id", DataType: "BIGINT", Ordinal: 1}}
+	source.columns["users"] = append(source.columns["users"], &Column{Name: "email", DataType: "VARCHAR", MaxLength: 200, Nullable: true, Ordinal: 3})
+	source.indexes["users"] = append(source.indexes["users"], &Index{Name: "uk_email", ColumnName: "email", Unique: true, Ordinal: 1})
+
+	target := newMockMetaData()
+	target.columns["users"][0] = &Column{Name: "name", DataType: "VARCHAR", MaxLength: 50, Nullable: true, Ordinal: 2}
+	target.columns["orders"] = append(target.columns["orders"], &Column{Name: "remark", DataType: "TEXT", Nullable: true, Ordinal: 3})
+
+	result, err := Diff(context.Background(), &DiffDatabase{Driver: &SqlDriver{}, MetaData: source}, &DiffDatabase{Driver: &SqlDriver{}, MetaData: target})
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	report := result.Report()
+	for _, want := range []string{
+		"+ TABLE logs: missing",
+		"- COLUMN orders.remark: unexpected",
+		"~ COLUMN users.name: VARCHAR(50) NULL -> VARCHAR(100) NULL",
+		"+ COLUMN users.email: missing",
+		"+ INDEX users.uk_email: missing",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+
+	var names []string
+	for _, changeSet := range result.Changelog.ChangeSets {
+		for _, ddl := range changeSet.DDLs {
+			names = append(names, ddlElementName(ddl))
+		}
+	}
+	want := "createTable,dropColumn,alterColumn,addColumn,createIndex"
+	if strings.Join(names, ",") != want {
+		t.Errorf("changelog nodes = %v, want %s", names, want)
+	}
+}
+
+func TestDiff_NoDifferences(t *testing.T) {
+	db := &DiffDatabase{Driver: &SqlDriver{}, MetaData: newMockMetaData()}
+	result, err := Diff(context.Background(), db, db)
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if result.HasDifferences() || len(result.Changelog.ChangeSets) != 0 {
+		t.Errorf("expected no differences, got %s", result.Report())
+	}
+}
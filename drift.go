@@ -0,0 +1,471 @@
+package dbfly
+
+import (
+	"context"
+	"strings"
+)
+
+// DriftResult 漂移检测结果，列出实际数据库相对于changelog预期结构的差异
+type DriftResult struct {
+	Differences []*Difference
+	// Unsimulated 包含无法模拟的SQL节点（sqlInline、sqlFile）或依赖数据库当前状态的执行条件的changeSet，其结构变更不在预期结构中
+	Unsimulated []string
+}
+
+// HasDrift 是否存在漂移，可作为迁移前的检查条件
+func (r *DriftResult) HasDrift() bool {
+	return len(r.Differences) > 0
+}
+
+// Report 输出可读的漂移报告
+func (r *DriftResult) Report() string {
+	var builder strings.Builder
+	if !r.HasDrift() {
+		builder.WriteString("no drift\n")
+	}
+	for _, difference := range r.Differences {
+		builder.WriteString(difference.String())
+		builder.WriteString("\n")
+	}
+	if len(r.Unsimulated) > 0 {
+		builder.WriteString("unsimulated changeSets: ")
+		builder.WriteString(strings.Join(r.Unsimulated, ", "))
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+// DetectDrift 将已执行changeSet中的DDL节点模拟为预期结构，并与当前数据库的实际结构对比。
+// 检测只读取变更记录，不创建或升级记录表，也不获取锁
+func (f *Dbfly) DetectDrift(ctx context.Context) (*DriftResult, error) {
+	if err := detectServer(ctx, f.driver, f.migratory.MetaData()); err != nil {
+		return nil, err
+	}
+	changeSets, err := f.parseEntrypoint()
+	if err != nil {
+		return nil, err
+	}
+	// 记录表不存在时 History 返回空，视为没有已执行的changeSet
+	history, err := f.recorder.History(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	executedChangeSets := make(map[string]bool)
+	for _, entry := range history {
+		if entry.Success {
+			executedChangeSets[entry.ChangeSetId] = true
+		}
+	}
+
+	metaData := f.migratory.MetaData()
+	expected := newExpectedSchema(f, metaData)
+	result := &DriftResult{}
+	for _, cs := range changeSets {
+		if !executedChangeSets[cs.Id] {
+			continue
+		}
+		simulated, err := expected.apply(ctx, cs.DDLs)
+		if err != nil {
+			return nil, Wrap(err, "simulate changeSet %s failed", cs.Id)
+		}
+		if !simulated {
+			result.Unsimulated = append(result.Unsimulated, cs.Id)
+		}
+	}
+
+	actual, err := Snapshot(ctx, f.driver, metaData)
+	if err != nil {
+		return nil, err
+	}
+	actual.Tables = f.excludeInternalTables(actual.Tables)
+	expectedSnapshot := expected.snapshot(actual)
+	// 视图和外键只能通过SQL节点创建，不参与对比
+	actual.Views = nil
+	for _, table := range actual.Tables {
+		table.ForeignKeys = nil
+	}
+	result.Differences = diffSnapshots(metaData, expectedSnapshot, metaData, actual).Differences
+	return result, nil
+}
+
+// excludeInternalTables 排除 dbfly 默认的内部表以及记录器、锁和结构版本使用的表
+func (f *Dbfly) excludeInternalTables(tables []*TableSnapshot) []*TableSnapshot {
+	names := []string{f.schemaVersionTableName}
+	for _, component := range []interface{}{f.recorder, f.locker} {
+		if named, ok := component.(interface{ TableName() string }); ok {
+			names = append(names, named.TableName())
+		}
+	}
+	result := tables[:0]
+	for _, table := range tables {
+		if !isDbflyTable(table.Name, names) {
+			result = append(result, table)
+		}
+	}
+	return result
+}
+
+// expectedSchema 根据DDL节点模拟的预期结构
+type expectedSchema struct {
+	fly           *Dbfly
+	metaData      DatabaseMetaData
+	tables        []*TableSnapshot
+	uniqueColumns map[*TableSnapshot][]string
+}
+
+func newExpectedSchema(fly *Dbfly, metaData DatabaseMetaData) *expectedSchema {
+	return &expectedSchema{
+		fly:           fly,
+		metaData:      metaData,
+		uniqueColumns: make(map[*TableSnapshot][]string),
+	}
+}
+
+func (s *expectedSchema) table(name string) *TableSnapshot {
+	for _, table := range s.tables {
+		if strings.EqualFold(table.Name, name) {
+			return table
+		}
+	}
+	return nil
+}
+
+// apply 模拟DDL节点，存在无法模拟的节点时返回false
+func (s *expectedSchema) apply(ctx context.Context, ddls []DDL) (bool, error) {
+	simulated := true
+	for _, ddl := range ddls {
+		if conditions := ddlConditions(ddl); conditions != nil {
+			if !staticConditions(conditions.Conditions) {
+				// 依赖数据库当前状态的条件无法还原执行时的结果
+				simulated = false
+				continue
+			}
+			pass, err := conditions.Check(ctx, s.fly)
+			if err != nil {
+				return false, err
+			}
+			if !pass {
+				continue
+			}
+		}
+		switch n := ddl.(type) {
+		case *CreateTableNode:
+			table := &TableSnapshot{Name: n.TableName, Comment: n.Comment}
+			var pk *PrimaryKeySnapshot
+			for _, column := range n.Columns {
+				table.Columns = append(table.Columns, s.column(column))
+				if column.PrimaryKey {
+					if pk == nil {
+						pk = &PrimaryKeySnapshot{}
+					}
+					if pk.Name == "" {
+						pk.Name = column.KeyName
+					}
+					pk.Columns = append(pk.Columns, column.ColumnName)
+				}
+				if column.Unique {
+					s.uniqueColumns[table] = append(s.uniqueColumns[table], column.ColumnName)
+				}
+			}
+			table.PrimaryKey = pk
+			s.tables = append(s.tables, table)
+		case *DropTableNode:
+			for i, table := range s.tables {
+				if strings.EqualFold(table.Name, n.TableName) {
+					s.tables = append(s.tables[:i], s.tables[i+1:]...)
+					break
+				}
+			}
+		case *RenameTableNode:
+			if table := s.table(n.TableName); table != nil {
+				table.Name = n.NewTableName
+			}
+		case *AlterTableCommentNode:
+			if table := s.table(n.TableName); table != nil {
+				table.Comment = n.Comment
+			}
+		case *AddColumnNode:
+			if table := s.table(n.TableName); table != nil {
+				for _, column := range n.Columns {
+					node := addColumnToColumnNode(column)
+					table.Columns = append(table.Columns, s.column(node))
+					if column.Unique {
+						s.uniqueColumns[table] = append(s.uniqueColumns[table], column.ColumnName)
+					}
+				}
+			}
+		case *AlterColumnNode:
+			if table := s.table(n.TableName); table != nil && n.Column != nil {
+				for i, column := range table.Columns {
+					if strings.EqualFold(column.Name, n.ColumnName) {
+						node := alterColumnToColumnNode(n.ColumnName, n.Column)
+						table.Columns[i] = s.column(node)
+					}
+				}
+				if n.Column.Unique {
+					s.uniqueColumns[table] = append(s.uniqueColumns[table], n.ColumnName)
+				}
+			}
+		case *RenameColumnNode:
+			if table := s.table(n.TableName); table != nil {
+				renameColumn(table, n.ColumnName, n.NewColumnName)
+				for i, column := range s.uniqueColumns[table] {
+					if strings.EqualFold(column, n.ColumnName) {
+						s.uniqueColumns[table][i] = n.NewColumnName
+					}
+				}
+			}
+		case *DropColumnNode:
+			if table := s.table(n.TableName); table != nil {
+				dropColumn(table, n.ColumnName)
+			}
+		case *CreatePrimaryKeyNode:
+			if table := s.table(n.TableName); table != nil {
+				table.PrimaryKey = &PrimaryKeySnapshot{Name: n.KeyName, Columns: indexColumnNames(n.Columns)}
+			}
+		case *DropPrimaryKeyNode:
+			if table := s.table(n.TableName); table != nil {
+				table.PrimaryKey = nil
+			}
+		case *CreateIndexNode:
+			if table := s.table(n.TableName); table != nil {
+				table.Indexes = append(table.Indexes, &IndexSnapshot{Name: n.IndexName, Unique: n.Unique, Columns: indexColumnNames(n.Columns)})
+			}
+		case *DropIndexNode:
+			if table := s.table(n.TableName); table != nil {
+				for i, index := range table.Indexes {
+					if strings.EqualFold(index.Name, n.IndexName) {
+						table.Indexes = append(table.Indexes[:i], table.Indexes[i+1:]...)
+						break
+					}
+				}
+			}
+		case *TransactionNode:
+			ok, err := s.apply(ctx, n.DMLs)
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				simulated = false
+			}
+		case *SavepointNode:
+			ok, err := s.apply(ctx, n.DDLs)
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				simulated = false
+			}
+		case *SqlInlineNode, *SqlFileNode:
+			simulated = false
+		}
+	}
+	return simulated, nil
+}
+
+// ddlConditions 可模拟的DDL节点的执行条件
+func ddlConditions(ddl DDL) *ConditionsNode {
+	switch n := ddl.(type) {
+	case *CreateTableNode:
+		return n.Conditions
+	case *CreateIndexNode:
+		return n.Conditions
+	case *CreatePrimaryKeyNode:
+		return n.Conditions
+	case *DropTableNode:
+		return n.Conditions
+	case *DropIndexNode:
+		return n.Conditions
+	case *AddColumnNode:
+		return n.Conditions
+	case *RenameColumnNode:
+		return n.Conditions
+	case *AlterColumnNode:
+		return n.Conditions
+	case *DropColumnNode:
+		return n.Conditions
+	case *DropPrimaryKeyNode:
+		return n.Conditions
+	case *RenameTableNode:
+		return n.Conditions
+	case *AlterTableCommentNode:
+		return n.Conditions
+	}
+	return nil
+}
+
+// staticConditions 条件是否只依赖方言、版本、属性和上下文，不依赖数据库中当前的结构与数据
+func staticConditions(conditions []Condition) bool {
+	for _, condition := range conditions {
+		switch c := condition.(type) {
+		case *ConditionNode:
+			if !staticConditions(c.Conditions) {
+				return false
+			}
+		case *AndNode:
+			if !staticConditions(c.Conditions) {
+				return false
+			}
+		case *OrNode:
+			if !staticConditions(c.Conditions) {
+				return false
+			}
+		case *NotNode:
+			if !staticConditions(c.Conditions) {
+				return false
+			}
+		case *DbmsNode, *DbmsVersionNode, *PropertyEqualsNode, *PropertyDefinedNode, *ContextNode:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// column 将列节点转换为当前数据库中的预期列快照
+func (s *expectedSchema) column(node *ColumnNode) *ColumnSnapshot {
+	column := &ColumnSnapshot{
+		Name:     node.ColumnName,
+		Nullable: !node.Notnull && !node.PrimaryKey,
+		Comment:  node.Comment,
+	}
+	defaultValue, defaultOriginValue := node.DefaultValue, node.DefaultOriginValue
+	dbmsNode := selectColumnDbms(s.metaData, node.ColumnDbms)
+	if dbmsNode != nil {
+		dataType, length, scale := parseColumnType(dbmsNode.DataType)
+		column.DataType = dataType
+		if strings.Contains(dataType, "CHAR") {
+			column.MaxLength = length
+		} else {
+			column.NumericPrecision, column.NumericScale = length, scale
+		}
+		if dbmsNode.DefaultValue != "" || dbmsNode.DefaultOriginValue != "" {
+			defaultValue, defaultOriginValue = dbmsNode.DefaultValue, dbmsNode.DefaultOriginValue
+		}
+	} else {
+		dataType, length, _ := parseColumnType(s.metaData.DataType(node.DataType))
+		column.DataType = dataType
+		switch node.DataType {
+		case Varchar, Char:
+			column.MaxLength = node.MaxLength
+		case Decimal:
+			column.NumericPrecision, column.NumericScale = node.MaxLength, node.NumericScale
+		default:
+			column.NumericPrecision = length
+		}
+	}
+	if defaultValue != "" {
+		column.DefaultValue = "'" + strings.ReplaceAll(defaultValue, "'", "''") + "'"
+	} else {
+		column.DefaultValue = defaultOriginValue
+	}
+	return column
+}
+
+// snapshot 输出预期结构快照，列定义中的unique约束按实际数据库中的索引名称补齐
+func (s *expectedSchema) snapshot(actual *SchemaSnapshot) *SchemaSnapshot {
+	comments := s.metaData.Dbms() != "SQLite"
+	for _, table := range s.tables {
+		if !comments {
+			table.Comment = ""
+			for _, column := range table.Columns {
+				column.Comment = ""
+			}
+		}
+		actualTable := actual.Table(table.Name)
+		if actualTable == nil {
+			continue
+		}
+		for _, column := range s.uniqueColumns[table] {
+			for _, index := range actualTable.Indexes {
+				if index.Unique && equalFoldSlice(index.Columns, []string{column}) && table.Index(index.Name) == nil {
+					table.Indexes = append(table.Indexes, index)
+					break
+				}
+			}
+		}
+	}
+	snapshot := &SchemaSnapshot{Dbms: s.metaData.Dbms(), Tables: s.tables}
+	snapshot.sort()
+	return snapshot
+}
+
+func renameColumn(table *TableSnapshot, columnName, newColumnName string) {
+	rename := func(columns []string) {
+		for i, column := range columns {
+			if strings.EqualFold(column, columnName) {
+				columns[i] = newColumnName
+			}
+		}
+	}
+	if column := table.Column(columnName); column != nil {
+		column.Name = newColumnName
+	}
+	if table.PrimaryKey != nil {
+		rename(table.PrimaryKey.Columns)
+	}
+	for _, index := range table.Indexes {
+		rename(index.Columns)
+	}
+}
+
+// dropColumn 删除列，同时删除包含该列的索引
+func dropColumn(table *TableSnapshot, columnName string) {
+	columns := table.Columns[:0]
+	for _, column := range table.Columns {
+		if !strings.EqualFold(column.Name, columnName) {
+			columns = append(columns, column)
+		}
+	}
+	table.Columns = columns
+	indexes := table.Indexes[:0]
+	for _, index := range table.Indexes {
+		contains := false
+		for _, column := range index.Columns {
+			if strings.EqualFold(column, columnName) {
+				contains = true
+				break
+			}
+		}
+		if !contains {
+			indexes = append(indexes, index)
+		}
+	}
+	table.Indexes = indexes
+}
+
+func indexColumnNames(columns []*IndexColumnNode) []string {
+	names := make([]string, 0, len(columns))
+	for _, column := range columns {
+		names = append(names, column.Name)
+	}
+	return names
+}
+
+func addColumnToColumnNode(column *AddColumnColumnNode) *ColumnNode {
+	return &ColumnNode{
+		ColumnName:         column.ColumnName,
+		DataType:           column.DataType,
+		MaxLength:          column.MaxLength,
+		NumericScale:       column.NumericScale,
+		Notnull:            column.Notnull,
+		DefaultValue:       column.DefaultValue,
+		DefaultOriginValue: column.DefaultOriginValue,
+		Comment:            column.Comment,
+		ColumnDbms:         column.ColumnDbms,
+	}
+}
+
+func alterColumnToColumnNode(columnName string, column *AlterColumnColumnNode) *ColumnNode {
+	return &ColumnNode{
+		ColumnName:         columnName,
+		DataType:           column.DataType,
+		MaxLength:          column.MaxLength,
+		NumericScale:       column.NumericScale,
+		Notnull:            column.Notnull,
+		DefaultValue:       column.DefaultValue,
+		DefaultOriginValue: column.DefaultOriginValue,
+		Comment:            column.Comment,
+		ColumnDbms:         column.ColumnDbms,
+	}
+}
@@ -0,0 +1,241 @@
+package dbfly
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// mockRecorder 基于内存的变更记录器
+type mockRecorder struct {
+	executed map[string]bool
+	entries  []ChangeLogEntry
+}
+
+func (r *mockRecorder) InitChangeLogTable(context.Context, *Dbfly) error {
+	return nil
+}
+
+func (r *mockRecorder) GetExecutedChangeSets(context.Context, *Dbfly) (map[string]bool, error) {
+	return r.executed, nil
+}
+
+func (r *mockRecorder) History(context.Context, *Dbfly) ([]ChangeLogEntry, error) {
+	return r.entries, nil
+}
+
+func (r *mockRecorder) NewChangeLog(_ context.Context, _ *Dbfly, entry *ChangeLogEntry) error {
+	entry.ExecType = ExecTypeRunning
+	r.entries = append(r.entries, *entry)
+	return nil
+}
+
+func (r *mockRecorder) CompleteChangeLog(_ context.Context, _ *Dbfly, entry *ChangeLogEntry) error {
+	entry.Success, entry.ExecType = true, ExecTypeExecuted
+	if r.executed == nil {
+		r.executed = make(map[string]bool)
+	}
+	r.executed[entry.ChangeSetId] = true
+	r.entries[len(r.entries)-1] = *entry
+	return nil
+}
+
+func (r *mockRecorder) FailChangeLog(_ context.Context, _ *Dbfly, entry *ChangeLogEntry) error {
+	r.entries[len(r.entries)-1] = *entry
+	return nil
+}
+
+func (r *mockRecorder) RollbackChangeLog(_ context.Context, _ *Dbfly, entry *ChangeLogEntry) error {
+	entry.Success, entry.ExecType = false, ExecTypeRolledBack
+	delete(r.executed, entry.ChangeSetId)
+	for i := range r.entries {
+		if r.entries[i].ChangeSetId == entry.ChangeSetId {
+			r.entries[i] = *entry
+		}
+	}
+	return nil
+}
+
+const driftChangelog = `<?xml version="1.0" encoding="UTF-8"?>
+<dbfly xmlns="https://www.jianggujin.com/c/xml/dbfly">
+    <changeSet id="users">
+        <createTable tableName="users">
+            <column columnName="id" dataType="BIGINT" primaryKey="true" keyName="pk_users"/>
+            <column columnName="name" dataType="VARCHAR" maxLength="100"/>
+        </createTable>
+        <createIndex tableName="users" indexName="idx_name">
+            <column name="name"/>
+        </createIndex>
+    </changeSet>
+    <changeSet id="orders">
+        <createTable tableName="orders">
+            <column columnName="id" dataType="BIGINT" primaryKey="true" keyName="pk_orders"/>
+            <column columnName="user_id" dataType="BIGINT" notnull="true"/>
+            <column columnName="amount" dataType="DECIMAL" maxLength="10" numericScale="2"/>
+        </createTable>
+        <dropColumn tableName="orders" columnName="amount"/>
+        <createIndex tableName="orders" indexName="idx_user">
+            <column name="user_id"/>
+            <column name="id"/>
+        </createIndex>
+    </changeSet>
+    <changeSet id="script">
+        <sqlInline><default>CREATE VIEW v_users AS SELECT id FROM users</default></sqlInline>
+    </changeSet>
+    <changeSet id="pending">
+        <addColumn tableName="users">
+            <column columnName="email" dataType="VARCHAR" maxLength="200"/>
+        </addColumn>
+    </changeSet>
+</dbfly>`
+
+func newDriftDbfly(metaData *mockMetaData) *Dbfly {
+	return newExecutedDbfly(metaData, driftChangelog, "users", "orders", "script")
+}
+
+// newExecutedDbfly 创建变更记录中指定changeSet已成功执行的实例
+func newExecutedDbfly(metaData *mockMetaData, changelog string, executed ...string) *Dbfly {
+	migratory := NewDefaultMigratory("mock", metaData)
+	source := NewFSSource(fstest.MapFS{"dbfly.xml": {Data: []byte(changelog)}})
+	recorder := &mockRecorder{executed: make(map[string]bool)}
+	for _, id := range executed {
+		recorder.executed[id] = true
+		recorder.entries = append(recorder.entries, ChangeLogEntry{ChangeSetId: id, Success: true, ExecType: ExecTypeExecuted})
+	}
+	return NewDbfly(&migratory, &SqlDriver{}, source, WithRecorder(recorder))
+}
+
+func TestDetectDrift_NoDrift(t *testing.T) {
+	result, err := newDriftDbfly(newMockMetaData()).DetectDrift(context.Background())
+	if err != nil {
+		t.Fatalf("DetectDrift() error = %v", err)
+	}
+	if result.HasDrift() {
+		t.Errorf("unexpected drift:\n%s", result.Report())
+	}
+	if len(result.Unsimulated) != 1 || result.Unsimulated[0] != "script" {
+		t.Errorf("Unsimulated = %v, want [script]", result.Unsimulated)
+	}
+}
+
+func TestDetectDrift_InternalTables(t *testing.T) {
+	metaData := withDbflyTables(newMockMetaData())
+	metaData.tables = append(metaData.tables, &Table{Name: "APP_SCHEMA_VERSION", TableType: "TABLE"})
+	fly := newDriftDbfly(metaData)
+	WithSchemaVersionTableName("APP_SCHEMA_VERSION")(fly)
+	result, err := fly.DetectDrift(context.Background())
+	if err != nil {
+		t.Fatalf("DetectDrift() error = %v", err)
+	}
+	if result.HasDrift() {
+		t.Errorf("internal tables reported as drift:\n%s", result.Report())
+	}
+}
+
+func TestDetectDrift(t *testing.T) {
+	metaData := newMockMetaData()
+	// 手工修改：扩展列长度、新增列和索引
+	metaData.columns["users"][0] = &Column{Name: "name", DataType: "VARCHAR", MaxLength: 255, Nullable: true, Ordinal: 2}
+	metaData.columns["users"] = append(metaData.columns["users"], &Column{Name: "hotfix", DataType: "INT", Nullable: true, Ordinal: 3})
+	metaData.indexes["orders"] = append(metaData.indexes["orders"], &Index{Name: "idx_hotfix", ColumnName: "user_id", Ordinal: 1})
+	result, err := newDriftDbfly(metaData).DetectDrift(context.Background())
+	if err != nil {
+		t.Fatalf("DetectDrift() error = %v", err)
+	}
+	report := result.Report()
+	for _, want := range []string{
+		"- INDEX orders.idx_hotfix: unexpected",
+		"~ COLUMN users.name: VARCHAR(255) NULL -> VARCHAR(100) NULL",
+		"- COLUMN users.hotfix: unexpected",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+	if len(result.Differences) != 3 {
+		t.Errorf("got %d differences, want 3:\n%s", len(result.Differences), report)
+	}
+}
+
+func TestDetectDrift_Conditions(t *testing.T) {
+	changelog := `<dbfly>
+    <changeSet id="users">
+        <createTable tableName="users">
+            <column columnName="id" dataType="BIGINT" primaryKey="true" keyName="pk_users"/>
+            <column columnName="name" dataType="VARCHAR" maxLength="100"/>
+        </createTable>
+        <createIndex tableName="users" indexName="idx_name"><column name="name"/></createIndex>
+        <createTable tableName="pg_only">
+            <conditions><condition><dbms name="PostgreSQL"/></condition></conditions>
+            <column columnName="id" dataType="BIGINT"/>
+        </createTable>
+    </changeSet>
+    <changeSet id="orders">
+        <createTable tableName="orders">
+            <conditions><condition><tableExists tableName="orders" not="true"/></condition></conditions>
+            <column columnName="id" dataType="BIGINT"/>
+        </createTable>
+    </changeSet>
+</dbfly>`
+	metaData := newMockMetaData()
+	metaData.tables = metaData.tables[:1]
+	result, err := newExecutedDbfly(metaData, changelog, "users", "orders").DetectDrift(context.Background())
+	if err != nil {
+		t.Fatalf("DetectDrift() error = %v", err)
+	}
+	// 方言不匹配的表不在预期结构中，依赖数据库状态的条件不做推测
+	if result.HasDrift() {
+		t.Errorf("unexpected drift:\n%s", result.Report())
+	}
+	if len(result.Unsimulated) != 1 || result.Unsimulated[0] != "orders" {
+		t.Errorf("Unsimulated = %v, want [orders]", result.Unsimulated)
+	}
+}
+
+func TestDetectDrift_Savepoint(t *testing.T) {
+	changelog := `<dbfly>
+    <changeSet id="users">
+        <transaction>
+            <savepoint name="sp1">
+                <createTable tableName="users">
+                    <column columnName="id" dataType="BIGINT" primaryKey="true" keyName="pk_users"/>
+                    <column columnName="name" dataType="VARCHAR" maxLength="100"/>
+                </createTable>
+                <savepoint><createIndex tableName="users" indexName="idx_name"><column name="name"/></createIndex></savepoint>
+            </savepoint>
+        </transaction>
+    </changeSet>
+</dbfly>`
+	metaData := newMockMetaData()
+	metaData.tables = metaData.tables[:1]
+	result, err := newExecutedDbfly(metaData, changelog, "users").DetectDrift(context.Background())
+	if err != nil {
+		t.Fatalf("DetectDrift() error = %v", err)
+	}
+	// 保存点中的操作同样计入预期结构
+	if result.HasDrift() {
+		t.Errorf("unexpected drift:\n%s", result.Report())
+	}
+	if len(result.Unsimulated) != 0 {
+		t.Errorf("Unsimulated = %v, want none", result.Unsimulated)
+	}
+}
+
+func TestDetectDrift_NoChangeLogTable(t *testing.T) {
+	metaData := newMockMetaData()
+	migratory := NewDefaultMigratory("mock", metaData)
+	source := NewFSSource(fstest.MapFS{"dbfly.xml": {Data: []byte(driftChangelog)}})
+	driver := &recordDriver{}
+	result, err := NewDbfly(&migratory, driver, source).DetectDrift(context.Background())
+	if err != nil {
+		t.Fatalf("DetectDrift() error = %v", err)
+	}
+	if len(result.Differences) != 2 {
+		t.Errorf("got %d differences, want 2 unexpected tables:\n%s", len(result.Differences), result.Report())
+	}
+	// 检测不创建记录表和结构版本表
+	if len(driver.sqls) != 0 {
+		t.Errorf("DetectDrift executed statements: %q", driver.sqls)
+	}
+}
@@ -3,7 +3,10 @@ package dbfly
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"io"
+	"sync/atomic"
 )
 
 // Tx 事务接口
@@ -28,6 +31,94 @@ type Driver interface {
 	BeginTx(context.Context) (Tx, error)
 }
 
+// Session 独占的数据库连接，会话级的锁需要在同一连接上获取与释放
+type Session interface {
+	Driver
+	// Close 归还连接
+	Close() error
+}
+
+// SessionDriver 可选接口，由能够提供独占连接的驱动实现
+type SessionDriver interface {
+	// Session 获取一个独占的连接
+	Session(context.Context) (Session, error)
+}
+
+// ErrSessionUnsupported 驱动不支持提供独占连接
+var ErrSessionUnsupported = errors.New("driver does not support dedicated sessions")
+
+// openSession 从驱动获取独占连接
+func openSession(ctx context.Context, driver Driver) (Session, error) {
+	sessionDriver, ok := driver.(SessionDriver)
+	if !ok {
+		return nil, Wrap(ErrSessionUnsupported, "driver %T does not implement SessionDriver", driver)
+	}
+	return sessionDriver.Session(ctx)
+}
+
+// txDriver 将事务包装为 Driver，使变更集中的操作与变更记录在同一事务中执行
+type txDriver struct {
+	tx        Tx
+	migratory Migratory
+}
+
+func (d *txDriver) Execute(ctx context.Context, sql string, args ...interface{}) (sql.Result, error) {
+	return d.tx.Execute(ctx, sql, args...)
+}
+
+func (d *txDriver) Query(ctx context.Context, sql string, args ...interface{}) (Rows, error) {
+	return d.tx.Query(ctx, sql, args...)
+}
+
+// BeginTx 在当前事务中创建保存点作为嵌套事务
+func (d *txDriver) BeginTx(ctx context.Context) (Tx, error) {
+	return newSavepoint(ctx, d.migratory, d.tx, nextSavepointName())
+}
+
+// savepointSeq 自动生成的保存点序号
+var savepointSeq atomic.Int64
+
+// nextSavepointName 生成保存点名称
+func nextSavepointName() string {
+	return fmt.Sprintf("dbfly_sp_%d", savepointSeq.Add(1))
+}
+
+// ErrSavepointUnsupported 数据库不支持保存点
+var ErrSavepointUnsupported = errors.New("savepoint is not supported")
+
+// newSavepoint 按迁移器的方言在事务中创建保存点，返回的 Tx 回滚时回滚到保存点，外层事务继续有效
+func newSavepoint(ctx context.Context, migratory Migratory, tx Tx, name string) (Tx, error) {
+	savepoint, rollback, err := migratory.SavepointSQL(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = tx.Execute(ctx, savepoint); err != nil {
+		return nil, Wrap(err, "create savepoint %s failed", name)
+	}
+	return &savepointTx{Tx: tx, ctx: ctx, name: name, rollback: rollback}, nil
+}
+
+// savepointTx 保存点，SQL 通过外层事务执行
+type savepointTx struct {
+	Tx
+	ctx      context.Context
+	name     string
+	rollback string
+}
+
+// Commit 保存点随外层事务提交，不执行 RELEASE SAVEPOINT，兼容 Oracle 等不支持释放保存点的数据库
+func (t *savepointTx) Commit() error {
+	return nil
+}
+
+// Rollback 回滚到保存点
+func (t *savepointTx) Rollback() error {
+	if _, err := t.Tx.Execute(t.ctx, t.rollback); err != nil {
+		return Wrap(err, "rollback to savepoint %s failed", t.name)
+	}
+	return nil
+}
+
 // Rows 查询结果
 type Rows interface {
 	io.Closer
@@ -62,6 +153,39 @@ func (m *SqlDriver) BeginTx(ctx context.Context) (Tx, error) {
 	return &sqlTx{tx: tx}, nil
 }
 
+func (m *SqlDriver) Session(ctx context.Context) (Session, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlSession{conn: conn}, nil
+}
+
+// sqlSession 基于原生sql.Conn的独占连接
+type sqlSession struct {
+	conn *sql.Conn
+}
+
+func (s *sqlSession) Execute(ctx context.Context, sql string, values ...interface{}) (sql.Result, error) {
+	return s.conn.ExecContext(ctx, sql, values...)
+}
+
+func (s *sqlSession) Query(ctx context.Context, sql string, values ...interface{}) (Rows, error) {
+	return s.conn.QueryContext(ctx, sql, values...)
+}
+
+func (s *sqlSession) BeginTx(ctx context.Context) (Tx, error) {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlTx{tx: tx}, nil
+}
+
+func (s *sqlSession) Close() error {
+	return s.conn.Close()
+}
+
 // sqlTx 基于原生sql.Tx的事务实现
 type sqlTx struct {
 	tx *sql.Tx
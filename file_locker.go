@@ -0,0 +1,113 @@
+package dbfly
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileLocker 基于文件排他锁的锁实现，适用于单机部署，不需要锁表。
+// 持有期间文件内容为持有者标识与加锁时间，进程退出时由操作系统释放
+type FileLocker struct {
+	path             string
+	timeout          time.Duration
+	retryInterval    time.Duration
+	maxRetryInterval time.Duration
+}
+
+type FileLockerOption func(*FileLocker)
+
+func WithFileLockTimeout(d time.Duration) FileLockerOption {
+	return func(l *FileLocker) {
+		if d > 0 {
+			l.timeout = d
+		}
+	}
+}
+
+func WithFileLockRetryInterval(d time.Duration) FileLockerOption {
+	return func(l *FileLocker) {
+		if d > 0 {
+			l.retryInterval = d
+		}
+	}
+}
+
+// WithFileLockMaxRetryInterval 设置重试等待时长的上限，等待时长从重试间隔开始按指数增长
+func WithFileLockMaxRetryInterval(d time.Duration) FileLockerOption {
+	return func(l *FileLocker) {
+		if d > 0 {
+			l.maxRetryInterval = d
+		}
+	}
+}
+
+// NewFileLocker 创建使用指定文件加锁的实例，文件不存在时自动创建，所在目录需已存在
+func NewFileLocker(path string, opts ...FileLockerOption) *FileLocker {
+	l := &FileLocker{
+		path:             filepath.Clean(path),
+		timeout:          defaultLockTimeout,
+		retryInterval:    defaultLockRetryInterval,
+		maxRetryInterval: defaultLockMaxRetryInterval,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Path 锁文件路径
+func (l *FileLocker) Path() string {
+	return l.path
+}
+
+func (l *FileLocker) Lock(ctx context.Context, fly *Dbfly) (Unlock, error) {
+	waiter := newLockWaiter(fly, l.retryInterval, l.maxRetryInterval, l.timeout, 0)
+	for {
+		file, acquired, err := tryLockFile(l.path)
+		if err != nil {
+			return nil, Wrap(err, "lock file %q failed", l.path)
+		}
+		if acquired {
+			owner := fly.LockOwner()
+			if err = writeLockOwner(file, owner); err != nil {
+				_ = file.Close()
+				return nil, Wrap(err, "write lock file %q failed", l.path)
+			}
+			fly.logger.Debug("file lock %q acquired by %s", l.path, owner)
+			unlock := func(ctx context.Context, fly *Dbfly) error {
+				// 保留文件本身，删除已加锁的文件会使其他进程锁住不同的文件
+				_ = file.Truncate(0)
+				return file.Close()
+			}
+			return unlock, nil
+		}
+		if err = waiter.wait(ctx, l.holder()); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// writeLockOwner 将持有者标识与加锁时间写入锁文件
+func writeLockOwner(file *os.File, owner string) error {
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+	_, err := file.WriteAt([]byte(fmt.Sprintf("%s\n%s\n", owner, time.Now().Format(time.RFC3339))), 0)
+	return err
+}
+
+// holder 读取锁文件中的持有者标识，Windows 下文件被独占打开时无法读取
+func (l *FileLocker) holder() string {
+	file, err := os.Open(l.path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	line, _ := bufio.NewReader(file).ReadString('\n')
+	return strings.TrimSpace(line)
+}
@@ -0,0 +1,43 @@
+package dbfly
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileLocker(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "dbfly.lock")
+	opts := []FileLockerOption{WithFileLockTimeout(50 * time.Millisecond), WithFileLockRetryInterval(10 * time.Millisecond)}
+	fly := NewDbfly(NewSqliteMigratory(), &recordDriver{}, nil, WithLocker(NewFileLocker(path, opts...)), WithLockOwner("node-1"))
+
+	unlock, err := fly.locker.Lock(ctx, fly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil || !strings.HasPrefix(string(content), "node-1\n") {
+		t.Errorf("lock file content = %q, %v", content, err)
+	}
+
+	other := NewDbfly(NewSqliteMigratory(), &recordDriver{}, nil, WithLocker(NewFileLocker(path, opts...)))
+	_, err = other.locker.Lock(ctx, other)
+	var timeoutErr *LockTimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.Holder != "node-1" {
+		t.Fatalf("Lock() error = %v, want LockTimeoutError held by node-1", err)
+	}
+
+	if err = unlock(ctx, fly); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err = other.locker.Lock(ctx, other)
+	if err != nil {
+		t.Fatalf("lock after release: %v", err)
+	}
+	_ = unlock(ctx, other)
+}
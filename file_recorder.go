@@ -0,0 +1,276 @@
+package dbfly
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileRecorderFormat 变更记录文件格式
+type FileRecorderFormat int
+
+const (
+	// FileRecorderJSON 所有记录组成一个 JSON 数组
+	FileRecorderJSON FileRecorderFormat = iota
+	// FileRecorderJSONL 每行一条 JSON 记录
+	FileRecorderJSONL
+)
+
+// FileRecorder 将变更记录保存在 JSON 或 JSONL 文件中的记录器，适用于不希望在目标数据库中建表的场景。
+// 每次修改先写入同目录下的临时文件再重命名替换，进程中断时不会留下不完整的文件
+type FileRecorder struct {
+	mu     sync.Mutex
+	path   string
+	format FileRecorderFormat
+}
+
+type FileRecorderOption func(*FileRecorder)
+
+// WithFileRecorderFormat 设置文件格式，默认扩展名为 .jsonl 时使用 JSONL，否则使用 JSON
+func WithFileRecorderFormat(format FileRecorderFormat) FileRecorderOption {
+	return func(r *FileRecorder) {
+		r.format = format
+	}
+}
+
+// NewFileRecorder 创建记录到指定文件的实例，文件不存在时自动创建，所在目录需已存在
+func NewFileRecorder(path string, opts ...FileRecorderOption) *FileRecorder {
+	r := &FileRecorder{path: filepath.Clean(path), format: FileRecorderJSON}
+	if strings.EqualFold(filepath.Ext(path), ".jsonl") {
+		r.format = FileRecorderJSONL
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Path 变更记录文件路径
+func (r *FileRecorder) Path() string {
+	return r.path
+}
+
+// fileChangeLogEntry 文件中的一条变更记录
+type fileChangeLogEntry struct {
+	ChangeSetId   string    `json:"changeSetId"`
+	Author        string    `json:"author,omitempty"`
+	Filename      string    `json:"filename,omitempty"`
+	Description   string    `json:"description,omitempty"`
+	OrderExecuted int       `json:"orderExecuted"`
+	Success       bool      `json:"success"`
+	ExecType      ExecType  `json:"execType"`
+	Checksum      string    `json:"checksum,omitempty"`
+	DurationMs    int64     `json:"durationMs"`
+	DeploymentId  string    `json:"deploymentId,omitempty"`
+	Tag           string    `json:"tag,omitempty"`
+	Contexts      string    `json:"contexts,omitempty"`
+	DbflyVersion  string    `json:"dbflyVersion,omitempty"`
+	ErrorMessage  string    `json:"errorMessage,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+func (r *FileRecorder) InitChangeLogTable(_ context.Context, fly *Dbfly) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := os.Stat(r.path); err == nil || !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := r.save(nil); err != nil {
+		return err
+	}
+	fly.logger.Debug("change log file initialized: %s", r.path)
+	return nil
+}
+
+func (r *FileRecorder) GetExecutedChangeSets(context.Context, *Dbfly) (map[string]bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entries, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	return entries.executed(), nil
+}
+
+func (r *FileRecorder) History(context.Context, *Dbfly) ([]ChangeLogEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entries, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	return entries.history(), nil
+}
+
+func (r *FileRecorder) NewChangeLog(_ context.Context, fly *Dbfly, entry *ChangeLogEntry) error {
+	if err := r.modify(func(entries changeLogs) (changeLogs, error) {
+		return entries.add(entry), nil
+	}); err != nil {
+		return err
+	}
+	fly.logger.Debug("change log created, changeSetId: %q", entry.ChangeSetId)
+	return nil
+}
+
+func (r *FileRecorder) CompleteChangeLog(_ context.Context, fly *Dbfly, entry *ChangeLogEntry) error {
+	if err := r.modify(func(entries changeLogs) (changeLogs, error) {
+		return entries, entries.complete(entry)
+	}); err != nil {
+		return err
+	}
+	fly.logger.Debug("change log completed, changeSetId: %q", entry.ChangeSetId)
+	return nil
+}
+
+func (r *FileRecorder) FailChangeLog(_ context.Context, fly *Dbfly, entry *ChangeLogEntry) error {
+	if err := r.modify(func(entries changeLogs) (changeLogs, error) {
+		return entries, entries.fail(entry)
+	}); err != nil {
+		return err
+	}
+	fly.logger.Debug("change log failed, changeSetId: %q", entry.ChangeSetId)
+	return nil
+}
+
+func (r *FileRecorder) RollbackChangeLog(_ context.Context, fly *Dbfly, entry *ChangeLogEntry) error {
+	if err := r.modify(func(entries changeLogs) (changeLogs, error) {
+		return entries, entries.rollback(entry)
+	}); err != nil {
+		return err
+	}
+	fly.logger.Debug("change log rolled back, changeSetId: %q", entry.ChangeSetId)
+	return nil
+}
+
+// modify 读取文件中的记录，修改后写回
+func (r *FileRecorder) modify(fn func(changeLogs) (changeLogs, error)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entries, err := r.load()
+	if err != nil {
+		return err
+	}
+	if entries, err = fn(entries); err != nil {
+		return err
+	}
+	return r.save(entries)
+}
+
+// load 读取文件中的记录，文件不存在时返回空
+func (r *FileRecorder) load() (changeLogs, error) {
+	content, err := os.ReadFile(r.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, Wrap(err, "read change log file %q failed", r.path)
+	}
+	var records []fileChangeLogEntry
+	if r.format == FileRecorderJSONL {
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		for {
+			var record fileChangeLogEntry
+			if err = decoder.Decode(&record); err != nil {
+				if err == io.EOF {
+					break
+				}
+				return nil, Wrap(err, "parse change log file %q failed", r.path)
+			}
+			records = append(records, record)
+		}
+	} else if len(bytes.TrimSpace(content)) > 0 {
+		if err = json.Unmarshal(content, &records); err != nil {
+			return nil, Wrap(err, "parse change log file %q failed", r.path)
+		}
+	}
+	entries := make(changeLogs, len(records))
+	for i, record := range records {
+		entries[i] = ChangeLogEntry{
+			ChangeSetId:   record.ChangeSetId,
+			Author:        record.Author,
+			Filename:      record.Filename,
+			Description:   record.Description,
+			OrderExecuted: record.OrderExecuted,
+			Success:       record.Success,
+			ExecType:      record.ExecType,
+			Checksum:      record.Checksum,
+			Duration:      time.Duration(record.DurationMs) * time.Millisecond,
+			DeploymentId:  record.DeploymentId,
+			Tag:           record.Tag,
+			Contexts:      record.Contexts,
+			DbflyVersion:  record.DbflyVersion,
+			ErrorMessage:  record.ErrorMessage,
+			CreatedAt:     record.CreatedAt,
+			UpdatedAt:     record.UpdatedAt,
+		}
+	}
+	return entries, nil
+}
+
+// save 写入同目录下的临时文件后重命名替换原文件
+func (r *FileRecorder) save(entries changeLogs) error {
+	records := make([]fileChangeLogEntry, len(entries))
+	for i, entry := range entries {
+		records[i] = fileChangeLogEntry{
+			ChangeSetId:   entry.ChangeSetId,
+			Author:        entry.Author,
+			Filename:      entry.Filename,
+			Description:   entry.Description,
+			OrderExecuted: entry.OrderExecuted,
+			Success:       entry.Success,
+			ExecType:      entry.ExecType,
+			Checksum:      entry.Checksum,
+			DurationMs:    entry.Duration.Milliseconds(),
+			DeploymentId:  entry.DeploymentId,
+			Tag:           entry.Tag,
+			Contexts:      entry.Contexts,
+			DbflyVersion:  entry.DbflyVersion,
+			ErrorMessage:  entry.ErrorMessage,
+			CreatedAt:     entry.CreatedAt,
+			UpdatedAt:     entry.UpdatedAt,
+		}
+	}
+	var buf bytes.Buffer
+	if r.format == FileRecorderJSONL {
+		encoder := json.NewEncoder(&buf)
+		for _, record := range records {
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+		}
+	} else {
+		encoder := json.NewEncoder(&buf)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(records); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".*.tmp")
+	if err != nil {
+		return Wrap(err, "create change log file %q failed", r.path)
+	}
+	tmp := file.Name()
+	if _, err = file.Write(buf.Bytes()); err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, r.path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return Wrap(err, "write change log file %q failed", r.path)
+	}
+	return nil
+}
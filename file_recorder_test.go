@@ -0,0 +1,109 @@
+package dbfly
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+const recorderChangelog = `<dbfly>
+    <changeSet id="a">
+        <sqlInline><default>CREATE TABLE a(id INT)</default></sqlInline>
+        <rollback><sqlInline><default>DROP TABLE a</default></sqlInline></rollback>
+    </changeSet>
+    <changeSet id="b" onFail="SKIP"><sqlInline><default>FAIL</default></sqlInline></changeSet>
+</dbfly>`
+
+// testRecorder 验证记录器的执行、失败、回滚与重新执行，newRecorder 每次返回读取同一份记录的实例
+func testRecorder(t *testing.T, newRecorder func() Recorder) {
+	ctx := context.Background()
+	source := NewFSSource(fstest.MapFS{"dbfly.xml": {Data: []byte(recorderChangelog)}})
+	migratory := NewDefaultMigratory("mock", &mockMetaData{dbms: "Mock"})
+	newFly := func() *Dbfly {
+		return NewDbfly(&migratory, &failDriver{}, source, WithRecorder(newRecorder()), WithLocker(NewMutexLocker()))
+	}
+
+	fly := newFly()
+	if err := fly.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	history, err := newFly().History(ctx)
+	if err != nil || len(history) != 2 {
+		t.Fatalf("History() = %+v, %v", history, err)
+	}
+	if a := history[0]; a.ChangeSetId != "a" || !a.Success || a.ExecType != ExecTypeExecuted || a.DeploymentId != fly.DeploymentID() {
+		t.Errorf("entry a = %+v", a)
+	}
+	if b := history[1]; b.ChangeSetId != "b" || b.Success || b.ExecType != ExecTypeFailed || !strings.Contains(b.ErrorMessage, "FAIL") {
+		t.Errorf("entry b = %+v", b)
+	}
+
+	fly = newFly()
+	if err = fly.RollbackDeployment(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+	executed, err := newRecorder().GetExecutedChangeSets(ctx, fly)
+	if err != nil || len(executed) != 0 {
+		t.Errorf("GetExecutedChangeSets() after rollback = %v, %v", executed, err)
+	}
+
+	// 重新执行时替换已回滚和失败的记录
+	if err = newFly().Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	if history, err = newFly().History(ctx); err != nil || len(history) != 2 ||
+		history[0].ExecType != ExecTypeExecuted || history[0].OrderExecuted != 3 || history[1].ExecType != ExecTypeFailed {
+		t.Errorf("History() after re-migrate = %+v, %v", history, err)
+	}
+}
+
+func TestMemoryRecorder(t *testing.T) {
+	recorder := NewMemoryRecorder()
+	testRecorder(t, func() Recorder { return recorder })
+}
+
+func TestMemoryRecorder_Rerecord(t *testing.T) {
+	ctx := context.Background()
+	recorder := NewMemoryRecorder()
+	fly := NewDbfly(NewSqliteMigratory(), &recordDriver{}, nil, WithRecorder(recorder))
+	// 事务提交失败时，变更集已记为成功后会重新创建记录并记为失败
+	entry := &ChangeLogEntry{ChangeSetId: "a"}
+	for _, record := range []func(context.Context, *Dbfly, *ChangeLogEntry) error{
+		recorder.NewChangeLog, recorder.CompleteChangeLog, recorder.NewChangeLog, recorder.FailChangeLog,
+	} {
+		if err := record(ctx, fly, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if executed, _ := recorder.GetExecutedChangeSets(ctx, fly); executed["a"] {
+		t.Error("changeSet a reported as executed after commit failure")
+	}
+	if history, _ := recorder.History(ctx, fly); len(history) != 1 || history[0].Success {
+		t.Errorf("History() = %+v", history)
+	}
+}
+
+func TestFileRecorder(t *testing.T) {
+	for _, name := range []string{"changelog.json", "changelog.jsonl"} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, name)
+			testRecorder(t, func() Recorder { return NewFileRecorder(path) })
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if jsonl := bytes.Count(content, []byte("\n")) == 2; jsonl != strings.HasSuffix(name, ".jsonl") {
+				t.Errorf("unexpected file format:\n%s", content)
+			}
+			if files, _ := os.ReadDir(dir); len(files) != 1 {
+				t.Errorf("temporary files left: %v", files)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package dbfly
+
+import (
+	"os"
+	"runtime"
+)
+
+// tryLockFile 当前平台不支持文件锁
+func tryLockFile(string) (*os.File, bool, error) {
+	return nil, false, New("file lock is not supported on %s", runtime.GOOS)
+}
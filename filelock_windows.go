@@ -0,0 +1,29 @@
+//go:build windows
+
+package dbfly
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// errorSharingViolation 文件已被其他进程以不共享方式打开
+const errorSharingViolation syscall.Errno = 32
+
+// tryLockFile 以不共享的方式打开文件作为排他锁，进程退出时由操作系统释放
+func tryLockFile(path string) (*os.File, bool, error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, false, err
+	}
+	handle, err := syscall.CreateFile(name, syscall.GENERIC_READ|syscall.GENERIC_WRITE, 0, nil,
+		syscall.OPEN_ALWAYS, syscall.FILE_ATTRIBUTE_NORMAL, 0)
+	if err != nil {
+		if errors.Is(err, errorSharingViolation) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return os.NewFile(uintptr(handle), path), true, nil
+}
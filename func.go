@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -211,6 +212,24 @@ func columnType(dataType, columnType string, maxLength, numericScale int) string
 	}
 }
 
+// parseColumnType 将形如 VARCHAR(100)、DECIMAL(10, 2) 的类型拆分为类型名称、长度和小数位数
+func parseColumnType(str string) (string, int, int) {
+	str = strings.TrimSpace(str)
+	start := strings.IndexByte(str, '(')
+	end := strings.LastIndexByte(str, ')')
+	if start < 0 || end < start {
+		return strings.ToUpper(str), 0, 0
+	}
+	name := strings.ToUpper(strings.TrimSpace(str[:start]))
+	parts := strings.Split(str[start+1:end], ",")
+	length, _ := strconv.Atoi(strings.TrimSpace(parts[0]))
+	var scale int
+	if len(parts) > 1 {
+		scale, _ = strconv.Atoi(strings.TrimSpace(parts[1]))
+	}
+	return name, length, scale
+}
+
 type Signed interface {
 	~int | ~int8 | ~int16 | ~int32 | ~int64
 }
@@ -0,0 +1,443 @@
+package dbfly
+
+import (
+	"context"
+	sql2 "database/sql"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const dbflyNamespace = "https://www.jianggujin.com/c/xml/dbfly"
+
+const defaultGenerateBatchSize = 100
+
+// ChangelogNode changelog根节点，用于输出changelog文件
+type ChangelogNode struct {
+	XMLName    xml.Name         `xml:"dbfly"`
+	Xmlns      string           `xml:"xmlns,attr,omitempty"`
+	ChangeSets []*ChangeSetNode `xml:"changeSet"`
+}
+
+func NewChangelogNode() *ChangelogNode {
+	return &ChangelogNode{Xmlns: dbflyNamespace}
+}
+
+// MarshalIndentXML 以缩进格式输出带XML声明的changelog内容
+func (n *ChangelogNode) MarshalIndentXML() ([]byte, error) {
+	data, err := xml.MarshalIndent(n, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+type generateOptions struct {
+	author      string
+	idPrefix    string
+	tables      []string
+	includeData bool
+	batchSize   int
+	logger      Logger
+	// internalTables 除默认名称外需要跳过的 dbfly 内部表
+	internalTables []string
+}
+
+type GenerateOption func(*generateOptions)
+
+// WithGenerateAuthor 设置生成的changeSet作者
+func WithGenerateAuthor(author string) GenerateOption {
+	return func(o *generateOptions) {
+		o.author = author
+	}
+}
+
+// WithGenerateIdPrefix 设置生成的changeSet标识前缀
+func WithGenerateIdPrefix(prefix string) GenerateOption {
+	return func(o *generateOptions) {
+		o.idPrefix = prefix
+	}
+}
+
+// WithGenerateTables 仅生成指定的表
+func WithGenerateTables(tables ...string) GenerateOption {
+	return func(o *generateOptions) {
+		o.tables = append(o.tables, tables...)
+	}
+}
+
+// WithGenerateData 同时导出表数据为insert节点，batchSize为每个insert节点包含的行数
+func WithGenerateData(batchSize int) GenerateOption {
+	return func(o *generateOptions) {
+		o.includeData = true
+		if batchSize > 0 {
+			o.batchSize = batchSize
+		}
+	}
+}
+
+// WithGenerateLogger 设置日志器，导出数据时跳过的二进制列以警告输出
+func WithGenerateLogger(logger Logger) GenerateOption {
+	return func(o *generateOptions) {
+		o.logger = logger
+	}
+}
+
+// WithGenerateInternalTables 设置修改过名称的记录表、锁表和结构版本表，与默认名称的内部表一样不生成
+func WithGenerateInternalTables(tables ...string) GenerateOption {
+	return func(o *generateOptions) {
+		o.internalTables = append(o.internalTables, tables...)
+	}
+}
+
+func (o *generateOptions) accept(tableName string) bool {
+	if len(o.tables) == 0 {
+		// 默认跳过 dbfly 自身的记录表、锁表和结构版本表
+		return !isDbflyTable(tableName, o.internalTables)
+	}
+	for _, name := range o.tables {
+		if strings.EqualFold(name, tableName) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDbflyTable 是否为 dbfly 默认的记录表、锁表、结构版本表或 internalTables 中的表
+func isDbflyTable(tableName string, internalTables []string) bool {
+	for _, names := range [][]string{{defaultChangeLogTableName, defaultChangeLockTableName, defaultSchemaVersionTableName}, internalTables} {
+		for _, name := range names {
+			if strings.EqualFold(tableName, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GenerateChangelog 根据现有数据库反向生成changelog，每张表生成一个changeSet
+func GenerateChangelog(ctx context.Context, driver Driver, metaData DatabaseMetaData, opts ...GenerateOption) (*ChangelogNode, error) {
+	options := &generateOptions{
+		idPrefix:  "init-",
+		batchSize: defaultGenerateBatchSize,
+		logger:    nopLogger{},
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	driver = bindDriver(driver, metaData)
+	snapshot, err := Snapshot(ctx, driver, metaData)
+	if err != nil {
+		return nil, err
+	}
+	changelog := NewChangelogNode()
+	for _, table := range snapshot.Tables {
+		if !options.accept(table.Name) {
+			continue
+		}
+		changeSet := &ChangeSetNode{
+			Id:     options.idPrefix + sanitizeChangeSetId(table.Name),
+			Author: options.author,
+			DDLs:   createTableDDLs(metaData, table),
+		}
+		if options.includeData {
+			inserts, err := generateInsertDDLs(ctx, driver, metaData, table, options)
+			if err != nil {
+				return nil, Wrap(err, "export data of table %s failed", table.Name)
+			}
+			changeSet.DDLs = append(changeSet.DDLs, inserts...)
+		}
+		changelog.ChangeSets = append(changelog.ChangeSets, changeSet)
+	}
+	return changelog, nil
+}
+
+// createTableDDLs 根据表快照生成建表、主键和索引节点
+func createTableDDLs(metaData DatabaseMetaData, table *TableSnapshot) []DDL {
+	createTable := &CreateTableNode{
+		TableName: table.Name,
+		Comment:   table.Comment,
+	}
+	singlePk := table.PrimaryKey != nil && len(table.PrimaryKey.Columns) == 1
+	for _, column := range table.Columns {
+		node := newColumnNode(metaData, column)
+		if singlePk && strings.EqualFold(column.Name, table.PrimaryKey.Columns[0]) {
+			node.PrimaryKey = true
+			node.KeyName = table.PrimaryKey.Name
+		}
+		createTable.Columns = append(createTable.Columns, node)
+	}
+	ddls := []DDL{createTable}
+	if table.PrimaryKey != nil && !singlePk {
+		keyName := table.PrimaryKey.Name
+		if keyName == "" {
+			keyName = "pk_" + table.Name
+		}
+		ddls = append(ddls, &CreatePrimaryKeyNode{
+			TableName: table.Name,
+			KeyName:   keyName,
+			Columns:   indexColumnNodes(table.PrimaryKey.Columns),
+		})
+	}
+	for _, index := range table.Indexes {
+		ddls = append(ddls, &CreateIndexNode{
+			TableName: table.Name,
+			IndexName: index.Name,
+			Unique:    index.Unique,
+			Columns:   indexColumnNodes(index.Columns),
+		})
+	}
+	return ddls
+}
+
+func indexColumnNodes(columns []string) []*IndexColumnNode {
+	nodes := make([]*IndexColumnNode, 0, len(columns))
+	for _, column := range columns {
+		nodes = append(nodes, &IndexColumnNode{Name: column})
+	}
+	return nodes
+}
+
+// newColumnNode 将列快照转换为列节点，无法映射为标准类型时使用 columnDbms 保留原生类型
+func newColumnNode(metaData DatabaseMetaData, column *ColumnSnapshot) *ColumnNode {
+	node := &ColumnNode{
+		ColumnName: column.Name,
+		Notnull:    !column.Nullable,
+		Comment:    column.Comment,
+	}
+	dataType := standardDataType(metaData, column)
+	value, originValue, portable := splitColumnDefault(metaData.Dbms(), column, dataType)
+	switch dataType {
+	case Varchar, Char:
+		node.MaxLength = column.MaxLength
+	case Decimal:
+		node.MaxLength = column.NumericPrecision
+		node.NumericScale = column.NumericScale
+	}
+	if dataType != "" && portable {
+		node.DataType = dataType
+		node.DefaultValue = value
+		node.DefaultOriginValue = originValue
+		return node
+	}
+	dbmsNode := &ColumnDbmsNode{
+		Dbms:               metaData.Dbms(),
+		DataType:           nativeColumnType(column),
+		DefaultValue:       value,
+		DefaultOriginValue: originValue,
+	}
+	if dataType == "" {
+		// 没有对应的标准类型时，其他数据库使用 TEXT
+		node.DataType = Text
+		node.MaxLength = 0
+		node.NumericScale = 0
+	} else {
+		node.DataType = dataType
+	}
+	node.ColumnDbms = append(node.ColumnDbms, dbmsNode)
+	return node
+}
+
+// standardDataTypes 反向映射标准类型时的匹配顺序，多个标准类型映射为同一原生类型时优先匹配靠前的类型
+var standardDataTypes = []string{Varchar, Char, Text, Clob, Smallint, Int, Bigint, Tinyint, Boolean, Decimal, Date, Timestamp, Time, Blob}
+
+// dataTypeAliases 常见原生类型与标准类型的对应关系
+var dataTypeAliases = map[string]string{
+	"VARCHAR2":                    Varchar,
+	"NVARCHAR":                    Varchar,
+	"NVARCHAR2":                   Varchar,
+	"CHARACTER VARYING":           Varchar,
+	"CHARACTER":                   Char,
+	"BPCHAR":                      Char,
+	"NCHAR":                       Char,
+	"TINYTEXT":                    Text,
+	"MEDIUMTEXT":                  Text,
+	"LONGTEXT":                    Clob,
+	"NCLOB":                       Clob,
+	"NTEXT":                       Clob,
+	"BOOL":                        Boolean,
+	"INT2":                        Smallint,
+	"INTEGER":                     Int,
+	"INT4":                        Int,
+	"MEDIUMINT":                   Int,
+	"INT8":                        Bigint,
+	"NUMERIC":                     Decimal,
+	"NUMBER":                      Decimal,
+	"DATETIME":                    Timestamp,
+	"TIMESTAMP WITHOUT TIME ZONE": Timestamp,
+	"TIME WITHOUT TIME ZONE":      Time,
+	"BYTEA":                       Blob,
+	"MEDIUMBLOB":                  Blob,
+	"LONGBLOB":                    Blob,
+}
+
+// standardDataType 将列的原生类型映射为标准类型，无法映射时返回空字符串
+func standardDataType(metaData DatabaseMetaData, column *ColumnSnapshot) string {
+	dataType := strings.ToUpper(column.DataType)
+	withPrecision := fmt.Sprintf("%s(%d)", dataType, column.NumericPrecision)
+	for _, standard := range standardDataTypes {
+		native := strings.ToUpper(metaData.DataType(standard))
+		if native == dataType || (column.NumericScale == 0 && native == withPrecision) {
+			// 多个标准类型映射为同一原生类型时（如 ClickHouse 的 String），跳过缺少长度的字符串类型
+			if standard = checkStandardLength(standard, column); standard != "" {
+				return standard
+			}
+		}
+	}
+	if standard, ok := dataTypeAliases[dataType]; ok {
+		return checkStandardLength(standard, column)
+	}
+	return ""
+}
+
+// checkStandardLength 字符串类型缺少长度时无法使用标准类型表示
+func checkStandardLength(standard string, column *ColumnSnapshot) string {
+	if (standard == Varchar || standard == Char) && column.MaxLength <= 0 {
+		return ""
+	}
+	return standard
+}
+
+// nativeColumnType 输出带长度和精度的原生类型
+func nativeColumnType(column *ColumnSnapshot) string {
+	dataType := strings.ToUpper(column.DataType)
+	switch {
+	case column.NumericPrecision > 0 && column.NumericScale > 0:
+		return fmt.Sprintf("%s(%d, %d)", dataType, column.NumericPrecision, column.NumericScale)
+	case column.NumericPrecision > 0 && (dataType == "DECIMAL" || dataType == "NUMERIC" || dataType == "NUMBER"):
+		return fmt.Sprintf("%s(%d)", dataType, column.NumericPrecision)
+	case column.MaxLength > 0 && strings.Contains(dataType, "CHAR"):
+		return fmt.Sprintf("%s(%d)", dataType, column.MaxLength)
+	}
+	return dataType
+}
+
+// splitColumnDefault 将数据库中的默认值拆分为字面值或原始表达式，portable表示能否跨数据库使用
+func splitColumnDefault(dbms string, column *ColumnSnapshot, dataType string) (string, string, bool) {
+	value := strings.TrimSpace(column.DefaultValue)
+	if value == "" || strings.EqualFold(value, "NULL") {
+		return "", "", true
+	}
+	// 去除 PostgreSQL 类型转换，如 'abc'::character varying
+	if index := strings.Index(value, "::"); index > 0 && value[0] == '\'' {
+		value = value[:index]
+	}
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'"), "", true
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return "", value, true
+	}
+	// MySQL 系列中字符串类型的默认值不带引号
+	if isMysqlFlavor(dbms) && (dataType == Varchar || dataType == Char || dataType == Text || dataType == Clob) {
+		return value, "", true
+	}
+	return "", value, false
+}
+
+// generateInsertDDLs 导出表数据为批量insert节点
+func generateInsertDDLs(ctx context.Context, driver Driver, metaData DatabaseMetaData, table *TableSnapshot, options *generateOptions) ([]DDL, error) {
+	quoter := metaData.Quoter()
+	names := make([]string, 0, len(table.Columns))
+	standards := make([]string, 0, len(table.Columns))
+	for _, column := range table.Columns {
+		// 二进制内容无法作为XML属性值输出，跳过该列
+		if isBinaryColumn(column) {
+			options.logger.Warn("skip binary column %s.%s (%s) when exporting data", table.Name, column.Name, column.DataType)
+			continue
+		}
+		names = append(names, column.Name)
+		standards = append(standards, standardDataType(metaData, column))
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	batchSize := options.batchSize
+	sql := fmt.Sprintf("SELECT %s FROM %s", quoter.MustJoin(names, ", "), quoter.MustQuote(table.Name))
+	if table.PrimaryKey != nil {
+		sql += " ORDER BY " + quoter.MustJoin(table.PrimaryKey.Columns, ", ")
+	}
+	var ddls []DDL
+	var current *InsertNode
+	values := make([]sql2.NullString, len(names))
+	scanArgs := make([]interface{}, len(names))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+	err := doEach(ctx, driver, func(rows Rows) error {
+		if err := rows.Scan(scanArgs...); err != nil {
+			return err
+		}
+		row := &DataRowNode{}
+		for i, name := range names {
+			column := &DataColumnNode{Name: name}
+			if values[i].Valid {
+				setDataColumnValue(column, standards[i], values[i].String)
+			} else {
+				column.ValueNull = true
+			}
+			row.Columns = append(row.Columns, column)
+		}
+		if current == nil || len(current.Rows) >= batchSize {
+			current = &InsertNode{TableName: table.Name}
+			ddls = append(ddls, current)
+		}
+		current.Rows = append(current.Rows, row)
+		return nil
+	}, sql)
+	return ddls, err
+}
+
+// setDataColumnValue 按列的标准类型写入类型化的值，避免日期、数值在严格的数据库中作为字符串字面量插入，
+// 无法按该类型解析的值仍写入 value
+func setDataColumnValue(column *DataColumnNode, standard, value string) {
+	switch standard {
+	case Tinyint, Smallint, Int, Bigint, Decimal:
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			column.ValueNumeric = value
+			return
+		}
+	case Boolean:
+		if _, err := strconv.ParseBool(value); err == nil {
+			column.ValueBoolean = value
+			return
+		}
+	case Date, Timestamp:
+		for _, layout := range dateLayouts {
+			if _, err := time.Parse(layout, value); err == nil {
+				column.ValueDate = value
+				return
+			}
+		}
+	}
+	column.Value = value
+}
+
+// isBinaryColumn 是否为二进制类型的列
+func isBinaryColumn(column *ColumnSnapshot) bool {
+	dataType := strings.ToUpper(column.DataType)
+	// Oracle 的 BINARY_FLOAT、BINARY_DOUBLE 为数值类型
+	if strings.HasPrefix(dataType, "BINARY_") {
+		return false
+	}
+	switch dataType {
+	case "BYTEA", "RAW", "LONG RAW", "IMAGE":
+		return true
+	}
+	return strings.Contains(dataType, "BLOB") || strings.Contains(dataType, "BINARY")
+}
+
+// sanitizeChangeSetId 将名称中不允许出现在changeSet标识中的字符替换为下划线
+func sanitizeChangeSetId(name string) string {
+	var builder strings.Builder
+	for _, r := range name {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '-' || r == '.' {
+			builder.WriteRune(r)
+		} else {
+			builder.WriteRune('_')
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,172 @@
+package dbfly
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStandardDataType(t *testing.T) {
+	metaData := newMockMetaData()
+	tests := []struct {
+		column *ColumnSnapshot
+		want   string
+	}{
+		{&ColumnSnapshot{DataType: "VARCHAR", MaxLength: 50}, Varchar},
+		{&ColumnSnapshot{DataType: "VARCHAR"}, ""},
+		{&ColumnSnapshot{DataType: "INT4"}, Int},
+		{&ColumnSnapshot{DataType: "DATETIME"}, Timestamp},
+		{&ColumnSnapshot{DataType: "NUMERIC", NumericPrecision: 10, NumericScale: 2}, Decimal},
+		{&ColumnSnapshot{DataType: "JSONB"}, ""},
+	}
+	for _, tt := range tests {
+		if got := standardDataType(metaData, tt.column); got != tt.want {
+			t.Errorf("standardDataType(%+v) = %q, want %q", tt.column, got, tt.want)
+		}
+	}
+}
+
+func TestSplitColumnDefault(t *testing.T) {
+	tests := []struct {
+		dbms, value, dataType string
+		wantValue, wantOrigin string
+		wantPortable          bool
+	}{
+		{"PostgreSQL", "'it''s'::character varying", Varchar, "it's", "", true},
+		{"PostgreSQL", "0", Int, "", "0", true},
+		{"PostgreSQL", "nextval('seq'::regclass)", Bigint, "", "nextval('seq'::regclass)", false},
+		{"MySQL", "abc", Varchar, "abc", "", true},
+		{"MySQL", "NULL", Varchar, "", "", true},
+	}
+	for _, tt := range tests {
+		value, origin, portable := splitColumnDefault(tt.dbms, &ColumnSnapshot{DefaultValue: tt.value}, tt.dataType)
+		if value != tt.wantValue || origin != tt.wantOrigin || portable != tt.wantPortable {
+			t.Errorf("splitColumnDefault(%q) = (%q, %q, %v)", tt.value, value, origin, portable)
+		}
+	}
+}
+
+func TestGenerateChangelog(t *testing.T) {
+	metaData := newMockMetaData()
+	metaData.tables[0].Comment = "用户"
+	metaData.columns["users"] = append(metaData.columns["users"], &Column{Name: "profile", DataType: "JSONB", Nullable: true, Ordinal: 3})
+	changelog, err := GenerateChangelog(context.Background(), &SqlDriver{}, metaData, WithGenerateAuthor("tester"))
+	if err != nil {
+		t.Fatalf("GenerateChangelog() error = %v", err)
+	}
+	if len(changelog.ChangeSets) != 2 || changelog.ChangeSets[1].Id != "init-users" || changelog.ChangeSets[1].Author != "tester" {
+		t.Fatalf("changeSets = %+v", changelog.ChangeSets)
+	}
+
+	data, err := changelog.MarshalIndentXML()
+	if err != nil {
+		t.Fatalf("MarshalIndentXML() error = %v", err)
+	}
+	changeSets, err := (&Dbfly{}).parseXmlContent("generated.xml", data, map[string]bool{})
+	if err != nil {
+		t.Fatalf("parse generated changelog error = %v\n%s", err, data)
+	}
+	if len(changeSets) != 2 {
+		t.Fatalf("parsed %d changeSets, want 2", len(changeSets))
+	}
+	users := changeSets[1]
+	createTable, ok := users.DDLs[0].(*CreateTableNode)
+	if !ok || createTable.TableName != "users" || createTable.Comment != "用户" {
+		t.Fatalf("first DDL = %#v, want createTable users", users.DDLs[0])
+	}
+	id, profile := createTable.Columns[0], createTable.Columns[2]
+	if id.DataType != Bigint || !id.PrimaryKey || id.KeyName != "pk_users" {
+		t.Errorf("id column = %+v", id)
+	}
+	if profile.DataType != Text || len(profile.ColumnDbms) != 1 || profile.ColumnDbms[0].DataType != "JSONB" {
+		t.Errorf("profile column = %+v", profile)
+	}
+	if index, ok := users.DDLs[1].(*CreateIndexNode); !ok || index.IndexName != "idx_name" {
+		t.Errorf("second DDL = %#v, want createIndex idx_name", users.DDLs[1])
+	}
+}
+
+func TestGenerateChangelog_InternalTables(t *testing.T) {
+	metaData := withDbflyTables(newMockMetaData())
+	metaData.tables = append(metaData.tables, &Table{Name: "APP_SCHEMA_VERSION", TableType: "TABLE"})
+	changelog, err := GenerateChangelog(context.Background(), &SqlDriver{}, metaData, WithGenerateInternalTables("app_schema_version"))
+	if err != nil {
+		t.Fatalf("GenerateChangelog() error = %v", err)
+	}
+	for _, changeSet := range changelog.ChangeSets {
+		if changeSet.Id != "init-orders" && changeSet.Id != "init-users" {
+			t.Errorf("internal table exported: %s", changeSet.Id)
+		}
+	}
+
+	source := &DiffDatabase{Driver: &SqlDriver{}, MetaData: metaData}
+	target := &DiffDatabase{Driver: &SqlDriver{}, MetaData: newMockMetaData()}
+	result, err := Diff(context.Background(), source, target, WithDiffInternalTables("APP_SCHEMA_VERSION"))
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if result.HasDifferences() {
+		t.Errorf("internal tables reported as differences:\n%s", result.Report())
+	}
+}
+
+// dataDriver 查询时返回固定的数据行
+type dataDriver struct {
+	recordDriver
+	rows [][]interface{}
+}
+
+func (d *dataDriver) Query(_ context.Context, sql string, _ ...interface{}) (Rows, error) {
+	d.sqls = append(d.sqls, sql)
+	return &valueRows{values: d.rows}, nil
+}
+
+func TestGenerateChangelog_TypedValues(t *testing.T) {
+	metaData := newMockMetaData()
+	metaData.columns["users"] = append(metaData.columns["users"],
+		&Column{Name: "balance", DataType: "DECIMAL", NumericPrecision: 10, NumericScale: 2, Nullable: true, Ordinal: 3},
+		&Column{Name: "created_at", DataType: "TIMESTAMP", Nullable: true, Ordinal: 4},
+		&Column{Name: "birthday", DataType: "DATE", Nullable: true, Ordinal: 5})
+	driver := &dataDriver{rows: [][]interface{}{{"1", "tom", "12.50", "2024-01-01T10:00:00Z", "unknown"}}}
+	changelog, err := GenerateChangelog(context.Background(), driver, metaData, WithGenerateTables("users"), WithGenerateData(0))
+	if err != nil {
+		t.Fatalf("GenerateChangelog() error = %v", err)
+	}
+	ddls := changelog.ChangeSets[0].DDLs
+	insert, ok := ddls[len(ddls)-1].(*InsertNode)
+	if !ok || len(insert.Rows) != 1 || len(insert.Rows[0].Columns) != 5 {
+		t.Fatalf("insert = %#v", ddls[len(ddls)-1])
+	}
+	want := []DataColumnNode{
+		{Name: "id", ValueNumeric: "1"},
+		{Name: "name", Value: "tom"},
+		{Name: "balance", ValueNumeric: "12.50"},
+		{Name: "created_at", ValueDate: "2024-01-01T10:00:00Z"},
+		// 无法按日期解析的值保留为字符串
+		{Name: "birthday", Value: "unknown"},
+	}
+	for i, column := range insert.Rows[0].Columns {
+		if *column != want[i] {
+			t.Errorf("column %d = %+v, want %+v", i, *column, want[i])
+		}
+	}
+}
+
+func TestGenerateChangelog_SkipBinaryColumns(t *testing.T) {
+	metaData := newMockMetaData()
+	metaData.columns["users"] = append(metaData.columns["users"],
+		&Column{Name: "avatar", DataType: "VARBINARY", Nullable: true, Ordinal: 3},
+		&Column{Name: "score", DataType: "BINARY_DOUBLE", Nullable: true, Ordinal: 4})
+	driver := &dataDriver{rows: [][]interface{}{{"1", "tom", "1.5"}}}
+	changelog, err := GenerateChangelog(context.Background(), driver, metaData, WithGenerateTables("users"), WithGenerateData(0))
+	if err != nil {
+		t.Fatalf("GenerateChangelog() error = %v", err)
+	}
+	if len(driver.sqls) != 1 || driver.sqls[0] != `SELECT "id", "name", "score" FROM "users" ORDER BY "id"` {
+		t.Errorf("query = %q", driver.sqls)
+	}
+	ddls := changelog.ChangeSets[0].DDLs
+	insert, ok := ddls[len(ddls)-1].(*InsertNode)
+	if !ok || len(insert.Rows) != 1 || len(insert.Rows[0].Columns) != 3 || insert.Rows[0].Columns[2].Value != "1.5" {
+		t.Errorf("insert = %#v", ddls[len(ddls)-1])
+	}
+}
@@ -0,0 +1,167 @@
+package dbfly
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// KingbaseES 兼容模式，对应数据库参数 database_mode
+const (
+	KingbaseModePg     = "pg"
+	KingbaseModeOracle = "oracle"
+	KingbaseModeMysql  = "mysql"
+)
+
+// KingbaseDatabaseMetaData KingbaseES元数据实现，系统表与 PostgreSQL 兼容，数据类型随兼容模式变化
+type KingbaseDatabaseMetaData struct {
+	*PostgresDatabaseMetaData
+	mu       sync.Mutex
+	mode     string
+	detected bool
+}
+
+type KingbaseOption func(*KingbaseDatabaseMetaData)
+
+// WithKingbaseMode 指定兼容模式，指定后不再自动探测
+func WithKingbaseMode(mode string) KingbaseOption {
+	return func(m *KingbaseDatabaseMetaData) {
+		if mode != "" {
+			m.mode = mode
+			m.detected = true
+		}
+	}
+}
+
+func NewKingbaseDatabaseMetaData(opts ...KingbaseOption) *KingbaseDatabaseMetaData {
+	m := &KingbaseDatabaseMetaData{
+		PostgresDatabaseMetaData: NewPostgresDatabaseMetaData(),
+		mode:                     KingbaseModePg,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+func (m *KingbaseDatabaseMetaData) Dbms() string {
+	return "KingbaseES"
+}
+
+// Mode 兼容模式，探测前为 pg 模式
+func (m *KingbaseDatabaseMetaData) Mode() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.mode
+}
+
+// DetectServer 通过数据库参数 database_mode 探测兼容模式，只探测一次
+func (m *KingbaseDatabaseMetaData) DetectServer(ctx context.Context, driver Driver) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.detected {
+		return nil
+	}
+	mode, err := DetectKingbaseMode(ctx, driver)
+	if err != nil {
+		return Wrap(err, "detect database mode failed")
+	}
+	if mode = strings.ToLower(strings.TrimSpace(mode)); mode != "" {
+		m.mode = mode
+	}
+	m.detected = true
+	return nil
+}
+
+func (m *KingbaseDatabaseMetaData) DataType(str string) string {
+	switch m.Mode() {
+	case KingbaseModeOracle:
+		switch str {
+		case Varchar:
+			return "VARCHAR2"
+		case Clob:
+			return "CLOB"
+		case Blob:
+			return "BLOB"
+		case Decimal:
+			return "NUMBER"
+		}
+	case KingbaseModeMysql:
+		switch str {
+		case Tinyint:
+			return "TINYINT"
+		case Clob:
+			return "LONGTEXT"
+		case Blob:
+			return "LONGBLOB"
+		case Timestamp:
+			return "DATETIME"
+		}
+	}
+	switch str {
+	case Boolean:
+		return "BOOLEAN"
+	case Int:
+		return "INT"
+	}
+	return m.PostgresDatabaseMetaData.DataType(str)
+}
+
+// Version KingbaseES 的 version() 形如 KingbaseES V008R006C008B0014 on ...，转换为 8.6.8
+func (m *KingbaseDatabaseMetaData) Version(ctx context.Context, driver Driver) (string, error) {
+	return m.queryVersion(ctx, driver, "SELECT version()", parseKingbaseVersion)
+}
+
+var kingbaseVersionPattern = regexp.MustCompile(`V(\d+)R(\d+)(?:C(\d+))?`)
+
+func parseKingbaseVersion(str string) string {
+	match := kingbaseVersionPattern.FindStringSubmatch(str)
+	if match == nil {
+		return leadingVersion(str)
+	}
+	parts := make([]string, 0, 3)
+	for _, part := range match[1:] {
+		if part == "" {
+			break
+		}
+		n, _ := strconv.Atoi(part)
+		parts = append(parts, strconv.Itoa(n))
+	}
+	return strings.Join(parts, ".")
+}
+
+// DetectKingbaseMode 查询数据库当前的兼容模式
+func DetectKingbaseMode(ctx context.Context, driver Driver) (string, error) {
+	return doGetScalar[string](ctx, driver, "SHOW database_mode")
+}
+
+// KingbaseMigratory KingbaseES迁移实现
+type KingbaseMigratory struct {
+	DefaultMigratory
+}
+
+// NewKingbaseMigratory 创建一个KingbaseES迁移实现实例
+func NewKingbaseMigratory(opts ...KingbaseOption) Migratory {
+	return &KingbaseMigratory{
+		DefaultMigratory: NewDefaultMigratory("kingbase", NewKingbaseDatabaseMetaData(opts...)),
+	}
+}
+
+// SupportsTransactionalDDL KingbaseES 的 DDL 可以在事务中执行并回滚
+func (m *KingbaseMigratory) SupportsTransactionalDDL() bool {
+	return true
+}
+
+// AlterColumn pg 模式不支持 MODIFY，使用 ALTER COLUMN 子句修改列
+func (m *KingbaseMigratory) AlterColumn(ctx context.Context, driver Driver, tableName string, columnName string, column *AlterColumnColumnNode, attributes *AttributesNode) error {
+	if m.MetaData().(*KingbaseDatabaseMetaData).Mode() == KingbaseModePg {
+		return alterPostgresStyleColumn(ctx, &m.DefaultMigratory, driver, tableName, columnName, column)
+	}
+	return m.DefaultMigratory.AlterColumn(ctx, driver, tableName, columnName, column, attributes)
+}
+
+func (m *KingbaseMigratory) DropPrimaryKey(ctx context.Context, driver Driver, tableName string, _ *AttributesNode) error {
+	return dropPrimaryKeyConstraint(ctx, &m.DefaultMigratory, driver, tableName)
+}
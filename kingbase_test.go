@@ -0,0 +1,60 @@
+package dbfly
+
+import (
+	"context"
+	"testing"
+)
+
+func TestKingbaseDatabaseMetaData_DataType(t *testing.T) {
+	tests := []struct {
+		mode, dataType, want string
+	}{
+		{"", Varchar, "VARCHAR"},
+		{"", Blob, "BYTEA"},
+		{KingbaseModeOracle, Varchar, "VARCHAR2"},
+		{KingbaseModeOracle, Decimal, "NUMBER"},
+		{KingbaseModeMysql, Timestamp, "DATETIME"},
+		{KingbaseModeMysql, Boolean, "BOOLEAN"},
+	}
+	for _, tt := range tests {
+		metaData := NewKingbaseDatabaseMetaData(WithKingbaseMode(tt.mode))
+		if got := metaData.DataType(tt.dataType); got != tt.want {
+			t.Errorf("mode %q DataType(%s) = %s, want %s", tt.mode, tt.dataType, got, tt.want)
+		}
+	}
+}
+
+// modeDriver 模拟 SHOW database_mode 的结果
+type modeDriver struct {
+	recordDriver
+	mode    string
+	queries int
+}
+
+func (d *modeDriver) Query(context.Context, string, ...interface{}) (Rows, error) {
+	d.queries++
+	return &valueRows{values: [][]interface{}{{d.mode}}}, nil
+}
+
+func TestKingbaseDatabaseMetaData_DetectServer(t *testing.T) {
+	driver := &modeDriver{mode: "ORACLE"}
+	metaData := NewKingbaseDatabaseMetaData()
+	for i := 0; i < 2; i++ {
+		if err := metaData.DetectServer(context.Background(), driver); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if metaData.Mode() != KingbaseModeOracle || metaData.DataType(Varchar) != "VARCHAR2" || driver.queries != 1 {
+		t.Errorf("mode = %q, queries = %d", metaData.Mode(), driver.queries)
+	}
+
+	// 指定的兼容模式优先于探测结果
+	driver = &modeDriver{mode: "oracle"}
+	metaData = NewKingbaseDatabaseMetaData(WithKingbaseMode(KingbaseModeMysql))
+	if err := metaData.DetectServer(context.Background(), driver); err != nil {
+		t.Fatal(err)
+	}
+	if metaData.Mode() != KingbaseModeMysql || driver.queries != 0 {
+		t.Errorf("mode = %q, queries = %d", metaData.Mode(), driver.queries)
+	}
+}
@@ -0,0 +1,504 @@
+package dbfly
+
+import (
+	"context"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// loadData 列类型
+const (
+	LoadDataString   = "STRING"
+	LoadDataNumeric  = "NUMERIC"
+	LoadDataBoolean  = "BOOLEAN"
+	LoadDataDate     = "DATE"
+	LoadDataComputed = "COMPUTED"
+	LoadDataBlob     = "BLOB"
+	LoadDataClob     = "CLOB"
+	LoadDataSkip     = "SKIP"
+)
+
+const (
+	defaultLoadDataBatchSize = 100
+	defaultLoadDataNullValue = "NULL"
+)
+
+// LoadDataColumnNode CSV列映射
+type LoadDataColumnNode struct {
+	// Header CSV表头名称
+	Header string `xml:"header,attr"`
+	// Name 表列名，默认与表头相同
+	Name string `xml:"name,attr,omitempty"`
+	// Type 列类型，默认为 STRING
+	Type string `xml:"type,attr,omitempty"`
+}
+
+// LoadDataNode 从CSV文件加载数据
+type LoadDataNode struct {
+	TableName string `xml:"tableName,attr"`
+	File      string `xml:"file,attr"`
+	// Separator 分隔符，默认为逗号
+	Separator string `xml:"separator,attr,omitempty"`
+	// Quote 引号字符，默认为双引号
+	Quote string `xml:"quote,attr,omitempty"`
+	// Encoding 文件编码，支持 UTF-8、UTF-16、UTF-16LE、UTF-16BE、ISO-8859-1
+	Encoding string `xml:"encoding,attr,omitempty"`
+	// NullValue 表示 NULL 的单元格内容，默认为 NULL
+	NullValue string `xml:"nullValue,attr,omitempty"`
+	// BatchSize 每条语句写入的行数
+	BatchSize  int                   `xml:"batchSize,attr,omitempty"`
+	Conditions *ConditionsNode       `xml:"conditions"`
+	Columns    []*LoadDataColumnNode `xml:"column"`
+}
+
+func (n *LoadDataNode) Execute(ctx context.Context, fly *Dbfly) error {
+	if ok, err := n.Conditions.Check(ctx, fly); !ok || err != nil {
+		return err
+	}
+	return n.load(ctx, fly, nil)
+}
+
+// LoadUpdateDataNode 从CSV文件加载数据，按主键存在则更新、不存在则插入
+type LoadUpdateDataNode struct {
+	LoadDataNode
+	// PrimaryKey 主键列，多个以逗号分隔，默认查询表的主键
+	PrimaryKey string `xml:"primaryKey,attr,omitempty"`
+}
+
+func (n *LoadUpdateDataNode) Execute(ctx context.Context, fly *Dbfly) error {
+	if ok, err := n.Conditions.Check(ctx, fly); !ok || err != nil {
+		return err
+	}
+	var keys []string
+	for _, key := range strings.Split(n.PrimaryKey, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		primaryKeys, err := fly.Migratory().MetaData().GetPrimaryKeys(ctx, fly.Driver(), n.TableName)
+		if err != nil {
+			return Wrap(err, "failed to get primary keys of table %q", n.TableName)
+		}
+		for _, primaryKey := range primaryKeys {
+			keys = append(keys, primaryKey.ColumnName)
+		}
+	}
+	if len(keys) == 0 {
+		return New("table %q has no primary key, set primaryKey of loadUpdateData", n.TableName)
+	}
+	return n.load(ctx, fly, keys)
+}
+
+// loadDataColumn 已与表头对应的列
+type loadDataColumn struct {
+	index    int
+	name     string
+	dataType string
+}
+
+// load 读取CSV并分批写入，keys 不为空时按主键更新
+func (n *LoadDataNode) load(ctx context.Context, fly *Dbfly, keys []string) error {
+	content, err := fly.Source().Read(n.File)
+	if err != nil {
+		return Wrap(err, "failed to read data file %q", n.File)
+	}
+	text, err := decodeText(content, n.Encoding)
+	if err != nil {
+		return Wrap(err, "failed to decode data file %q", n.File)
+	}
+	separator, quote := ',', '"'
+	if n.Separator != "" {
+		if n.Separator == `\t` {
+			separator = '\t'
+		} else {
+			separator, _ = utf8.DecodeRuneInString(n.Separator)
+		}
+	}
+	if n.Quote != "" {
+		quote, _ = utf8.DecodeRuneInString(n.Quote)
+	}
+	records, err := parseCsv(text, separator, quote)
+	if err != nil {
+		return Wrap(err, "failed to parse data file %q", n.File)
+	}
+	if len(records) < 2 {
+		fly.logger.Debug("data file %q has no rows", n.File)
+		return nil
+	}
+	columns, err := n.mapColumns(records[0])
+	if err != nil {
+		return err
+	}
+	// 主键列名与表头大小写可能不同（如 Oracle 返回大写列名），统一使用表头中的列名
+	keyNames := make([]string, len(keys))
+	for i, key := range keys {
+		column := findColumn(columns, key)
+		if column == nil {
+			return New("primary key column %q is missing in data file %q", key, n.File)
+		}
+		keyNames[i] = column.name
+	}
+
+	nullValue := n.NullValue
+	if nullValue == "" {
+		nullValue = defaultLoadDataNullValue
+	}
+	rows := make([][]string, 0, len(records)-1)
+	args := make([][]any, 0, len(records)-1)
+	for i, record := range records[1:] {
+		if len(record) != len(records[0]) {
+			return New("line %d of data file %q has %d fields, header has %d", i+2, n.File, len(record), len(records[0]))
+		}
+		values := make([]string, len(columns))
+		var rowArgs []any
+		for j, column := range columns {
+			value, raw, err := column.value(record[column.index], nullValue, fly.Source())
+			if err != nil {
+				return Wrap(err, "invalid value at line %d of data file %q", i+2, n.File)
+			}
+			if raw {
+				values[j] = record[column.index]
+				continue
+			}
+			values[j] = "?"
+			rowArgs = append(rowArgs, value)
+		}
+		rows = append(rows, values)
+		args = append(args, rowArgs)
+	}
+
+	writer := newDataWriter(fly.Migratory().MetaData(), n.TableName, columns, keyNames)
+	if writer == nil {
+		return New("loadUpdateData is not supported by %s", fly.Migratory().MetaData().Dbms())
+	}
+	batchSize := n.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultLoadDataBatchSize
+	}
+	fly.logger.Debug("load data into table %q from %q, rows: %d", n.TableName, n.File, len(rows))
+	for start := 0; start < len(rows); start += batchSize {
+		end := start + batchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		var batchArgs []any
+		for _, rowArgs := range args[start:end] {
+			batchArgs = append(batchArgs, rowArgs...)
+		}
+		if _, err = fly.Execute(ctx, writer(rows[start:end]), batchArgs...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mapColumns 按表头映射列，未配置的列按表头名称作为字符串写入
+func (n *LoadDataNode) mapColumns(header []string) ([]*loadDataColumn, error) {
+	configs := make(map[string]*LoadDataColumnNode, len(n.Columns))
+	for _, column := range n.Columns {
+		configs[column.Header] = column
+	}
+	columns := make([]*loadDataColumn, 0, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		column := &loadDataColumn{index: i, name: name, dataType: LoadDataString}
+		if config, ok := configs[name]; ok {
+			delete(configs, name)
+			if config.Name != "" {
+				column.name = config.Name
+			}
+			if config.Type != "" {
+				column.dataType = strings.ToUpper(config.Type)
+			}
+		}
+		switch column.dataType {
+		case LoadDataSkip:
+			continue
+		case LoadDataString, LoadDataNumeric, LoadDataBoolean, LoadDataDate, LoadDataComputed, LoadDataBlob, LoadDataClob:
+		default:
+			return nil, New("invalid type %q of column %q", column.dataType, name)
+		}
+		columns = append(columns, column)
+	}
+	for _, column := range n.Columns {
+		if _, ok := configs[column.Header]; ok {
+			return nil, New("column %q is missing in header of data file %q", column.Header, n.File)
+		}
+	}
+	if len(columns) == 0 {
+		return nil, New("data file %q has no columns to load", n.File)
+	}
+	return columns, nil
+}
+
+// value 转换单元格的值，raw 为 true 时单元格内容作为SQL表达式写入
+func (c *loadDataColumn) value(cell, nullValue string, source Source) (value any, raw bool, err error) {
+	if cell == nullValue {
+		return nil, false, nil
+	}
+	column := &DataColumnNode{Name: c.name}
+	switch c.dataType {
+	case LoadDataString:
+		return cell, false, nil
+	case LoadDataComputed:
+		return nil, true, nil
+	}
+	// 空单元格除字符串外均视为 NULL
+	if cell == "" {
+		return nil, false, nil
+	}
+	switch c.dataType {
+	case LoadDataNumeric:
+		column.ValueNumeric = cell
+	case LoadDataBoolean:
+		column.ValueBoolean = cell
+	case LoadDataDate:
+		column.ValueDate = cell
+	case LoadDataBlob:
+		column.ValueBlobFile = cell
+	case LoadDataClob:
+		column.ValueClobFile = cell
+	}
+	value, _, err = column.bindValue(source)
+	return value, false, err
+}
+
+// findColumn 按名称查找列，忽略大小写
+func findColumn(columns []*loadDataColumn, name string) *loadDataColumn {
+	for _, column := range columns {
+		if strings.EqualFold(column.name, name) {
+			return column
+		}
+	}
+	return nil
+}
+
+// dataWriter 生成一批行的写入语句，行内为已渲染的值（占位符或SQL表达式）
+type dataWriter func(rows [][]string) string
+
+// newDataWriter 按数据库生成插入或更新语句，keys 为与 columns 中名称一致的主键列，不支持更新时返回 nil
+func newDataWriter(metaData DatabaseMetaData, tableName string, columns []*loadDataColumn, keys []string) dataWriter {
+	quoter := metaData.Quoter()
+	table := quoter.MustQuote(tableName)
+	names := make([]string, len(columns))
+	var updates []string
+	for i, column := range columns {
+		names[i] = quoter.MustQuote(column.name)
+		isKey := false
+		for _, key := range keys {
+			if column.name == key {
+				isKey = true
+				break
+			}
+		}
+		if !isKey {
+			updates = append(updates, names[i])
+		}
+	}
+	columnList := strings.Join(names, ", ")
+	values := func(rows [][]string) string {
+		list := make([]string, len(rows))
+		for i, row := range rows {
+			list[i] = "(" + strings.Join(row, ", ") + ")"
+		}
+		return strings.Join(list, ", ")
+	}
+	insert := func(rows [][]string) string {
+		return "INSERT INTO " + table + " (" + columnList + ") VALUES " + values(rows)
+	}
+	oracle := matchDbms(metaData, "Oracle")
+	if oracle {
+		// Oracle 23c 之前不支持多行 VALUES，使用 INSERT ALL
+		insert = func(rows [][]string) string {
+			var builder strings.Builder
+			builder.WriteString("INSERT ALL")
+			for _, row := range rows {
+				builder.WriteString(" INTO " + table + " (" + columnList + ") VALUES (" + strings.Join(row, ", ") + ")")
+			}
+			builder.WriteString(" SELECT 1 FROM DUAL")
+			return builder.String()
+		}
+	}
+	if len(keys) == 0 {
+		return insert
+	}
+
+	quotedKeys := make([]string, len(keys))
+	for i, key := range keys {
+		quotedKeys[i] = quoter.MustQuote(key)
+	}
+	assign := func(format string) string {
+		list := make([]string, len(updates))
+		for i, name := range updates {
+			list[i] = strings.ReplaceAll(format, "%s", name)
+		}
+		return strings.Join(list, ", ")
+	}
+	switch {
+	case matchDbms(metaData, MysqlFlavorMysql):
+		return func(rows [][]string) string {
+			if len(updates) == 0 {
+				return insert(rows) + " ON DUPLICATE KEY UPDATE " + quotedKeys[0] + " = " + quotedKeys[0]
+			}
+			return insert(rows) + " ON DUPLICATE KEY UPDATE " + assign("%s = VALUES(%s)")
+		}
+	case matchDbms(metaData, "openGauss"), matchDbms(metaData, "GaussDB"), matchDbms(metaData, "VastBase"):
+		return func(rows [][]string) string {
+			if len(updates) == 0 {
+				return insert(rows) + " ON DUPLICATE KEY UPDATE NOTHING"
+			}
+			return insert(rows) + " ON DUPLICATE KEY UPDATE " + assign("%s = EXCLUDED.%s")
+		}
+	case matchDbms(metaData, "PostgreSQL"), matchDbms(metaData, "KingbaseES"), matchDbms(metaData, "SQLite"):
+		return func(rows [][]string) string {
+			conflict := insert(rows) + " ON CONFLICT (" + strings.Join(quotedKeys, ", ") + ") DO "
+			if len(updates) == 0 {
+				return conflict + "NOTHING"
+			}
+			return conflict + "UPDATE SET " + assign("%s = EXCLUDED.%s")
+		}
+	case oracle, matchDbms(metaData, "DM DBMS"):
+		return func(rows [][]string) string {
+			selects := make([]string, len(rows))
+			for i, row := range rows {
+				fields := make([]string, len(row))
+				for j, value := range row {
+					fields[j] = value + " " + names[j]
+				}
+				selects[i] = "SELECT " + strings.Join(fields, ", ") + " FROM DUAL"
+			}
+			return mergeSQL(table+" t", "("+strings.Join(selects, " UNION ALL ")+") s", quotedKeys, names, updates, "")
+		}
+	case matchDbms(metaData, "Microsoft SQL Server"):
+		return func(rows [][]string) string {
+			return mergeSQL(table+" AS t", "(VALUES "+values(rows)+") AS s ("+columnList+")", quotedKeys, names, updates, ";")
+		}
+	}
+	// ClickHouse 没有主键约束，直接插入在默认的 MergeTree 引擎下会重复写入，不支持更新
+	return nil
+}
+
+// mergeSQL 生成 MERGE 语句，目标表别名为 t、数据源别名为 s
+func mergeSQL(target, using string, keys, names, updates []string, terminator string) string {
+	var builder strings.Builder
+	builder.WriteString("MERGE INTO " + target + " USING " + using + " ON (")
+	for i, key := range keys {
+		if i > 0 {
+			builder.WriteString(" AND ")
+		}
+		builder.WriteString("t." + key + " = s." + key)
+	}
+	builder.WriteString(")")
+	if len(updates) > 0 {
+		builder.WriteString(" WHEN MATCHED THEN UPDATE SET ")
+		for i, name := range updates {
+			if i > 0 {
+				builder.WriteString(", ")
+			}
+			builder.WriteString("t." + name + " = s." + name)
+		}
+	}
+	sources := make([]string, len(names))
+	for i, name := range names {
+		sources[i] = "s." + name
+	}
+	builder.WriteString(" WHEN NOT MATCHED THEN INSERT (" + strings.Join(names, ", ") + ") VALUES (" + strings.Join(sources, ", ") + ")")
+	builder.WriteString(terminator)
+	return builder.String()
+}
+
+// decodeText 按编码将文件内容转换为字符串，并去除字节顺序标记
+func decodeText(content []byte, encoding string) (string, error) {
+	switch strings.ToUpper(strings.NewReplacer("-", "", "_", "").Replace(encoding)) {
+	case "", "UTF8":
+		return strings.TrimPrefix(string(content), "\uFEFF"), nil
+	case "UTF16":
+		if len(content) >= 2 && content[0] == 0xFF && content[1] == 0xFE {
+			return decodeUtf16(content[2:], false)
+		}
+		if len(content) >= 2 && content[0] == 0xFE && content[1] == 0xFF {
+			content = content[2:]
+		}
+		return decodeUtf16(content, true)
+	case "UTF16LE":
+		return decodeUtf16(content, false)
+	case "UTF16BE":
+		return decodeUtf16(content, true)
+	case "ISO88591", "LATIN1":
+		runes := make([]rune, len(content))
+		for i, b := range content {
+			runes[i] = rune(b)
+		}
+		return string(runes), nil
+	}
+	return "", New("unsupported encoding %q", encoding)
+}
+
+func decodeUtf16(content []byte, bigEndian bool) (string, error) {
+	if len(content)%2 != 0 {
+		return "", New("invalid UTF-16 content length %d", len(content))
+	}
+	units := make([]uint16, len(content)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(content[2*i])<<8 | uint16(content[2*i+1])
+		} else {
+			units[i] = uint16(content[2*i+1])<<8 | uint16(content[2*i])
+		}
+	}
+	return strings.TrimPrefix(string(utf16.Decode(units)), "\uFEFF"), nil
+}
+
+// parseCsv 解析CSV内容，引号内的分隔符、换行和连续两个引号按字面处理，忽略空行
+func parseCsv(text string, separator, quote rune) ([][]string, error) {
+	var records [][]string
+	var record []string
+	var field strings.Builder
+	inQuote, quoted := false, false
+	endRecord := func() {
+		record = append(record, field.String())
+		if len(record) > 1 || record[0] != "" || quoted {
+			records = append(records, record)
+		}
+		record = nil
+		field.Reset()
+		quoted = false
+	}
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if inQuote {
+			if r != quote {
+				field.WriteRune(r)
+			} else if i+1 < len(runes) && runes[i+1] == quote {
+				field.WriteRune(quote)
+				i++
+			} else {
+				inQuote = false
+			}
+			continue
+		}
+		switch {
+		case r == quote && field.Len() == 0 && !quoted:
+			inQuote, quoted = true, true
+		case r == separator:
+			record = append(record, field.String())
+			field.Reset()
+			quoted = false
+		case r == '\r' && i+1 < len(runes) && runes[i+1] == '\n':
+		case r == '\n' || r == '\r':
+			endRecord()
+		default:
+			field.WriteRune(r)
+		}
+	}
+	if inQuote {
+		return nil, New("unterminated quoted field")
+	}
+	if field.Len() > 0 || len(record) > 0 || quoted {
+		endRecord()
+	}
+	return records, nil
+}
@@ -0,0 +1,183 @@
+package dbfly
+
+import (
+	"context"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseCsv(t *testing.T) {
+	text := "id;name;remark\r\n1;'a;b';'it''s'\n\n2;'line1\nline2';\n"
+	records, err := parseCsv(text, ';', '\'')
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"id", "name", "remark"},
+		{"1", "a;b", "it's"},
+		{"2", "line1\nline2", ""},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("parseCsv() = %q, want %q", records, want)
+	}
+	if _, err = parseCsv(`1,"unterminated`, ',', '"'); err == nil {
+		t.Error("unterminated quote should fail")
+	}
+}
+
+func TestDecodeText(t *testing.T) {
+	tests := []struct {
+		content  []byte
+		encoding string
+		want     string
+	}{
+		{[]byte("\xEF\xBB\xBFid"), "", "id"},
+		{[]byte{0xFF, 0xFE, 'i', 0, 'd', 0}, "UTF-16", "id"},
+		{[]byte{0, 'i', 0, 'd'}, "utf-16be", "id"},
+		{[]byte{'c', 'a', 'f', 0xE9}, "ISO-8859-1", "café"},
+	}
+	for _, tt := range tests {
+		got, err := decodeText(tt.content, tt.encoding)
+		if err != nil || got != tt.want {
+			t.Errorf("decodeText(%q, %q) = %q, %v, want %q", tt.content, tt.encoding, got, err, tt.want)
+		}
+	}
+	if _, err := decodeText([]byte("id"), "GBK"); err == nil {
+		t.Error("unsupported encoding should fail")
+	}
+}
+
+func TestLoadDataNode_Execute(t *testing.T) {
+	source := NewFSSource(fstest.MapFS{
+		"data/status.csv": {Data: []byte("code,label,sort,enabled,note\nA,Active,1,true,x\nI,\"Inactive, old\",2,,NULL\nD,Deleted,NULL,false,y\n")},
+	})
+	node := &LoadDataNode{
+		TableName: "t_status",
+		File:      "data/status.csv",
+		BatchSize: 2,
+		Columns: []*LoadDataColumnNode{
+			{Header: "label", Name: "status_name"},
+			{Header: "sort", Type: LoadDataNumeric},
+			{Header: "enabled", Type: "boolean"},
+			{Header: "note", Type: LoadDataSkip},
+		},
+	}
+	driver := &recordDriver{}
+	fly := NewDbfly(NewPostgresMigratory(), NewPlaceholderDriver(driver, PlaceholderDollar), source)
+	if err := node.Execute(context.Background(), fly); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`INSERT INTO "t_status" ("code", "status_name", "sort", "enabled") VALUES ($1, $2, $3, $4), ($5, $6, $7, $8)`,
+		`INSERT INTO "t_status" ("code", "status_name", "sort", "enabled") VALUES ($1, $2, $3, $4)`,
+	}
+	if !reflect.DeepEqual(driver.sqls, want) {
+		t.Fatalf("Execute() sql = %q, want %q", driver.sqls, want)
+	}
+	wantArgs := [][]interface{}{
+		{"A", "Active", int64(1), true, "I", "Inactive, old", int64(2), nil},
+		{"D", "Deleted", nil, false},
+	}
+	if !reflect.DeepEqual(driver.args, wantArgs) {
+		t.Errorf("Execute() args = %#v, want %#v", driver.args, wantArgs)
+	}
+
+	node.Columns = append(node.Columns, &LoadDataColumnNode{Header: "missing"})
+	if err := node.Execute(context.Background(), fly); err == nil {
+		t.Error("missing header should fail")
+	}
+}
+
+func TestLoadUpdateDataNode_Execute(t *testing.T) {
+	source := NewFSSource(fstest.MapFS{
+		"data/status.csv": {Data: []byte("code,label,updated_at\nA,Active,NOW()\n")},
+	})
+	tests := []struct {
+		migratory Migratory
+		want      string
+	}{
+		{NewMysqlMigratory(), "INSERT INTO `t_status` (`code`, `label`, `updated_at`) VALUES (?, ?, NOW()) " +
+			"ON DUPLICATE KEY UPDATE `label` = VALUES(`label`), `updated_at` = VALUES(`updated_at`)"},
+		{NewPostgresMigratory(), `INSERT INTO "t_status" ("code", "label", "updated_at") VALUES (?, ?, NOW()) ` +
+			`ON CONFLICT ("code") DO UPDATE SET "label" = EXCLUDED."label", "updated_at" = EXCLUDED."updated_at"`},
+		{NewOracleMigratory(), `MERGE INTO "t_status" t USING (SELECT ? "code", ? "label", NOW() "updated_at" FROM DUAL) s ` +
+			`ON (t."code" = s."code") WHEN MATCHED THEN UPDATE SET t."label" = s."label", t."updated_at" = s."updated_at" ` +
+			`WHEN NOT MATCHED THEN INSERT ("code", "label", "updated_at") VALUES (s."code", s."label", s."updated_at")`},
+		{NewSqlServerMigratory(), `MERGE INTO [t_status] AS t USING (VALUES (?, ?, NOW())) AS s ([code], [label], [updated_at]) ` +
+			`ON (t.[code] = s.[code]) WHEN MATCHED THEN UPDATE SET t.[label] = s.[label], t.[updated_at] = s.[updated_at] ` +
+			`WHEN NOT MATCHED THEN INSERT ([code], [label], [updated_at]) VALUES (s.[code], s.[label], s.[updated_at]);`},
+	}
+	for _, tt := range tests {
+		node := &LoadUpdateDataNode{
+			LoadDataNode: LoadDataNode{
+				TableName: "t_status",
+				File:      "data/status.csv",
+				Columns:   []*LoadDataColumnNode{{Header: "updated_at", Type: LoadDataComputed}},
+			},
+			PrimaryKey: "code",
+		}
+		driver := &recordDriver{}
+		if err := node.Execute(context.Background(), NewDbfly(tt.migratory, driver, source)); err != nil {
+			t.Fatal(err)
+		}
+		if len(driver.sqls) != 1 || driver.sqls[0] != tt.want {
+			t.Errorf("%s Execute() = %q, want %q", tt.migratory.Name(), driver.sqls, tt.want)
+		}
+	}
+}
+
+func TestLoadUpdateDataNode_ClickHouse(t *testing.T) {
+	source := NewFSSource(fstest.MapFS{
+		"data/status.csv": {Data: []byte("code,label\nA,Active\n")},
+	})
+	node := &LoadUpdateDataNode{
+		LoadDataNode: LoadDataNode{TableName: "t_status", File: "data/status.csv"},
+		PrimaryKey:   "code",
+	}
+	driver := &recordDriver{}
+	err := node.Execute(context.Background(), NewDbfly(NewClickHouseMigratory(), driver, source))
+	if err == nil || !strings.Contains(err.Error(), "not supported by ClickHouse") || len(driver.sqls) != 0 {
+		t.Errorf("Execute() error = %v, sqls = %q", err, driver.sqls)
+	}
+}
+
+func TestLoadUpdateDataNode_KeyCase(t *testing.T) {
+	source := NewFSSource(fstest.MapFS{
+		"data/status.csv": {Data: []byte("code,label\nA,Active\n")},
+	})
+	// 主键列名与表头大小写不一致时使用表头中的列名
+	node := &LoadUpdateDataNode{
+		LoadDataNode: LoadDataNode{TableName: "t_status", File: "data/status.csv"},
+		PrimaryKey:   "CODE",
+	}
+	driver := &recordDriver{}
+	if err := node.Execute(context.Background(), NewDbfly(NewOracleMigratory(), driver, source)); err != nil {
+		t.Fatal(err)
+	}
+	want := `MERGE INTO "t_status" t USING (SELECT ? "code", ? "label" FROM DUAL) s ON (t."code" = s."code") ` +
+		`WHEN MATCHED THEN UPDATE SET t."label" = s."label" ` +
+		`WHEN NOT MATCHED THEN INSERT ("code", "label") VALUES (s."code", s."label")`
+	if len(driver.sqls) != 1 || driver.sqls[0] != want {
+		t.Errorf("Execute() = %q, want %q", driver.sqls, want)
+	}
+}
+
+func TestLoadDataNode_Unmarshal(t *testing.T) {
+	content := `<changeSet id="seed">
+		<loadUpdateData tableName="t_status" file="data/status.csv" separator=";" primaryKey="code" batchSize="50">
+			<column header="sort" type="NUMERIC"/>
+		</loadUpdateData>
+	</changeSet>`
+	var changeSet ChangeSetNode
+	if err := xml.Unmarshal([]byte(content), &changeSet); err != nil {
+		t.Fatal(err)
+	}
+	node, ok := changeSet.DDLs[0].(*LoadUpdateDataNode)
+	if !ok || node.TableName != "t_status" || node.Separator != ";" || node.PrimaryKey != "code" ||
+		node.BatchSize != 50 || len(node.Columns) != 1 || node.Columns[0].Type != LoadDataNumeric {
+		t.Errorf("unmarshal loadUpdateData = %+v", changeSet.DDLs[0])
+	}
+}
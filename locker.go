@@ -2,9 +2,12 @@ package dbfly
 
 import (
 	"context"
+	"crypto/rand"
 	sql2 "database/sql"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	mrand "math/rand"
 	"os"
 	"time"
 )
@@ -15,6 +18,9 @@ const (
 	defaultLockRetryInterval = 100 * time.Millisecond
 	defaultLockTimeout       = 30 * time.Second
 	defaultLockMaxRetries    = 300 // 30s / 100ms
+	defaultLockTTL           = 5 * time.Minute
+	// defaultLockMaxRetryInterval 指数退避的等待上限
+	defaultLockMaxRetryInterval = 5 * time.Second
 )
 
 const (
@@ -25,17 +31,81 @@ const (
 	LOCK_COLUMN_VERSION   = "VERSION"
 )
 
+// ErrLockLost 租约未能按时续期或已被其他进程接管
+var ErrLockLost = errors.New("lock lease lost")
+
+// ErrLockTimeout 等待锁超时或超过最大重试次数，具体信息见 LockTimeoutError
+var ErrLockTimeout = errors.New("lock acquisition timeout")
+
+// LockTimeoutError 等待锁超时，可通过 errors.As 获取超时时的持有者
+type LockTimeoutError struct {
+	// Holder 当前持有者，无法获取时为空
+	Holder   string
+	Waited   time.Duration
+	Attempts int
+}
+
+func (e *LockTimeoutError) Error() string {
+	holder := e.Holder
+	if holder == "" {
+		holder = "unknown"
+	}
+	return fmt.Sprintf("lock acquisition timeout after %s and %d attempts, held by %s", e.Waited.Round(time.Millisecond), e.Attempts, holder)
+}
+
+func (e *LockTimeoutError) Unwrap() error {
+	return ErrLockTimeout
+}
+
+// LockWait 等待锁时的状态
+type LockWait struct {
+	Attempt int
+	// Holder 当前持有者，无法获取时为空
+	Holder string
+	// Waited 已等待时长
+	Waited time.Duration
+	// Next 下一次重试前的等待时长
+	Next time.Duration
+}
+
+// LockWaitFunc 加锁失败、等待重试前的回调，可用于在就绪探针中报告正在等待迁移锁
+type LockWaitFunc func(LockWait)
+
+// ErrLockAdminUnsupported 锁不支持查询状态与强制释放
+var ErrLockAdminUnsupported = errors.New("locker does not support administration")
+
 type Unlock func(context.Context, *Dbfly) error
 
 type Locker interface {
 	Lock(context.Context, *Dbfly) (Unlock, error)
 }
 
+// LockStatus 迁移锁的状态
+type LockStatus struct {
+	Locked bool
+	// Owner 持有者标识
+	Owner string
+	// Since 加锁时间，DbLocker 为最近一次续期的时间
+	Since   time.Time
+	Version int
+}
+
+// LockAdmin 支持查询状态与强制释放的锁
+type LockAdmin interface {
+	Status(context.Context, *Dbfly) (*LockStatus, error)
+	ForceRelease(context.Context, *Dbfly) error
+}
+
+// DbLocker 基于锁表的租约锁，LOCK_TIME 早于租约时长的锁视为失效，可被其他进程接管；
+// 持有期间由后台心跳续期，续期失败超过租约时长或被接管时中止迁移
 type DbLocker struct {
-	tableName     string
-	retryInterval time.Duration
-	timeout       time.Duration
-	maxRetries    int
+	tableName         string
+	retryInterval     time.Duration
+	maxRetryInterval  time.Duration
+	timeout           time.Duration
+	maxRetries        int
+	ttl               time.Duration
+	heartbeatInterval time.Duration
 }
 
 type LockerOption func(*DbLocker)
@@ -56,6 +126,15 @@ func WithLockRetryInterval(d time.Duration) LockerOption {
 	}
 }
 
+// WithLockMaxRetryInterval 设置重试等待时长的上限，等待时长从重试间隔开始按指数增长
+func WithLockMaxRetryInterval(d time.Duration) LockerOption {
+	return func(l *DbLocker) {
+		if d > 0 {
+			l.maxRetryInterval = d
+		}
+	}
+}
+
 func WithLockTimeout(d time.Duration) LockerOption {
 	return func(l *DbLocker) {
 		if d > 0 {
@@ -72,121 +151,267 @@ func WithLockMaxRetries(n int) LockerOption {
 	}
 }
 
+// WithLockTTL 设置租约时长，需大于各节点间的时钟偏差，默认为 5 分钟
+func WithLockTTL(d time.Duration) LockerOption {
+	return func(l *DbLocker) {
+		if d > 0 {
+			l.ttl = d
+		}
+	}
+}
+
+// WithLockHeartbeatInterval 设置续期间隔，默认为租约时长的三分之一
+func WithLockHeartbeatInterval(d time.Duration) LockerOption {
+	return func(l *DbLocker) {
+		if d > 0 {
+			l.heartbeatInterval = d
+		}
+	}
+}
+
 func NewDbLocker(opts ...LockerOption) *DbLocker {
 	l := &DbLocker{
-		tableName:     defaultChangeLockTableName,
-		retryInterval: defaultLockRetryInterval,
-		timeout:       defaultLockTimeout,
-		maxRetries:    defaultLockMaxRetries,
+		tableName:        defaultChangeLockTableName,
+		retryInterval:    defaultLockRetryInterval,
+		maxRetryInterval: defaultLockMaxRetryInterval,
+		timeout:          defaultLockTimeout,
+		maxRetries:       defaultLockMaxRetries,
+		ttl:              defaultLockTTL,
 	}
 	for _, opt := range opts {
 		opt(l)
 	}
+	if l.heartbeatInterval <= 0 || l.heartbeatInterval >= l.ttl {
+		l.heartbeatInterval = l.ttl / 3
+	}
 	return l
 }
 
+// TableName 锁表名称
+func (l *DbLocker) TableName() string {
+	return l.tableName
+}
+
+// TTL 租约时长
+func (l *DbLocker) TTL() time.Duration {
+	return l.ttl
+}
+
 func (l *DbLocker) Lock(ctx context.Context, fly *Dbfly) (Unlock, error) {
-	migratory := fly.Migratory()
 	driver := fly.Driver()
-	metaData := migratory.MetaData()
 
 	// 1. 确保锁表存在
 	if err := l.createLockTable(ctx, fly); err != nil {
 		return nil, err
 	}
 
-	hostname, err := os.Hostname()
-	if err != nil {
-		hostname = "unknown"
-	}
-	quoter := metaData.Quoter()
+	owner := fly.LockOwner()
+	quoter := fly.Migratory().MetaData().Quoter()
 
-	// 计算截止时间
-	deadline := time.Now().Add(l.timeout)
+	// 2. 未锁定或租约已过期时接管，版本号递增使每次加锁都能被区分
+	acquireSQL := fmt.Sprintf("UPDATE %s SET %s = 1, %s = ?, %s = ?, %s = %s + 1 WHERE %s = 1 AND (%s = 0 OR %s IS NULL OR %s < ?)",
+		quoter.MustQuote(l.tableName),
+		quoter.MustQuote(LOCK_COLUMN_LOCKED),
+		quoter.MustQuote(LOCK_COLUMN_LOCKED_BY),
+		quoter.MustQuote(LOCK_COLUMN_LOCK_TIME),
+		quoter.MustQuote(LOCK_COLUMN_VERSION), quoter.MustQuote(LOCK_COLUMN_VERSION),
+		quoter.MustQuote(LOCK_COLUMN_ID),
+		quoter.MustQuote(LOCK_COLUMN_LOCKED),
+		quoter.MustQuote(LOCK_COLUMN_LOCK_TIME),
+		quoter.MustQuote(LOCK_COLUMN_LOCK_TIME))
+	// 3. 锁记录不存在时插入，主键冲突说明其他进程已插入
+	insertSQL := fmt.Sprintf("INSERT INTO %s(%s, %s, %s, %s, %s) VALUES(1, 1, ?, ?, 1)",
+		quoter.MustQuote(l.tableName),
+		quoter.MustQuote(LOCK_COLUMN_ID),
+		quoter.MustQuote(LOCK_COLUMN_LOCKED),
+		quoter.MustQuote(LOCK_COLUMN_LOCKED_BY),
+		quoter.MustQuote(LOCK_COLUMN_LOCK_TIME),
+		quoter.MustQuote(LOCK_COLUMN_VERSION))
+	countSQL := fmt.Sprintf("SELECT COUNT(1) FROM %s WHERE %s = 1",
+		quoter.MustQuote(l.tableName),
+		quoter.MustQuote(LOCK_COLUMN_ID))
 
-	// 重试循环
-	for retry := 0; retry < l.maxRetries; retry++ {
-		// 检查超时
-		if time.Now().After(deadline) {
-			fly.logger.Error("lock acquisition timeout after %d", l.timeout)
-			return nil, New("lock acquisition timeout after %d", l.timeout)
+	waiter := newLockWaiter(fly, l.retryInterval, l.maxRetryInterval, l.timeout, l.maxRetries)
+	for {
+		now := time.Now()
+		if _, err := driver.Execute(ctx, acquireSQL, owner, now, now.Add(-l.ttl)); err != nil {
+			return nil, Wrap(err, "acquire lock failed")
 		}
-
-		// 2. 乐观锁：查询当前版本
-		currentVersion := 0
-		sql := fmt.Sprintf("SELECT %s FROM %s WHERE %s = 1",
-			quoter.MustQuote(LOCK_COLUMN_VERSION),
-			quoter.MustQuote(l.tableName),
-			quoter.MustQuote(LOCK_COLUMN_ID))
-
-		if currentVersion, err = doGetScalar[int](ctx, driver, sql); err != nil && !errors.Is(err, NoData) {
-			return nil, Wrap(err, "get current database schema failed")
+		count, err := doGetScalar[int](ctx, driver, countSQL)
+		if err != nil {
+			return nil, Wrap(err, "get lock record failed")
 		}
-		if currentVersion > 0 {
-			newVersion := currentVersion + 1
-
-			// 3. UPDATE 带版本号条件（原子判断）
-			updateSQL := fmt.Sprintf("UPDATE %s SET %s = 1, %s = ?, %s = ?, %s = ? WHERE %s = 1 AND %s = ?",
-				quoter.MustQuote(l.tableName),
-				quoter.MustQuote(LOCK_COLUMN_LOCKED),
-				quoter.MustQuote(LOCK_COLUMN_LOCKED_BY),
-				quoter.MustQuote(LOCK_COLUMN_LOCK_TIME),
-				quoter.MustQuote(LOCK_COLUMN_VERSION),
-				quoter.MustQuote(LOCK_COLUMN_ID),
-				quoter.MustQuote(LOCK_COLUMN_VERSION))
-			var result sql2.Result
-			if result, err = driver.Execute(ctx, updateSQL, hostname, time.Now(), newVersion, currentVersion); err != nil {
-				// UPDATE 失败（版本不匹配），等待后重试
-				fly.logger.Warn("retry lock acquisition, attempt: %d, maxRetries: %d", retry+1, l.maxRetries)
-				time.Sleep(l.retryInterval)
-				continue
-			}
-			if affected, err := result.RowsAffected(); err != nil || affected == 0 {
-				// UPDATE 失败（版本不匹配），等待后重试
-				fly.logger.Warn("retry lock acquisition, attempt: %d, maxRetries: %d", retry+1, l.maxRetries)
-				time.Sleep(l.retryInterval)
-				continue
-			}
-			currentVersion = newVersion
-		} else {
-			currentVersion = 1
-			// 4. INSERT 新记录
-			insertSQL := fmt.Sprintf("INSERT INTO %s(%s, %s, %s, %s, %s) VALUES(1, 1, ?, ?, ?)",
-				quoter.MustQuote(l.tableName),
-				quoter.MustQuote(LOCK_COLUMN_ID),
-				quoter.MustQuote(LOCK_COLUMN_LOCKED),
-				quoter.MustQuote(LOCK_COLUMN_LOCKED_BY),
-				quoter.MustQuote(LOCK_COLUMN_LOCK_TIME),
-				quoter.MustQuote(LOCK_COLUMN_VERSION))
-			if _, err = driver.Execute(ctx, insertSQL, hostname, time.Now(), currentVersion); err != nil {
-				fly.logger.Warn("retry lock acquisition, attempt: %d, maxRetries: %d", retry+1, l.maxRetries)
-				// INSERT 失败（记录已存在），等待后重试
-				time.Sleep(l.retryInterval)
+		if count == 0 {
+			if _, err = driver.Execute(ctx, insertSQL, owner, now); err != nil {
+				if err = waiter.wait(ctx, ""); err != nil {
+					return nil, err
+				}
 				continue
 			}
 		}
 
-		// 5. 复查验证（消除 TOCTOU）
-		var lockedBy string
-		var version int
-		if lockedBy, version, err = l.getLockInfo(ctx, fly); err != nil {
+		// 4. 复查锁定者（不依赖各驱动 RowsAffected 的语义）
+		lockedBy, version, err := l.getLockInfo(ctx, fly, driver)
+		if err != nil && !errors.Is(err, NoData) {
 			return nil, err
 		}
-		if lockedBy != hostname || currentVersion != version {
-			fly.logger.Warn("retry lock acquisition, attempt: %d, maxRetries: %d", retry+1, l.maxRetries)
-			// 验证失败，等待后重试
-			time.Sleep(l.retryInterval)
+		if lockedBy != owner {
+			if err = waiter.wait(ctx, lockedBy); err != nil {
+				return nil, err
+			}
 			continue
 		}
 
+		fly.logger.Debug("lock acquired by %s, version: %d", owner, version)
+		stop := l.heartbeat(ctx, fly, driver, owner)
 		unlockFunc := func(ctx context.Context, fly *Dbfly) error {
-			return l.Unlock(ctx, fly, hostname, version)
+			stop()
+			return l.Unlock(ctx, fly, owner, version)
 		}
-
 		return unlockFunc, nil
 	}
+}
+
+// lockWaiter 加锁失败后的等待策略：等待时长从 interval 开始按指数增长至 maxInterval，
+// 并加入随机抖动，避免多个实例同时重试
+type lockWaiter struct {
+	fly         *Dbfly
+	interval    time.Duration
+	maxInterval time.Duration
+	timeout     time.Duration
+	// maxRetries 最大尝试次数，0 表示只受超时限制
+	maxRetries int
+	start      time.Time
+	attempt    int
+}
+
+func newLockWaiter(fly *Dbfly, interval, maxInterval, timeout time.Duration, maxRetries int) *lockWaiter {
+	if maxInterval < interval {
+		maxInterval = interval
+	}
+	return &lockWaiter{
+		fly:         fly,
+		interval:    interval,
+		maxInterval: maxInterval,
+		timeout:     timeout,
+		maxRetries:  maxRetries,
+		start:       time.Now(),
+	}
+}
+
+// wait 在下一次尝试前等待，等待期间响应上下文取消，超时或超过最大尝试次数时返回 LockTimeoutError
+func (w *lockWaiter) wait(ctx context.Context, holder string) error {
+	w.attempt++
+	waited := time.Since(w.start)
+	if waited >= w.timeout || (w.maxRetries > 0 && w.attempt >= w.maxRetries) {
+		err := &LockTimeoutError{Holder: holder, Waited: waited, Attempts: w.attempt}
+		w.fly.logger.Error("%s", err.Error())
+		return err
+	}
+	delay := w.backoff()
+	if remaining := w.timeout - waited; delay > remaining {
+		delay = remaining
+	}
+	w.fly.logger.Warn("lock held by %q, retry in %s, attempt: %d", holder, delay.Round(time.Millisecond), w.attempt)
+	if w.fly.onLockWait != nil {
+		w.fly.onLockWait(LockWait{Attempt: w.attempt, Holder: holder, Waited: waited, Next: delay})
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// backoff 第 n 次等待的时长为 interval*2^(n-1)，不超过 maxInterval，取其一半加上一半以内的随机值
+func (w *lockWaiter) backoff() time.Duration {
+	d := w.interval
+	for i := 1; i < w.attempt && d < w.maxInterval; i++ {
+		d *= 2
+	}
+	if d > w.maxInterval {
+		d = w.maxInterval
+	}
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + time.Duration(mrand.Int63n(int64(half)+1))
+}
 
-	return nil, New("lock acquisition failed after %d retries", l.maxRetries)
+// heartbeat 后台定期续期租约，返回停止续期的函数
+func (l *DbLocker) heartbeat(ctx context.Context, fly *Dbfly, driver Driver, owner string) func() {
+	quoter := fly.Migratory().MetaData().Quoter()
+	renewSQL := fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = 1 AND %s = 1 AND %s = ?",
+		quoter.MustQuote(l.tableName),
+		quoter.MustQuote(LOCK_COLUMN_LOCK_TIME),
+		quoter.MustQuote(LOCK_COLUMN_ID),
+		quoter.MustQuote(LOCK_COLUMN_LOCKED),
+		quoter.MustQuote(LOCK_COLUMN_LOCKED_BY))
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(l.heartbeatInterval)
+		defer ticker.Stop()
+		renewed := time.Now()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			now := time.Now()
+			_, err := driver.Execute(ctx, renewSQL, now, owner)
+			if err == nil {
+				// MySQL 等驱动在值未变化时 RowsAffected 为 0，通过复查判断是否仍持有锁
+				var lockedBy string
+				lockedBy, _, err = l.getLockInfo(ctx, fly, driver)
+				if errors.Is(err, NoData) {
+					fly.logger.Error("lock released by others")
+					fly.abort(Wrap(ErrLockLost, "lock released by others"))
+					return
+				}
+				if err == nil && lockedBy != owner {
+					fly.logger.Error("lock taken over by %q", lockedBy)
+					fly.abort(Wrap(ErrLockLost, "lock taken over by %q", lockedBy))
+					return
+				}
+			}
+			if err == nil {
+				renewed = now
+				continue
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			fly.logger.Warn("renew lock lease failed: %+v", err)
+			if time.Since(renewed) >= l.ttl {
+				fly.logger.Error("lock lease expired, last renewed at %s", renewed.Format(time.RFC3339))
+				fly.abort(Wrap(ErrLockLost, "lock lease not renewed since %s", renewed.Format(time.RFC3339)))
+				return
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// defaultLockOwner 默认的锁持有者标识，同一主机上的多个进程或容器也能区分
+func defaultLockOwner() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	token := make([]byte, 4)
+	_, _ = rand.Read(token)
+	return fmt.Sprintf("%s:%d:%s", hostname, os.Getpid(), hex.EncodeToString(token))
 }
 
 func (l *DbLocker) createLockTable(ctx context.Context, fly *Dbfly) error {
@@ -199,7 +424,7 @@ func (l *DbLocker) createLockTable(ctx context.Context, fly *Dbfly) error {
 		return Wrap(err, "check lock table exists failed")
 	}
 	if exists {
-		return nil
+		return upgradeTable(ctx, fly, l.tableName, lockSchema)
 	}
 
 	quoter := metaData.Quoter()
@@ -223,11 +448,11 @@ func (l *DbLocker) createLockTable(ctx context.Context, fly *Dbfly) error {
 		}
 		return Wrap(err, "create lock table failed")
 	}
-	return nil
+	return setSchemaVersion(ctx, fly, l.tableName, lockSchema.version)
 }
 
 // getLockInfo 查询当前锁定者和版本号
-func (l *DbLocker) getLockInfo(ctx context.Context, fly *Dbfly) (string, int, error) {
+func (l *DbLocker) getLockInfo(ctx context.Context, fly *Dbfly, driver Driver) (string, int, error) {
 	quoter := fly.Migratory().MetaData().Quoter()
 
 	sql := fmt.Sprintf("SELECT %s, %s FROM %s WHERE %s = 1 AND %s = 1",
@@ -238,7 +463,7 @@ func (l *DbLocker) getLockInfo(ctx context.Context, fly *Dbfly) (string, int, er
 		quoter.MustQuote(LOCK_COLUMN_LOCKED))
 	var lockedBy string
 	var version int
-	rows, err := fly.Driver().Query(ctx, sql)
+	rows, err := driver.Query(ctx, sql)
 	if err != nil {
 		return "", 0, err
 	}
@@ -249,10 +474,92 @@ func (l *DbLocker) getLockInfo(ctx context.Context, fly *Dbfly) (string, int, er
 		}
 		return lockedBy, version, nil
 	}
-	return "", 0, New("lock record not found")
+	return "", 0, NoData
+}
+
+// Status 查询锁表中的锁状态，锁表或锁记录不存在时视为未锁定
+func (l *DbLocker) Status(ctx context.Context, fly *Dbfly) (*LockStatus, error) {
+	metaData := fly.Migratory().MetaData()
+	driver := fly.Driver()
+	status := &LockStatus{}
+	exists, _, err := metaData.ExistsTable(ctx, driver, l.tableName)
+	if err != nil || !exists {
+		return status, err
+	}
+
+	quoter := metaData.Quoter()
+	sql := fmt.Sprintf("SELECT %s, %s, %s, %s FROM %s WHERE %s = 1",
+		quoter.MustQuote(LOCK_COLUMN_LOCKED),
+		quoter.MustQuote(LOCK_COLUMN_LOCKED_BY),
+		quoter.MustQuote(LOCK_COLUMN_LOCK_TIME),
+		quoter.MustQuote(LOCK_COLUMN_VERSION),
+		quoter.MustQuote(l.tableName),
+		quoter.MustQuote(LOCK_COLUMN_ID))
+	var (
+		locked   sql2.NullInt64
+		lockedBy sql2.NullString
+		lockTime lockTime
+		version  sql2.NullInt64
+	)
+	err = doEach(ctx, driver, func(rows Rows) error {
+		return rows.Scan(&locked, &lockedBy, &lockTime, &version)
+	}, sql)
+	if err != nil {
+		return nil, Wrap(err, "get lock status failed")
+	}
+	status.Locked = locked.Int64 == 1
+	status.Owner = lockedBy.String
+	status.Since = lockTime.Time
+	status.Version = int(version.Int64)
+	return status, nil
+}
+
+// ForceRelease 无条件释放锁，版本号递增使原持有者的续期与释放失效
+func (l *DbLocker) ForceRelease(ctx context.Context, fly *Dbfly) error {
+	quoter := fly.Migratory().MetaData().Quoter()
+	sql := fmt.Sprintf("UPDATE %s SET %s = 0, %s = %s + 1 WHERE %s = 1",
+		quoter.MustQuote(l.tableName),
+		quoter.MustQuote(LOCK_COLUMN_LOCKED),
+		quoter.MustQuote(LOCK_COLUMN_VERSION), quoter.MustQuote(LOCK_COLUMN_VERSION),
+		quoter.MustQuote(LOCK_COLUMN_ID))
+	if _, err := fly.Driver().Execute(ctx, sql); err != nil {
+		return Wrap(err, "force release lock failed")
+	}
+	return nil
+}
+
+// lockTime 兼容以 time.Time、字符串或字节返回时间的驱动，如未开启 parseTime 的 MySQL 驱动
+type lockTime struct {
+	Time time.Time
+}
+
+func (t *lockTime) Scan(value any) error {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		t.Time = time.Time{}
+		return nil
+	case time.Time:
+		t.Time = v
+		return nil
+	case []byte:
+		text = string(v)
+	case string:
+		text = v
+	default:
+		return New("unsupported lock time type %T", value)
+	}
+	for _, layout := range dateLayouts {
+		if parsed, err := time.ParseInLocation(layout, text, time.Local); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+	return New("invalid lock time %q", text)
 }
 
-func (l *DbLocker) Unlock(ctx context.Context, fly *Dbfly, hostname string, version int) error {
+// Unlock 释放指定持有者与版本号的锁
+func (l *DbLocker) Unlock(ctx context.Context, fly *Dbfly, owner string, version int) error {
 	migratory := fly.Migratory()
 	driver := fly.Driver()
 	metaData := migratory.MetaData()
@@ -264,6 +571,6 @@ func (l *DbLocker) Unlock(ctx context.Context, fly *Dbfly, hostname string, vers
 		quoter.MustQuote(LOCK_COLUMN_ID),
 		quoter.MustQuote(LOCK_COLUMN_LOCKED_BY),
 		quoter.MustQuote(LOCK_COLUMN_VERSION))
-	_, err := driver.Execute(ctx, sql, hostname, version)
+	_, err := driver.Execute(ctx, sql, owner, version)
 	return err
 }
@@ -1,6 +1,11 @@
 package dbfly
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -63,3 +68,315 @@ func TestNewDbLocker_ZeroValuesIgnored(t *testing.T) {
 		t.Errorf("maxRetries = %d, want %d", l.maxRetries, defaultLockMaxRetries)
 	}
 }
+
+// leaseDriver 在内存中模拟锁表的单行记录
+type leaseDriver struct {
+	mu        sync.Mutex
+	exists    bool
+	locked    bool
+	lockedBy  string
+	lockTime  time.Time
+	version   int
+	failRenew bool
+}
+
+func (d *leaseDriver) Execute(_ context.Context, sql string, args ...interface{}) (sql.Result, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch {
+	case len(args) == 0:
+		// 强制释放
+		d.locked = false
+		d.version++
+	case strings.HasPrefix(sql, "INSERT"):
+		if d.exists {
+			return nil, New("duplicate key")
+		}
+		d.exists, d.locked, d.lockedBy, d.lockTime, d.version = true, true, args[0].(string), args[1].(time.Time), 1
+	case len(args) == 3:
+		// 加锁：未锁定或租约过期时接管
+		if d.exists && (!d.locked || d.lockTime.Before(args[2].(time.Time))) {
+			d.locked, d.lockedBy, d.lockTime = true, args[0].(string), args[1].(time.Time)
+			d.version++
+		}
+	case len(args) == 2:
+		if lockTime, ok := args[0].(time.Time); ok {
+			// 续期
+			if d.failRenew {
+				return nil, New("connection refused")
+			}
+			if d.locked && d.lockedBy == args[1] {
+				d.lockTime = lockTime
+			}
+		} else if d.lockedBy == args[0] && d.version == args[1] {
+			// 释放
+			d.locked = false
+		}
+	}
+	return nil, nil
+}
+
+func (d *leaseDriver) Query(_ context.Context, sql string, _ ...interface{}) (Rows, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if strings.Contains(sql, defaultSchemaVersionTableName) {
+		return &valueRows{values: [][]interface{}{{lockSchema.version}}}, nil
+	}
+	if strings.Contains(sql, "COUNT(1)") {
+		count := 0
+		if d.exists {
+			count = 1
+		}
+		return &valueRows{values: [][]interface{}{{count}}}, nil
+	}
+	if strings.Contains(sql, "LOCK_TIME") {
+		locked := 0
+		if d.locked {
+			locked = 1
+		}
+		return &valueRows{values: [][]interface{}{{int64(locked), d.lockedBy, d.lockTime, int64(d.version)}}}, nil
+	}
+	if d.locked {
+		return &valueRows{values: [][]interface{}{{d.lockedBy, d.version}}}, nil
+	}
+	return &valueRows{}, nil
+}
+
+func (d *leaseDriver) BeginTx(context.Context) (Tx, error) {
+	return nil, New("transaction is not supported")
+}
+
+func (d *leaseDriver) state() (bool, string, time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.locked, d.lockedBy, d.lockTime
+}
+
+// valueRows 按行返回固定值的查询结果
+type valueRows struct {
+	values [][]interface{}
+	index  int
+}
+
+func (r *valueRows) Close() error               { return nil }
+func (r *valueRows) Columns() ([]string, error) { return nil, nil }
+func (r *valueRows) Err() error                 { return nil }
+
+func (r *valueRows) Next() bool {
+	r.index++
+	return r.index <= len(r.values)
+}
+
+func (r *valueRows) Scan(dest ...interface{}) error {
+	for i, value := range r.values[r.index-1] {
+		switch d := dest[i].(type) {
+		case *int:
+			*d = value.(int)
+		case *string:
+			*d = value.(string)
+		case *interface{}:
+			*d = value
+		case sql.Scanner:
+			if err := d.Scan(value); err != nil {
+				return err
+			}
+		default:
+			return New("unsupported scan type %T", dest[i])
+		}
+	}
+	return nil
+}
+
+// leaseTables 锁表与结构版本表均已存在
+func leaseTables() []*Table {
+	return []*Table{{Name: defaultChangeLockTableName, TableType: "TABLE"}, {Name: defaultSchemaVersionTableName, TableType: "TABLE"}}
+}
+
+func newLeaseFly(driver Driver, opts ...LockerOption) *Dbfly {
+	metaData := &mockMetaData{dbms: "Mock", tables: leaseTables()}
+	migratory := NewDefaultMigratory("mock", metaData)
+	return NewDbfly(&migratory, driver, nil, WithLocker(NewDbLocker(opts...)))
+}
+
+func TestDbLocker_Lease(t *testing.T) {
+	ctx := context.Background()
+	driver := &leaseDriver{}
+	opts := []LockerOption{WithLockTimeout(50 * time.Millisecond), WithLockRetryInterval(10 * time.Millisecond), WithLockTTL(time.Minute)}
+
+	fly := newLeaseFly(driver, opts...)
+	unlock, err := fly.locker.Lock(ctx, fly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 租约有效期内其他进程无法加锁
+	other := newLeaseFly(driver, opts...)
+	if _, err = other.locker.Lock(ctx, other); err == nil {
+		t.Fatal("lock should be held by the first locker")
+	}
+	if err = unlock(ctx, fly); err != nil {
+		t.Fatal(err)
+	}
+	if locked, _, _ := driver.state(); locked {
+		t.Fatal("lock should be released")
+	}
+
+	// 持有者异常退出后，过期的锁可被接管
+	driver.locked, driver.lockedBy, driver.lockTime = true, "dead-pod", time.Now().Add(-time.Hour)
+	unlock, err = other.locker.Lock(ctx, other)
+	if err != nil {
+		t.Fatalf("stale lock should be taken over: %v", err)
+	}
+	if _, lockedBy, _ := driver.state(); lockedBy == "dead-pod" {
+		t.Error("lock owner should be replaced")
+	}
+	_ = unlock(ctx, other)
+}
+
+func TestDbLocker_Heartbeat(t *testing.T) {
+	driver := &leaseDriver{}
+	fly := newLeaseFly(driver, WithLockTTL(150*time.Millisecond), WithLockHeartbeatInterval(20*time.Millisecond))
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	fly.cancel = cancel
+
+	unlock, err := fly.locker.Lock(ctx, fly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, acquired := driver.state()
+	time.Sleep(80 * time.Millisecond)
+	if _, _, renewed := driver.state(); !renewed.After(acquired) {
+		t.Error("lease should be renewed by heartbeat")
+	}
+
+	// 被其他进程接管后中止迁移
+	driver.mu.Lock()
+	driver.lockedBy = "other"
+	driver.mu.Unlock()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("migration should be aborted when lease is lost")
+	}
+	if !errors.Is(context.Cause(ctx), ErrLockLost) {
+		t.Errorf("cause = %v, want ErrLockLost", context.Cause(ctx))
+	}
+	_ = unlock(context.Background(), fly)
+}
+
+func TestDbLocker_HeartbeatExpired(t *testing.T) {
+	driver := &leaseDriver{}
+	fly := newLeaseFly(driver, WithLockTTL(60*time.Millisecond), WithLockHeartbeatInterval(10*time.Millisecond))
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	fly.cancel = cancel
+
+	unlock, err := fly.locker.Lock(ctx, fly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = unlock(context.Background(), fly) }()
+	driver.mu.Lock()
+	driver.failRenew = true
+	driver.mu.Unlock()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("migration should be aborted when lease cannot be renewed")
+	}
+	if !errors.Is(context.Cause(ctx), ErrLockLost) {
+		t.Errorf("cause = %v, want ErrLockLost", context.Cause(ctx))
+	}
+}
+
+func TestDbfly_LockStatus(t *testing.T) {
+	ctx := context.Background()
+	driver := &leaseDriver{}
+	fly := newLeaseFly(driver, WithLockTTL(time.Minute))
+	fly.lockOwner = "pod-a:1:abcd"
+	if _, err := fly.locker.Lock(ctx, fly); err != nil {
+		t.Fatal(err)
+	}
+	status, err := fly.LockStatus(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Locked || status.Owner != "pod-a:1:abcd" || status.Version != 1 || status.Since.IsZero() {
+		t.Errorf("LockStatus() = %+v", status)
+	}
+
+	if err = fly.ForceReleaseLock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if status, err = fly.LockStatus(ctx); err != nil || status.Locked || status.Version != 2 {
+		t.Errorf("LockStatus() after force release = %+v, %v", status, err)
+	}
+
+	fly = NewDbfly(NewPostgresMigratory(), &recordDriver{}, nil, WithLocker(NewPostgresLocker()))
+	if _, err = fly.LockStatus(ctx); !errors.Is(err, ErrLockAdminUnsupported) {
+		t.Errorf("LockStatus() error = %v, want ErrLockAdminUnsupported", err)
+	}
+}
+
+func TestDbfly_LockOwner(t *testing.T) {
+	a, b := NewDbfly(NewMysqlMigratory(), &recordDriver{}, nil), NewDbfly(NewMysqlMigratory(), &recordDriver{}, nil)
+	if a.LockOwner() == b.LockOwner() || strings.Count(a.LockOwner(), ":") < 2 {
+		t.Errorf("default owners %q and %q should be distinct host:pid:instance", a.LockOwner(), b.LockOwner())
+	}
+	if owner := NewDbfly(NewMysqlMigratory(), &recordDriver{}, nil, WithLockOwner("job-42")).LockOwner(); owner != "job-42" {
+		t.Errorf("LockOwner() = %q, want job-42", owner)
+	}
+}
+
+func TestLockTime_Scan(t *testing.T) {
+	want := time.Date(2024, 5, 1, 8, 30, 0, 0, time.Local)
+	for _, value := range []any{want, "2024-05-01 08:30:00", []byte("2024-05-01 08:30:00")} {
+		var got lockTime
+		if err := got.Scan(value); err != nil || !got.Time.Equal(want) {
+			t.Errorf("Scan(%v) = %v, %v", value, got.Time, err)
+		}
+	}
+}
+
+func TestLockWaiter_Backoff(t *testing.T) {
+	w := newLockWaiter(NewDbfly(NewMysqlMigratory(), &recordDriver{}, nil), 100*time.Millisecond, time.Second, time.Minute, 0)
+	wants := []time.Duration{100, 200, 400, 800, 1000, 1000}
+	for i, want := range wants {
+		w.attempt = i + 1
+		want *= time.Millisecond
+		if got := w.backoff(); got < want/2 || got > want {
+			t.Errorf("attempt %d backoff() = %s, want in [%s, %s]", i+1, got, want/2, want)
+		}
+	}
+}
+
+func TestDbLocker_WaitTimeout(t *testing.T) {
+	driver := &leaseDriver{exists: true, locked: true, lockedBy: "pod-b:7:beef", lockTime: time.Now(), version: 3}
+	var waits []LockWait
+	metaData := &mockMetaData{dbms: "Mock", tables: leaseTables()}
+	migratory := NewDefaultMigratory("mock", metaData)
+	fly := NewDbfly(&migratory, driver, nil,
+		WithLocker(NewDbLocker(WithLockTimeout(80*time.Millisecond), WithLockRetryInterval(10*time.Millisecond), WithLockMaxRetryInterval(20*time.Millisecond))),
+		WithOnLockWait(func(wait LockWait) { waits = append(waits, wait) }))
+
+	_, err := fly.locker.Lock(context.Background(), fly)
+	var timeoutErr *LockTimeoutError
+	if !errors.Is(err, ErrLockTimeout) || !errors.As(err, &timeoutErr) || timeoutErr.Holder != "pod-b:7:beef" {
+		t.Fatalf("Lock() error = %v, want LockTimeoutError held by pod-b:7:beef", err)
+	}
+	if len(waits) == 0 || waits[0].Holder != "pod-b:7:beef" || waits[0].Attempt != 1 {
+		t.Errorf("OnLockWait calls = %+v", waits)
+	}
+
+	// 等待期间响应上下文取消
+	fly.locker = NewDbLocker(WithLockTimeout(time.Minute), WithLockRetryInterval(time.Second))
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err = fly.locker.Lock(ctx, fly); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Lock() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Lock() returned after %s, should stop waiting on cancellation", elapsed)
+	}
+}
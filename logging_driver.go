@@ -39,6 +39,25 @@ func (l *LoggingDriver) BeginTx(ctx context.Context) (Tx, error) {
 	return &LoggingTx{tx: tx, logger: l.logger, logSQLMode: l.logSQLMode}, nil
 }
 
+// Session 获取独占连接，连接上执行的SQL同样被记录
+func (l *LoggingDriver) Session(ctx context.Context) (Session, error) {
+	session, err := openSession(ctx, l.driver)
+	if err != nil {
+		return nil, err
+	}
+	return &loggingSession{LoggingDriver: NewLoggingDriver(session, l.logger, l.logSQLMode), session: session}, nil
+}
+
+// loggingSession 包装 Session，记录独占连接上的SQL
+type loggingSession struct {
+	*LoggingDriver
+	session Session
+}
+
+func (s *loggingSession) Close() error {
+	return s.session.Close()
+}
+
 func (l *LoggingDriver) logSQL(action, sql string, args []any) {
 	if l.logSQLMode&LogSQLTemplate != 0 {
 		l.logger.Debug("%s statement: %q", action, sql)
@@ -0,0 +1,142 @@
+package dbfly
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// changeLogs 按变更集维护的变更记录，每个变更集只保留一条记录，
+// 再次创建记录时替换原记录
+type changeLogs []ChangeLogEntry
+
+func (l changeLogs) executed() map[string]bool {
+	result := make(map[string]bool)
+	for _, entry := range l {
+		if entry.Success {
+			result[entry.ChangeSetId] = true
+		}
+	}
+	return result
+}
+
+// history 按执行顺序排列的副本
+func (l changeLogs) history() []ChangeLogEntry {
+	if len(l) == 0 {
+		return nil
+	}
+	entries := make([]ChangeLogEntry, len(l))
+	copy(entries, l)
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].OrderExecuted != entries[j].OrderExecuted {
+			return entries[i].OrderExecuted < entries[j].OrderExecuted
+		}
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+	return entries
+}
+
+// add 添加执行中的记录，先移除该变更集的原记录。事务提交失败时同一变更集会在
+// 已记为成功后再次创建记录，保留成功记录会使其仍被视为已执行
+func (l changeLogs) add(entry *ChangeLogEntry) changeLogs {
+	now := time.Now()
+	entry.Success, entry.ExecType, entry.CreatedAt, entry.UpdatedAt = false, ExecTypeRunning, now, now
+	result := l[:0]
+	for _, e := range l {
+		if e.ChangeSetId != entry.ChangeSetId {
+			result = append(result, e)
+		}
+	}
+	return append(result, *entry)
+}
+
+// update 更新变更集中成功状态为 success 的记录
+func (l changeLogs) update(entry *ChangeLogEntry, success bool) error {
+	entry.UpdatedAt = time.Now()
+	for i := range l {
+		if l[i].ChangeSetId == entry.ChangeSetId && l[i].Success == success {
+			l[i] = *entry
+			return nil
+		}
+	}
+	return New("change log of changeSet %s not found", entry.ChangeSetId)
+}
+
+func (l changeLogs) complete(entry *ChangeLogEntry) error {
+	entry.Success, entry.ExecType = true, ExecTypeExecuted
+	return l.update(entry, false)
+}
+
+func (l changeLogs) fail(entry *ChangeLogEntry) error {
+	return l.update(entry, false)
+}
+
+func (l changeLogs) rollback(entry *ChangeLogEntry) error {
+	entry.Success, entry.ExecType = false, ExecTypeRolledBack
+	return l.update(entry, true)
+}
+
+// MemoryRecorder 进程内的变更记录器，记录不持久化，用于测试
+type MemoryRecorder struct {
+	mu      sync.Mutex
+	entries changeLogs
+}
+
+func NewMemoryRecorder() *MemoryRecorder {
+	return &MemoryRecorder{}
+}
+
+func (r *MemoryRecorder) InitChangeLogTable(context.Context, *Dbfly) error {
+	return nil
+}
+
+func (r *MemoryRecorder) GetExecutedChangeSets(context.Context, *Dbfly) (map[string]bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.entries.executed(), nil
+}
+
+func (r *MemoryRecorder) History(context.Context, *Dbfly) ([]ChangeLogEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.entries.history(), nil
+}
+
+func (r *MemoryRecorder) NewChangeLog(_ context.Context, fly *Dbfly, entry *ChangeLogEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = r.entries.add(entry)
+	fly.logger.Debug("change log created, changeSetId: %q", entry.ChangeSetId)
+	return nil
+}
+
+func (r *MemoryRecorder) CompleteChangeLog(_ context.Context, fly *Dbfly, entry *ChangeLogEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.entries.complete(entry); err != nil {
+		return err
+	}
+	fly.logger.Debug("change log completed, changeSetId: %q", entry.ChangeSetId)
+	return nil
+}
+
+func (r *MemoryRecorder) FailChangeLog(_ context.Context, fly *Dbfly, entry *ChangeLogEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.entries.fail(entry); err != nil {
+		return err
+	}
+	fly.logger.Debug("change log failed, changeSetId: %q", entry.ChangeSetId)
+	return nil
+}
+
+func (r *MemoryRecorder) RollbackChangeLog(_ context.Context, fly *Dbfly, entry *ChangeLogEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.entries.rollback(entry); err != nil {
+		return err
+	}
+	fly.logger.Debug("change log rolled back, changeSetId: %q", entry.ChangeSetId)
+	return nil
+}
@@ -2,7 +2,9 @@ package dbfly
 
 import (
 	"context"
+	"strconv"
 	"strings"
+	"sync"
 )
 
 const (
@@ -30,10 +32,14 @@ type DatabaseMetaData interface {
 	GetTables(context.Context, Driver) ([]*Table, error)
 	// GetColumns 查找指定表的所有列
 	GetColumns(context.Context, Driver, string) ([]string, error)
+	// GetColumnDetails 查找指定表的所有列定义（类型、长度、可空、默认值、注释）
+	GetColumnDetails(context.Context, Driver, string) ([]*Column, error)
 	// GetIndexes 查找指定表的所有索引
 	GetIndexes(context.Context, Driver, string) ([]*Index, error)
 	// GetPrimaryKeys 查找指定表的主键
 	GetPrimaryKeys(context.Context, Driver, string) ([]*PrimaryKey, error)
+	// GetForeignKeys 查找指定表的外键
+	GetForeignKeys(context.Context, Driver, string) ([]*ForeignKey, error)
 	// ExistsTable 判断是否存在指定表，返回实际表名
 	ExistsTable(context.Context, Driver, string) (bool, string, error)
 	// ExistsColumn 判断指定表中是否存在指定列，返回实际表名和列名
@@ -44,16 +50,219 @@ type DatabaseMetaData interface {
 	ExistsPrimaryKey(context.Context, Driver, string) (bool, string, error)
 	// Quoter 使用引号包裹器
 	Quoter() *Quoter
+	// Version 查询数据库版本号，如 8.0.36
+	Version(context.Context, Driver) (string, error)
+	// Placeholder 常用Go驱动的绑定参数占位符风格
+	Placeholder() PlaceholderStyle
+}
+
+// ServerDetector 可选接口，连接数据库后探测服务端类型与版本，迁移开始前由 Dbfly 调用
+type ServerDetector interface {
+	DetectServer(context.Context, Driver) error
+}
+
+// VersionCache 可选接口，返回已查询过的数据库版本，供无法访问连接的方言选择使用
+type VersionCache interface {
+	CachedVersion() string
+}
+
+// detectServer 探测服务端类型并查询版本，查询结果由元数据缓存
+func detectServer(ctx context.Context, driver Driver, metaData DatabaseMetaData) error {
+	if detector, ok := metaData.(ServerDetector); ok {
+		if err := detector.DetectServer(ctx, driver); err != nil {
+			return err
+		}
+	}
+	_, err := metaData.Version(ctx, driver)
+	return err
+}
+
+// cachedVersion 已查询过的数据库版本，未查询时返回空字符串
+func cachedVersion(metaData DatabaseMetaData) string {
+	if cache, ok := metaData.(VersionCache); ok {
+		return cache.CachedVersion()
+	}
+	return ""
+}
+
+// versionHolder 查询并缓存数据库版本，元数据由迁移、锁续期和锁状态查询共享，缓存需加锁访问
+type versionHolder struct {
+	mu      sync.Mutex
+	version string
+}
+
+func (h *versionHolder) CachedVersion() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.version
+}
+
+// queryVersion 执行版本查询并缓存，parse 为空时截取开头的数字版本号；查询失败时不缓存，下次调用重新查询
+func (h *versionHolder) queryVersion(ctx context.Context, driver Driver, sql string, parse func(string) string) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.version != "" {
+		return h.version, nil
+	}
+	version, err := doGetScalar[string](ctx, driver, sql)
+	if err != nil {
+		return "", Wrap(err, "get database version failed")
+	}
+	if parse == nil {
+		parse = leadingVersion
+	}
+	h.version = parse(strings.TrimSpace(version))
+	return h.version, nil
+}
+
+// DbmsFallback 可选接口，方言匹配时 Dbms() 未命中后依次尝试的DBMS名称，如 TiDB 回退到 MySQL
+type DbmsFallback interface {
+	DbmsFallbacks() []string
+}
+
+// dbmsCandidates 方言匹配时依次尝试的DBMS名称
+func dbmsCandidates(metaData DatabaseMetaData) []string {
+	names := []string{metaData.Dbms()}
+	if fallback, ok := metaData.(DbmsFallback); ok {
+		names = append(names, fallback.DbmsFallbacks()...)
+	}
+	return names
+}
+
+// matchDbms 判断DBMS名称是否与当前数据库匹配，包括回退名称
+func matchDbms(metaData DatabaseMetaData, name string) bool {
+	for _, candidate := range dbmsCandidates(metaData) {
+		if candidate == name {
+			return true
+		}
+	}
+	return false
+}
+
+// selectDbms 按 Dbms() 优先、回退名称其次的顺序选择匹配的方言配置，
+// 设置了最低版本时要求已知的数据库版本不低于该版本，同一名称按声明顺序选择第一个满足的配置
+func selectDbms[T any](metaData DatabaseMetaData, items []T, dbms func(T) (string, string)) (T, bool) {
+	version := cachedVersion(metaData)
+	for _, candidate := range dbmsCandidates(metaData) {
+		for _, item := range items {
+			name, minVersion := dbms(item)
+			if name != candidate {
+				continue
+			}
+			if minVersion != "" && (version == "" || compareVersion(version, minVersion) < 0) {
+				continue
+			}
+			return item, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// selectColumnDbms 选择与当前数据库匹配的列方言，未匹配时返回nil
+func selectColumnDbms(metaData DatabaseMetaData, nodes []*ColumnDbmsNode) *ColumnDbmsNode {
+	node, _ := selectDbms(metaData, nodes, func(n *ColumnDbmsNode) (string, string) { return n.Dbms, n.MinVersion })
+	return node
+}
+
+// compareVersion 按数字段比较两个版本号，忽略非数字后缀
+func compareVersion(a, b string) int {
+	as, bs := versionParts(a), versionParts(b)
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionInRange 判断版本是否在 [min, max] 范围内，max 只比较其包含的段数，如 8.0.36 满足 max 8.0
+func versionInRange(version, min, max string) bool {
+	if min != "" && compareVersion(version, min) < 0 {
+		return false
+	}
+	if max != "" {
+		parts := versionParts(version)
+		if size := len(versionParts(max)); len(parts) > size {
+			parts = parts[:size]
+		}
+		strs := make([]string, len(parts))
+		for i, part := range parts {
+			strs[i] = strconv.Itoa(part)
+		}
+		if compareVersion(strings.Join(strs, "."), max) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// leadingVersion 截取开头由数字和点组成的版本号
+func leadingVersion(str string) string {
+	end := 0
+	for end < len(str) && (str[end] == '.' || (str[end] >= '0' && str[end] <= '9')) {
+		end++
+	}
+	return strings.TrimRight(str[:end], ".")
+}
+
+func versionParts(version string) []int {
+	var parts []int
+	for _, field := range strings.Split(version, ".") {
+		end := 0
+		for end < len(field) && field[end] >= '0' && field[end] <= '9' {
+			end++
+		}
+		if end == 0 {
+			break
+		}
+		n, _ := strconv.Atoi(field[:end])
+		parts = append(parts, n)
+		if end < len(field) {
+			break
+		}
+	}
+	return parts
 }
 
 type Table struct {
 	Name      string
 	TableType string
+	Comment   string
+}
+
+// Column 列定义信息
+type Column struct {
+	Name string
+	// DataType 数据库原生类型名称，不包含长度
+	DataType         string
+	MaxLength        int
+	NumericPrecision int
+	NumericScale     int
+	Nullable         bool
+	// DefaultValue 数据库中保存的默认值表达式
+	DefaultValue string
+	Comment      string
+	// Ordinal 列在表中的位置，从1开始
+	Ordinal int
 }
 
 type Index struct {
 	Name       string
 	ColumnName string
+	Unique     bool
+	// Ordinal 列在索引中的位置，从1开始
+	Ordinal int
 }
 
 type PrimaryKey struct {
@@ -61,6 +270,15 @@ type PrimaryKey struct {
 	ColumnName string
 }
 
+type ForeignKey struct {
+	Name          string
+	ColumnName    string
+	RefTableName  string
+	RefColumnName string
+	// Ordinal 列在外键中的位置，从1开始
+	Ordinal int
+}
+
 type TableGetter func(context.Context, Driver) ([]*Table, error)
 type ColumnGetter func(context.Context, Driver, string) ([]string, error)
 type IndexGetter func(context.Context, Driver, string) ([]*Index, error)
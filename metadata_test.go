@@ -2,6 +2,9 @@ package dbfly
 
 import (
 	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
 	"testing"
 )
 
@@ -398,3 +401,91 @@ func TestExistsPrimaryKey(t *testing.T) {
 		})
 	}
 }
+
+func TestVersionInRange(t *testing.T) {
+	tests := []struct {
+		version, min, max string
+		want              bool
+	}{
+		{"8.0.36", "8.0", "", true},
+		{"5.7.44", "8.0", "", false},
+		{"8.0.36", "", "8.0", true},
+		{"8.4.0", "", "8.0", false},
+		{"12.2.0.1.0", "12.1", "19", true},
+		{"11.2.0.4.0", "12.1", "19", false},
+	}
+	for _, tt := range tests {
+		if got := versionInRange(tt.version, tt.min, tt.max); got != tt.want {
+			t.Errorf("versionInRange(%q, %q, %q) = %v, want %v", tt.version, tt.min, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestSelectDbms_MinVersion(t *testing.T) {
+	nodes := []*SqlDbmsNode{
+		{Dbms: "Mock", MinVersion: "8.0", Content: "RENAME COLUMN"},
+		{Dbms: "Mock", Content: "CHANGE COLUMN"},
+	}
+	key := func(n *SqlDbmsNode) (string, string) { return n.Dbms, n.MinVersion }
+	tests := []struct {
+		version, want string
+	}{
+		{"8.0.36", "RENAME COLUMN"},
+		{"5.7.44", "CHANGE COLUMN"},
+		{"", "CHANGE COLUMN"},
+	}
+	for _, tt := range tests {
+		metaData := &mockMetaData{dbms: "Mock", version: tt.version}
+		node, ok := selectDbms(metaData, nodes, key)
+		if !ok || node.Content != tt.want {
+			t.Errorf("version %q selectDbms() = %v, want %s", tt.version, node, tt.want)
+		}
+	}
+}
+
+func TestParseKingbaseVersion(t *testing.T) {
+	got := parseKingbaseVersion("KingbaseES V008R006C008B0014 on x86_64-pc-linux-gnu, compiled by gcc")
+	if got != "8.6.8" {
+		t.Errorf("parseKingbaseVersion() = %s, want 8.6.8", got)
+	}
+}
+
+// versionDriver 返回固定版本并统计查询次数
+type versionDriver struct {
+	version string
+	queries atomic.Int32
+}
+
+func (d *versionDriver) Execute(context.Context, string, ...interface{}) (sql.Result, error) {
+	return nil, nil
+}
+
+func (d *versionDriver) Query(context.Context, string, ...interface{}) (Rows, error) {
+	d.queries.Add(1)
+	return &valueRows{values: [][]interface{}{{d.version}}}, nil
+}
+
+func (d *versionDriver) BeginTx(context.Context) (Tx, error) {
+	return nil, New("transaction is not supported")
+}
+
+func TestVersionHolder_Concurrent(t *testing.T) {
+	for _, metaData := range []DatabaseMetaData{NewPostgresDatabaseMetaData(), NewMysqlDatabaseMetaData()} {
+		driver := &versionDriver{version: "8.0.36"}
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if version, err := metaData.Version(context.Background(), driver); err != nil || version != "8.0.36" {
+					t.Errorf("%s Version() = %q, %v", metaData.Dbms(), version, err)
+				}
+				_ = cachedVersion(metaData)
+			}()
+		}
+		wg.Wait()
+		if n := driver.queries.Load(); n != 1 {
+			t.Errorf("%s version queried %d times, want 1", metaData.Dbms(), n)
+		}
+	}
+}
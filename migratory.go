@@ -40,6 +40,10 @@ type Migratory interface {
 	SplitSQLStatements(script string) []string
 	// MetaData 元数据信息
 	MetaData() DatabaseMetaData
+	// SupportsTransactionalDDL 是否支持在事务中执行 DDL，changeSet 的 runInTransaction 为 auto 时据此决定是否使用事务
+	SupportsTransactionalDDL() bool
+	// SavepointSQL 创建保存点及回滚到保存点的语句，不支持保存点时返回 ErrSavepointUnsupported
+	SavepointSQL(name string) (savepoint string, rollback string, err error)
 }
 
 type DefaultMigratory struct {
@@ -74,6 +78,16 @@ func (m *DefaultMigratory) Name() string {
 	return m.name
 }
 
+// SupportsTransactionalDDL 默认不支持，DDL 可能隐式提交事务
+func (m *DefaultMigratory) SupportsTransactionalDDL() bool {
+	return false
+}
+
+// SavepointSQL 默认使用标准 SQL 的 SAVEPOINT 与 ROLLBACK TO SAVEPOINT
+func (m *DefaultMigratory) SavepointSQL(name string) (string, string, error) {
+	return "SAVEPOINT " + name, "ROLLBACK TO SAVEPOINT " + name, nil
+}
+
 func (m *DefaultMigratory) Quote(str string) string {
 	return m.metaData.Quoter().MustQuote(str)
 }
@@ -141,15 +155,8 @@ func (m *DefaultMigratory) CreateTable(ctx context.Context, driver Driver, table
 }
 
 func (m *DefaultMigratory) CreateTableColumn(node *ColumnNode, builder *strings.Builder) bool {
-	var dbmsNode *ColumnDbmsNode
-
 	// 查找方言
-	for _, dbms := range node.ColumnDbms {
-		if dbms.Dbms == m.MetaData().Dbms() {
-			dbmsNode = dbms
-			break
-		}
-	}
+	dbmsNode := selectColumnDbms(m.MetaData(), node.ColumnDbms)
 	m.QuoteTo(builder, node.ColumnName)
 	builder.WriteString(" ")
 	var defaultValue string
@@ -266,15 +273,8 @@ func (m *DefaultMigratory) AddColumn(ctx context.Context, driver Driver, tableNa
 }
 
 func (m *DefaultMigratory) CreateAddTableColumn(node *AddColumnColumnNode, builder *strings.Builder) {
-	var dbmsNode *ColumnDbmsNode
-
 	// 查找方言
-	for _, dbms := range node.ColumnDbms {
-		if dbms.Dbms == m.MetaData().Dbms() {
-			dbmsNode = dbms
-			break
-		}
-	}
+	dbmsNode := selectColumnDbms(m.MetaData(), node.ColumnDbms)
 	m.QuoteTo(builder, node.ColumnName)
 	builder.WriteString(" ")
 	var defaultValue string
@@ -330,15 +330,8 @@ func (m *DefaultMigratory) AlterColumn(ctx context.Context, driver Driver, table
 }
 
 func (m *DefaultMigratory) CreateAlterTableColumn(node *AlterColumnColumnNode, builder *strings.Builder, columnName string) {
-	var dbmsNode *ColumnDbmsNode
-
 	// 查找方言
-	for _, dbms := range node.ColumnDbms {
-		if dbms.Dbms == m.MetaData().Dbms() {
-			dbmsNode = dbms
-			break
-		}
-	}
+	dbmsNode := selectColumnDbms(m.MetaData(), node.ColumnDbms)
 	m.QuoteTo(builder, columnName)
 	builder.WriteString(" ")
 	var defaultValue string
@@ -0,0 +1,93 @@
+package dbfly
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MutexLocker 进程内的锁实现，用于测试或协调同一进程中的多个 Dbfly 实例，
+// 需要互斥的实例共享同一个 MutexLocker
+type MutexLocker struct {
+	mu      sync.Mutex
+	status  LockStatus
+	release chan struct{} // 锁释放时关闭，唤醒等待者
+	timeout time.Duration
+}
+
+type MutexLockerOption func(*MutexLocker)
+
+func WithMutexLockTimeout(d time.Duration) MutexLockerOption {
+	return func(l *MutexLocker) {
+		if d > 0 {
+			l.timeout = d
+		}
+	}
+}
+
+func NewMutexLocker(opts ...MutexLockerOption) *MutexLocker {
+	l := &MutexLocker{timeout: defaultLockTimeout}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+func (l *MutexLocker) Lock(ctx context.Context, fly *Dbfly) (Unlock, error) {
+	start := time.Now()
+	timer := time.NewTimer(l.timeout)
+	defer timer.Stop()
+	for attempt := 1; ; attempt++ {
+		l.mu.Lock()
+		if !l.status.Locked {
+			l.status = LockStatus{Locked: true, Owner: fly.LockOwner(), Since: time.Now(), Version: l.status.Version + 1}
+			l.release = make(chan struct{})
+			version := l.status.Version
+			l.mu.Unlock()
+			return func(context.Context, *Dbfly) error {
+				l.unlock(version)
+				return nil
+			}, nil
+		}
+		release, holder := l.release, l.status.Owner
+		l.mu.Unlock()
+
+		waited := time.Since(start)
+		fly.logger.Warn("lock held by %q, attempt: %d", holder, attempt)
+		if fly.onLockWait != nil {
+			fly.onLockWait(LockWait{Attempt: attempt, Holder: holder, Waited: waited, Next: l.timeout - waited})
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+			return nil, &LockTimeoutError{Holder: holder, Waited: time.Since(start), Attempts: attempt}
+		case <-release:
+		}
+	}
+}
+
+// unlock 释放指定版本的锁，强制释放后原持有者的释放不影响新的持有者
+func (l *MutexLocker) unlock(version int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.status.Locked && l.status.Version == version {
+		l.status.Locked = false
+		close(l.release)
+	}
+}
+
+func (l *MutexLocker) Status(context.Context, *Dbfly) (*LockStatus, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	status := l.status
+	return &status, nil
+}
+
+func (l *MutexLocker) ForceRelease(context.Context, *Dbfly) error {
+	l.mu.Lock()
+	version := l.status.Version
+	l.mu.Unlock()
+	l.unlock(version)
+	return nil
+}
@@ -0,0 +1,64 @@
+package dbfly
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMutexLocker(t *testing.T) {
+	ctx := context.Background()
+	locker := NewMutexLocker(WithMutexLockTimeout(50 * time.Millisecond))
+	a := NewDbfly(NewMysqlMigratory(), &recordDriver{}, nil, WithLocker(locker), WithLockOwner("a"))
+	b := NewDbfly(NewMysqlMigratory(), &recordDriver{}, nil, WithLocker(locker), WithLockOwner("b"))
+
+	unlock, err := locker.Lock(ctx, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := b.LockStatus(ctx); !status.Locked || status.Owner != "a" || status.Version != 1 {
+		t.Errorf("LockStatus() = %+v", status)
+	}
+	var timeoutErr *LockTimeoutError
+	if _, err = locker.Lock(ctx, b); !errors.As(err, &timeoutErr) || timeoutErr.Holder != "a" {
+		t.Fatalf("Lock() error = %v, want LockTimeoutError held by a", err)
+	}
+
+	// 释放后唤醒等待者
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		time.Sleep(10 * time.Millisecond)
+		_ = unlock(ctx, a)
+	}()
+	unlockB, err := locker.Lock(ctx, b)
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 强制释放后，原持有者的释放不影响新的持有者
+	if err = b.ForceReleaseLock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	unlockA, err := locker.Lock(ctx, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = unlockB(ctx, b)
+	if status, _ := a.LockStatus(ctx); !status.Locked || status.Owner != "a" {
+		t.Errorf("stale unlock released the new holder: %+v", status)
+	}
+	_ = unlockA(ctx, a)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	unlock, _ = locker.Lock(ctx, a)
+	if _, err = locker.Lock(cancelled, b); !errors.Is(err, context.Canceled) {
+		t.Errorf("Lock() error = %v, want context.Canceled", err)
+	}
+	_ = unlock(ctx, a)
+}
@@ -6,21 +6,145 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
+)
+
+// MySQL 协议兼容的服务端类型，同时作为方言匹配使用的DBMS名称
+const (
+	MysqlFlavorMysql     = "MySQL"
+	MysqlFlavorMariaDB   = "MariaDB"
+	MysqlFlavorTiDB      = "TiDB"
+	MysqlFlavorOceanBase = "OceanBase"
 )
 
 type MysqlDatabaseMetaData struct {
-	quoter *Quoter
-	schema string
+	quoter   *Quoter
+	schema   string
+	mu       sync.Mutex
+	flavor   string
+	version  string
+	detected bool
+}
+
+type MysqlOption func(*MysqlDatabaseMetaData)
+
+// WithMysqlServer 指定服务端类型和版本，指定后不再自动探测
+func WithMysqlServer(flavor, version string) MysqlOption {
+	return func(m *MysqlDatabaseMetaData) {
+		if flavor != "" {
+			m.flavor = flavor
+			m.version = version
+			m.detected = true
+		}
+	}
 }
 
-func NewMysqlDatabaseMetaData() *MysqlDatabaseMetaData {
-	return &MysqlDatabaseMetaData{
+func NewMysqlDatabaseMetaData(opts ...MysqlOption) *MysqlDatabaseMetaData {
+	m := &MysqlDatabaseMetaData{
 		quoter: NewQuoter('`', '`', AlwaysReserve),
+		flavor: MysqlFlavorMysql,
+	}
+	for _, opt := range opts {
+		opt(m)
 	}
+	return m
 }
 
+// Dbms 返回探测到的服务端类型，MariaDB、TiDB、OceanBase 未匹配方言时回退到 MySQL
 func (m *MysqlDatabaseMetaData) Dbms() string {
-	return "MySQL"
+	flavor, _ := m.server()
+	return flavor
+}
+
+func (m *MysqlDatabaseMetaData) Placeholder() PlaceholderStyle {
+	return PlaceholderQuestion
+}
+
+func (m *MysqlDatabaseMetaData) DbmsFallbacks() []string {
+	if flavor, _ := m.server(); flavor == MysqlFlavorMysql {
+		return nil
+	}
+	return []string{MysqlFlavorMysql}
+}
+
+// Flavor 服务端类型
+func (m *MysqlDatabaseMetaData) Flavor() string {
+	flavor, _ := m.server()
+	return flavor
+}
+
+// ServerVersion 服务端版本，TiDB 与 OceanBase 为产品自身版本而非兼容的 MySQL 版本
+func (m *MysqlDatabaseMetaData) ServerVersion() string {
+	_, version := m.server()
+	return version
+}
+
+// server 读取服务端类型和版本，元数据由迁移、锁续期和锁状态查询共享，需加锁访问
+func (m *MysqlDatabaseMetaData) server() (string, string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.flavor, m.version
+}
+
+// DetectServer 通过 VERSION() 探测服务端类型和版本，只探测一次
+func (m *MysqlDatabaseMetaData) DetectServer(ctx context.Context, driver Driver) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.detected {
+		return nil
+	}
+	version, err := doGetScalar[string](ctx, driver, "SELECT VERSION()")
+	if err != nil {
+		return Wrap(err, "detect server version failed")
+	}
+	m.flavor, m.version = parseMysqlServerVersion(version)
+	m.detected = true
+	return nil
+}
+
+// parseMysqlServerVersion 解析 VERSION() 返回值，如 10.6.12-MariaDB-log、8.0.11-TiDB-v7.5.0、5.7.25-OceanBase_CE-v4.2.1.0
+func parseMysqlServerVersion(str string) (string, string) {
+	lower := strings.ToLower(str)
+	for _, item := range []struct{ flavor, marker string }{
+		{MysqlFlavorTiDB, "-tidb-"},
+		{MysqlFlavorOceanBase, "-oceanbase"},
+	} {
+		if index := strings.Index(lower, item.marker); index >= 0 {
+			rest := str[index+len(item.marker):]
+			if i := strings.Index(strings.ToLower(rest), "v"); i >= 0 {
+				rest = rest[i+1:]
+			}
+			return item.flavor, leadingVersion(rest)
+		}
+	}
+	if strings.Contains(lower, "mariadb") {
+		// 旧版复制协议会在版本前添加 5.5.5- 前缀
+		return MysqlFlavorMariaDB, leadingVersion(strings.TrimPrefix(str, "5.5.5-"))
+	}
+	return MysqlFlavorMysql, leadingVersion(str)
+}
+
+// isMysqlFlavor 判断DBMS名称是否属于 MySQL 协议兼容的服务端
+func isMysqlFlavor(dbms string) bool {
+	switch dbms {
+	case MysqlFlavorMysql, MysqlFlavorMariaDB, MysqlFlavorTiDB, MysqlFlavorOceanBase:
+		return true
+	}
+	return false
+}
+
+// supportsRenameColumn 是否支持 RENAME COLUMN 语法，MySQL 8.0、MariaDB 10.5.2 起支持
+func (m *MysqlDatabaseMetaData) supportsRenameColumn() bool {
+	flavor, version := m.server()
+	switch flavor {
+	case MysqlFlavorMysql:
+		return compareVersion(version, "8.0") >= 0
+	case MysqlFlavorMariaDB:
+		return compareVersion(version, "10.5.2") >= 0
+	case MysqlFlavorTiDB:
+		return true
+	}
+	return false
 }
 
 func (m *MysqlDatabaseMetaData) getSchema(ctx context.Context, driver Driver) (string, error) {
@@ -82,12 +206,21 @@ func (m *MysqlDatabaseMetaData) GetTables(ctx context.Context, driver Driver) ([
        CASE TABLE_TYPE
            WHEN 'BASE TABLE' THEN 'TABLE'
            ELSE TABLE_TYPE END
-                     AS TABLE_TYPE
+                     AS TABLE_TYPE,
+       CASE TABLE_TYPE
+           WHEN 'VIEW' THEN ''
+           ELSE TABLE_COMMENT END
+                     AS TABLE_COMMENT
 FROM INFORMATION_SCHEMA.TABLES
 WHERE TABLE_SCHEMA = ? AND TABLE_TYPE in ('BASE TABLE', 'VIEW')
 ORDER BY TABLE_NAME`
 	return doGetSlices[Table](ctx, driver, func(rows Rows, t *Table) error {
-		return rows.Scan(&t.Name, &t.TableType)
+		var comment sql2.NullString
+		if err := rows.Scan(&t.Name, &t.TableType, &comment); err != nil {
+			return err
+		}
+		t.Comment = comment.String
+		return nil
 	}, sql, schema)
 }
 
@@ -101,6 +234,46 @@ func (m *MysqlDatabaseMetaData) GetColumns(ctx context.Context, driver Driver, t
 	return doGetScalars[string](ctx, driver, sql, schema, tableName)
 }
 
+func (m *MysqlDatabaseMetaData) GetColumnDetails(ctx context.Context, driver Driver, tableName string) ([]*Column, error) {
+	schema, err := m.getSchema(ctx, driver)
+	if err != nil {
+		return nil, err
+	}
+	sql := `SELECT COLUMN_NAME,
+       DATA_TYPE,
+       CHARACTER_MAXIMUM_LENGTH,
+       NUMERIC_PRECISION,
+       NUMERIC_SCALE,
+       IS_NULLABLE,
+       COLUMN_DEFAULT,
+       COLUMN_COMMENT,
+       ORDINAL_POSITION
+FROM INFORMATION_SCHEMA.COLUMNS
+WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+ORDER BY ORDINAL_POSITION`
+	return doGetSlices[Column](ctx, driver, func(rows Rows, t *Column) error {
+		var (
+			maxLength    sql2.NullInt64
+			precision    sql2.NullInt64
+			scale        sql2.NullInt64
+			nullable     string
+			defaultValue sql2.NullString
+			comment      sql2.NullString
+		)
+		if err := rows.Scan(&t.Name, &t.DataType, &maxLength, &precision, &scale, &nullable, &defaultValue, &comment, &t.Ordinal); err != nil {
+			return err
+		}
+		t.DataType = strings.ToUpper(t.DataType)
+		t.MaxLength = int(maxLength.Int64)
+		t.NumericPrecision = int(precision.Int64)
+		t.NumericScale = int(scale.Int64)
+		t.Nullable = strings.ToUpper(nullable) == "YES"
+		t.DefaultValue = defaultValue.String
+		t.Comment = comment.String
+		return nil
+	}, sql, schema, tableName)
+}
+
 func (m *MysqlDatabaseMetaData) GetIndexes(ctx context.Context, driver Driver, tableName string) ([]*Index, error) {
 	schema, err := m.getSchema(ctx, driver)
 	if err != nil {
@@ -111,10 +284,14 @@ func (m *MysqlDatabaseMetaData) GetIndexes(ctx context.Context, driver Driver, t
 	var (
 		keyName    sql2.NullString
 		columnName sql2.NullString
+		nonUnique  sql2.NullInt64
+		seqInIndex sql2.NullInt64
 	)
 	binders := columnBinders{
-		"KEY_NAME":    &keyName,
-		"COLUMN_NAME": &columnName,
+		"KEY_NAME":     &keyName,
+		"COLUMN_NAME":  &columnName,
+		"NON_UNIQUE":   &nonUnique,
+		"SEQ_IN_INDEX": &seqInIndex,
 	}
 	return doGetSlices[Index](ctx, driver, func(rows Rows, t *Index) error {
 		var err error
@@ -128,6 +305,8 @@ func (m *MysqlDatabaseMetaData) GetIndexes(ctx context.Context, driver Driver, t
 		}
 		t.Name = keyName.String
 		t.ColumnName = columnName.String
+		t.Unique = nonUnique.Int64 == 0
+		t.Ordinal = int(seqInIndex.Int64)
 		return nil
 	}, sql)
 }
@@ -171,6 +350,26 @@ func (m *MysqlDatabaseMetaData) GetPrimaryKeys(ctx context.Context, driver Drive
 	return list, err
 }
 
+func (m *MysqlDatabaseMetaData) GetForeignKeys(ctx context.Context, driver Driver, tableName string) ([]*ForeignKey, error) {
+	schema, err := m.getSchema(ctx, driver)
+	if err != nil {
+		return nil, err
+	}
+	sql := `SELECT CONSTRAINT_NAME,
+       COLUMN_NAME,
+       REFERENCED_TABLE_NAME,
+       REFERENCED_COLUMN_NAME,
+       ORDINAL_POSITION
+FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE
+WHERE TABLE_SCHEMA = ?
+  AND TABLE_NAME = ?
+  AND REFERENCED_TABLE_NAME IS NOT NULL
+ORDER BY CONSTRAINT_NAME, ORDINAL_POSITION`
+	return doGetSlices[ForeignKey](ctx, driver, func(rows Rows, t *ForeignKey) error {
+		return rows.Scan(&t.Name, &t.ColumnName, &t.RefTableName, &t.RefColumnName, &t.Ordinal)
+	}, sql, schema, tableName)
+}
+
 func (m *MysqlDatabaseMetaData) ExistsTable(ctx context.Context, driver Driver, tableName string) (bool, string, error) {
 	return ExistsTable(m.GetTables, ctx, driver, tableName)
 }
@@ -187,6 +386,20 @@ func (m *MysqlDatabaseMetaData) ExistsPrimaryKey(ctx context.Context, driver Dri
 	return ExistsPrimaryKey(m.GetTables, m.GetPrimaryKeys, ctx, driver, tableName)
 }
 
+// Version 返回探测到的服务端版本
+func (m *MysqlDatabaseMetaData) Version(ctx context.Context, driver Driver) (string, error) {
+	if err := m.DetectServer(ctx, driver); err != nil {
+		return "", err
+	}
+	_, version := m.server()
+	return version, nil
+}
+
+func (m *MysqlDatabaseMetaData) CachedVersion() string {
+	_, version := m.server()
+	return version
+}
+
 func (m *MysqlDatabaseMetaData) Quoter() *Quoter {
 	return m.quoter
 }
@@ -197,12 +410,16 @@ type MysqlMigratory struct {
 }
 
 // NewMysqlMigratory 创建一个Mysql迁移实现实例
-func NewMysqlMigratory() Migratory {
+func NewMysqlMigratory(opts ...MysqlOption) Migratory {
 	return &MysqlMigratory{
-		DefaultMigratory: NewDefaultMigratory("mysql", NewMysqlDatabaseMetaData()),
+		DefaultMigratory: NewDefaultMigratory("mysql", NewMysqlDatabaseMetaData(opts...)),
 	}
 }
 
+func (m *MysqlMigratory) mysqlMetaData() *MysqlDatabaseMetaData {
+	return m.MetaData().(*MysqlDatabaseMetaData)
+}
+
 func (m *MysqlMigratory) CreateTable(ctx context.Context, driver Driver, tableName string, comment string, columns []*ColumnNode, attributes *AttributesNode) error {
 	var builder strings.Builder
 	builder.WriteString("CREATE TABLE ")
@@ -235,7 +452,7 @@ func (m *MysqlMigratory) CreateTable(ctx context.Context, driver Driver, tableNa
 	builder.WriteString("\n)")
 	if attributes != nil && len(attributes.Attributes) > 0 {
 		for _, attr := range attributes.Attributes {
-			if attr.Dbms != m.MetaData().Dbms() {
+			if !matchDbms(m.MetaData(), attr.Dbms) {
 				continue
 			}
 			builder.WriteString(" ")
@@ -271,6 +488,13 @@ func (m *MysqlMigratory) DropIndex(ctx context.Context, driver Driver, tableName
 
 func (m *MysqlMigratory) AddColumn(ctx context.Context, driver Driver, tableName string, columns []*AddColumnColumnNode, _ *AttributesNode) error {
 	for _, column := range columns {
+		// TiDB 不支持在 ADD COLUMN 中定义唯一约束，拆分为单独的 ADD UNIQUE
+		unique := column.Unique && m.mysqlMetaData().Flavor() == MysqlFlavorTiDB
+		if unique {
+			copied := *column
+			copied.Unique = false
+			column = &copied
+		}
 		var builder strings.Builder
 		builder.WriteString("ALTER TABLE ")
 		m.QuoteTo(&builder, tableName)
@@ -279,10 +503,20 @@ func (m *MysqlMigratory) AddColumn(ctx context.Context, driver Driver, tableName
 		if _, err := driver.Execute(ctx, builder.String()); err != nil {
 			return err
 		}
+		if unique {
+			if err := m.addUnique(ctx, driver, tableName, column.ColumnName); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
 
+func (m *MysqlMigratory) addUnique(ctx context.Context, driver Driver, tableName, columnName string) error {
+	_, err := driver.Execute(ctx, fmt.Sprintf("ALTER TABLE %s ADD UNIQUE (%s)", m.Quote(tableName), m.Quote(columnName)))
+	return err
+}
+
 func (m *MysqlMigratory) createAddTableColumn(node *AddColumnColumnNode, builder *strings.Builder) {
 	m.DefaultMigratory.CreateAddTableColumn(node, builder)
 
@@ -294,13 +528,25 @@ func (m *MysqlMigratory) createAddTableColumn(node *AddColumnColumnNode, builder
 }
 
 func (m *MysqlMigratory) AlterColumn(ctx context.Context, driver Driver, tableName string, columnName string, column *AlterColumnColumnNode, _ *AttributesNode) error {
+	// TiDB 不支持在 MODIFY 中定义唯一约束，拆分为单独的 ADD UNIQUE
+	unique := column.Unique && m.mysqlMetaData().Flavor() == MysqlFlavorTiDB
+	if unique {
+		copied := *column
+		copied.Unique = false
+		column = &copied
+	}
 	var builder strings.Builder
 	builder.WriteString("ALTER TABLE ")
 	m.QuoteTo(&builder, tableName)
 	builder.WriteString(" MODIFY ")
 	m.createAlterTableColumn(column, &builder, columnName)
-	_, err := driver.Execute(ctx, builder.String())
-	return err
+	if _, err := driver.Execute(ctx, builder.String()); err != nil {
+		return err
+	}
+	if unique {
+		return m.addUnique(ctx, driver, tableName, columnName)
+	}
+	return nil
 }
 
 func (m *MysqlMigratory) createAlterTableColumn(node *AlterColumnColumnNode, builder *strings.Builder, columnName string) {
@@ -313,6 +559,39 @@ func (m *MysqlMigratory) createAlterTableColumn(node *AlterColumnColumnNode, bui
 	}
 }
 
+// RenameColumn 不支持 RENAME COLUMN 的版本从 SHOW CREATE TABLE 中取出原列定义，使用 CHANGE 重命名
+func (m *MysqlMigratory) RenameColumn(ctx context.Context, driver Driver, tableName string, columnName string, newColumnName string, attributes *AttributesNode) error {
+	if m.mysqlMetaData().supportsRenameColumn() {
+		return m.DefaultMigratory.RenameColumn(ctx, driver, tableName, columnName, newColumnName, attributes)
+	}
+	var createTable string
+	err := doEach(ctx, driver, func(rows Rows) error {
+		var name string
+		return rows.Scan(&name, &createTable)
+	}, "SHOW CREATE TABLE "+m.Quote(tableName))
+	if err != nil {
+		return err
+	}
+	definition, ok := mysqlColumnDefinition(createTable, columnName)
+	if !ok {
+		return New("column %s of table %s not found", columnName, tableName)
+	}
+	_, err = driver.Execute(ctx, fmt.Sprintf("ALTER TABLE %s CHANGE %s %s %s", m.Quote(tableName), m.Quote(columnName), m.Quote(newColumnName), definition))
+	return err
+}
+
+// mysqlColumnDefinition 从建表语句中截取指定列名之后的列定义
+func mysqlColumnDefinition(createTable, columnName string) (string, bool) {
+	prefix := "`" + strings.ReplaceAll(columnName, "`", "``") + "` "
+	for _, line := range strings.Split(createTable, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) > len(prefix) && strings.EqualFold(line[:len(prefix)], prefix) {
+			return strings.TrimSuffix(line[len(prefix):], ","), true
+		}
+	}
+	return "", false
+}
+
 func (m *MysqlMigratory) RenameTable(ctx context.Context, driver Driver, tableName string, newTableName string, _ *AttributesNode) error {
 	_, err := driver.Execute(ctx, fmt.Sprintf("RENAME TABLE %s TO %s", m.Quote(tableName), m.Quote(newTableName)))
 	return err
@@ -0,0 +1,99 @@
+package dbfly
+
+import "testing"
+
+func TestParseMysqlServerVersion(t *testing.T) {
+	tests := []struct {
+		version, flavor, want string
+	}{
+		{"8.0.36", MysqlFlavorMysql, "8.0.36"},
+		{"5.7.44-log", MysqlFlavorMysql, "5.7.44"},
+		{"10.6.12-MariaDB-log", MysqlFlavorMariaDB, "10.6.12"},
+		{"5.5.5-10.4.32-MariaDB", MysqlFlavorMariaDB, "10.4.32"},
+		{"8.0.11-TiDB-v7.5.0", MysqlFlavorTiDB, "7.5.0"},
+		{"5.7.25-OceanBase_CE-v4.2.1.0", MysqlFlavorOceanBase, "4.2.1.0"},
+		{"5.7.25-OceanBase-v3.2.4", MysqlFlavorOceanBase, "3.2.4"},
+	}
+	for _, tt := range tests {
+		flavor, version := parseMysqlServerVersion(tt.version)
+		if flavor != tt.flavor || version != tt.want {
+			t.Errorf("parseMysqlServerVersion(%q) = %s %s, want %s %s", tt.version, flavor, version, tt.flavor, tt.want)
+		}
+	}
+}
+
+func TestMysqlDatabaseMetaData_supportsRenameColumn(t *testing.T) {
+	tests := []struct {
+		flavor, version string
+		want            bool
+	}{
+		{MysqlFlavorMysql, "5.7.44", false},
+		{MysqlFlavorMysql, "8.0.36", true},
+		{MysqlFlavorMariaDB, "10.4.32", false},
+		{MysqlFlavorMariaDB, "10.5.2", true},
+		{MysqlFlavorTiDB, "5.4.0", true},
+		{MysqlFlavorOceanBase, "4.2.1.0", false},
+	}
+	for _, tt := range tests {
+		metaData := NewMysqlDatabaseMetaData(WithMysqlServer(tt.flavor, tt.version))
+		if got := metaData.supportsRenameColumn(); got != tt.want {
+			t.Errorf("%s %s supportsRenameColumn() = %v, want %v", tt.flavor, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestSelectColumnDbms_Fallback(t *testing.T) {
+	nodes := []*ColumnDbmsNode{
+		{Dbms: "MySQL", DataType: "LONGTEXT"},
+		{Dbms: "TiDB", DataType: "TEXT"},
+	}
+	tests := []struct {
+		flavor, want string
+	}{
+		{MysqlFlavorMysql, "LONGTEXT"},
+		{MysqlFlavorTiDB, "TEXT"},
+		{MysqlFlavorMariaDB, "LONGTEXT"},
+	}
+	for _, tt := range tests {
+		metaData := NewMysqlDatabaseMetaData(WithMysqlServer(tt.flavor, ""))
+		node := selectColumnDbms(metaData, nodes)
+		if node == nil || node.DataType != tt.want {
+			t.Errorf("%s selectColumnDbms() = %v, want %s", tt.flavor, node, tt.want)
+		}
+	}
+	if selectColumnDbms(NewPostgresDatabaseMetaData(), nodes) != nil {
+		t.Error("PostgreSQL should not match MySQL column dbms")
+	}
+}
+
+func TestMysqlColumnDefinition(t *testing.T) {
+	createTable := "CREATE TABLE `t_user` (\n" +
+		"  `id` bigint NOT NULL,\n" +
+		"  `user_name` varchar(50) NOT NULL DEFAULT 'a,b' COMMENT '用户名',\n" +
+		"  PRIMARY KEY (`id`)\n" +
+		") ENGINE=InnoDB"
+	definition, ok := mysqlColumnDefinition(createTable, "USER_NAME")
+	if !ok || definition != "varchar(50) NOT NULL DEFAULT 'a,b' COMMENT '用户名'" {
+		t.Errorf("mysqlColumnDefinition() = %q, %v", definition, ok)
+	}
+	if _, ok = mysqlColumnDefinition(createTable, "missing"); ok {
+		t.Error("missing column should not be found")
+	}
+}
+
+func TestCompareVersion(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"8.0.36", "8.0", 1},
+		{"8.0", "8.0.0", 0},
+		{"10.4.32", "10.5.2", -1},
+		{"5.7.44-log", "5.7.44", 0},
+	}
+	for _, tt := range tests {
+		if got := compareVersion(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersion(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
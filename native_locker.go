@@ -0,0 +1,251 @@
+package dbfly
+
+import (
+	"context"
+	"database/sql"
+	"hash/fnv"
+	"time"
+)
+
+const defaultNativeLockName = "dbfly_change_lock"
+
+// nativeLock 尝试获取一次锁，成功时返回释放函数
+type nativeLock func(ctx context.Context, driver Driver, name string) (release func(context.Context) error, acquired bool, err error)
+
+// NativeLocker 基于数据库原生锁的锁实现，不依赖锁表。会话级的锁在驱动提供的独占连接上获取，
+// 持有锁的连接断开时由数据库自动释放，连接池需至少保留一个额外的连接供迁移使用
+type NativeLocker struct {
+	name          string
+	timeout       time.Duration
+	retryInterval time.Duration
+	// maxRetryInterval 指数退避的等待上限
+	maxRetryInterval time.Duration
+	lock             nativeLock
+	// session 是否需要在独占连接上持有锁
+	session bool
+}
+
+type NativeLockerOption func(*NativeLocker)
+
+// WithNativeLockName 设置锁名称，同一数据库上使用相同名称的迁移互斥
+func WithNativeLockName(name string) NativeLockerOption {
+	return func(l *NativeLocker) {
+		if name != "" {
+			l.name = name
+		}
+	}
+}
+
+func WithNativeLockTimeout(d time.Duration) NativeLockerOption {
+	return func(l *NativeLocker) {
+		if d > 0 {
+			l.timeout = d
+		}
+	}
+}
+
+func WithNativeLockRetryInterval(d time.Duration) NativeLockerOption {
+	return func(l *NativeLocker) {
+		if d > 0 {
+			l.retryInterval = d
+		}
+	}
+}
+
+// WithNativeLockMaxRetryInterval 设置重试等待时长的上限，等待时长从重试间隔开始按指数增长
+func WithNativeLockMaxRetryInterval(d time.Duration) NativeLockerOption {
+	return func(l *NativeLocker) {
+		if d > 0 {
+			l.maxRetryInterval = d
+		}
+	}
+}
+
+func newNativeLocker(lock nativeLock, session bool, opts []NativeLockerOption) *NativeLocker {
+	l := &NativeLocker{
+		name:             defaultNativeLockName,
+		timeout:          defaultLockTimeout,
+		retryInterval:    defaultLockRetryInterval,
+		maxRetryInterval: defaultLockMaxRetryInterval,
+		lock:             lock,
+		session:          session,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// NewPostgresLocker 使用 pg_try_advisory_lock 的锁，适用于 PostgreSQL 及兼容数据库
+func NewPostgresLocker(opts ...NativeLockerOption) *NativeLocker {
+	return newNativeLocker(postgresAdvisoryLock, true, opts)
+}
+
+// NewMysqlLocker 使用 GET_LOCK 的锁，适用于 MySQL 及兼容数据库
+func NewMysqlLocker(opts ...NativeLockerOption) *NativeLocker {
+	return newNativeLocker(mysqlNamedLock, true, opts)
+}
+
+// NewOracleLocker 使用 DBMS_LOCK.REQUEST 的锁，适用于 Oracle 与达梦，需要 DBMS_LOCK 的执行权限
+func NewOracleLocker(opts ...NativeLockerOption) *NativeLocker {
+	return newNativeLocker(oracleUserLock, true, opts)
+}
+
+// NewSqliteLocker 对数据库文件旁的 .lock 文件加排他锁，内存数据库不加锁
+func NewSqliteLocker(opts ...NativeLockerOption) *NativeLocker {
+	return newNativeLocker(sqliteFileLock, false, opts)
+}
+
+// NewNativeLocker 按迁移器选择数据库原生的锁，不支持的数据库返回 nil
+func NewNativeLocker(migratory Migratory, opts ...NativeLockerOption) *NativeLocker {
+	metaData := migratory.MetaData()
+	switch {
+	case matchDbms(metaData, "PostgreSQL"), matchDbms(metaData, "KingbaseES"), matchDbms(metaData, "VastBase"),
+		matchDbms(metaData, "openGauss"), matchDbms(metaData, "GaussDB"):
+		return NewPostgresLocker(opts...)
+	case matchDbms(metaData, MysqlFlavorMysql):
+		return NewMysqlLocker(opts...)
+	case matchDbms(metaData, "Oracle"), matchDbms(metaData, "DM DBMS"):
+		return NewOracleLocker(opts...)
+	case matchDbms(metaData, "SQLite"):
+		return NewSqliteLocker(opts...)
+	}
+	return nil
+}
+
+// Name 锁名称
+func (l *NativeLocker) Name() string {
+	return l.name
+}
+
+func (l *NativeLocker) Lock(ctx context.Context, fly *Dbfly) (Unlock, error) {
+	driver := fly.Driver()
+	closeSession := func() error { return nil }
+	if l.session {
+		session, err := openSession(ctx, driver)
+		if err != nil {
+			return nil, err
+		}
+		driver, closeSession = session, session.Close
+	}
+	waiter := newLockWaiter(fly, l.retryInterval, l.maxRetryInterval, l.timeout, 0)
+	for {
+		release, acquired, err := l.lock(ctx, driver, l.name)
+		if err != nil {
+			_ = closeSession()
+			return nil, Wrap(err, "acquire lock %q failed", l.name)
+		}
+		if acquired {
+			fly.logger.Debug("lock %q acquired", l.name)
+			unlock := func(ctx context.Context, fly *Dbfly) error {
+				err := release(ctx)
+				if closeErr := closeSession(); err == nil {
+					err = closeErr
+				}
+				return err
+			}
+			return unlock, nil
+		}
+		if err = waiter.wait(ctx, ""); err != nil {
+			_ = closeSession()
+			return nil, err
+		}
+	}
+}
+
+// lockKey 将锁名称转换为数值型锁标识
+func lockKey(name string) uint64 {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(name))
+	return hash.Sum64()
+}
+
+func postgresAdvisoryLock(ctx context.Context, driver Driver, name string) (func(context.Context) error, bool, error) {
+	key := int64(lockKey(name))
+	scan := func(rows Rows, t *bool) error {
+		return rows.Scan(t)
+	}
+	acquired, err := doGet[bool](ctx, driver, scan, "SELECT pg_try_advisory_lock(?)", key)
+	if err != nil || !acquired {
+		return nil, false, err
+	}
+	return func(ctx context.Context) error {
+		_, err := doGet[bool](ctx, driver, scan, "SELECT pg_advisory_unlock(?)", key)
+		return err
+	}, true, nil
+}
+
+func mysqlNamedLock(ctx context.Context, driver Driver, name string) (func(context.Context) error, bool, error) {
+	// MySQL 5.7 起锁名称最长 64 个字符
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	result, err := doGetScalar[int](ctx, driver, "SELECT COALESCE(GET_LOCK(?, 0), 0)", name)
+	if err != nil || result != 1 {
+		return nil, false, err
+	}
+	return func(ctx context.Context) error {
+		_, err := doGetScalar[int](ctx, driver, "SELECT COALESCE(RELEASE_LOCK(?), 0)", name)
+		return err
+	}, true, nil
+}
+
+// oracleUserLock 用户锁标识范围为 0 到 1073741823，DBMS_LOCK 的函数不能在 SQL 中调用，
+// 需放在 PL/SQL 匿名块中执行并通过输出参数取回结果
+func oracleUserLock(ctx context.Context, driver Driver, name string) (func(context.Context) error, bool, error) {
+	id := int64(lockKey(name) % 1073741824)
+	// 0 成功，1 超时，4 当前会话已持有
+	var result int
+	_, err := driver.Execute(ctx, "BEGIN ? := DBMS_LOCK.REQUEST(?, DBMS_LOCK.X_MODE, 0, FALSE); END;", sql.Out{Dest: &result}, id)
+	if err != nil {
+		return nil, false, err
+	}
+	switch result {
+	case 0, 4:
+	case 1:
+		return nil, false, nil
+	default:
+		return nil, false, New("DBMS_LOCK.REQUEST returned %d", result)
+	}
+	return func(ctx context.Context) error {
+		var result int
+		if _, err := driver.Execute(ctx, "BEGIN ? := DBMS_LOCK.RELEASE(?); END;", sql.Out{Dest: &result}, id); err != nil {
+			return err
+		}
+		if result != 0 {
+			return New("DBMS_LOCK.RELEASE returned %d", result)
+		}
+		return nil
+	}, true, nil
+}
+
+// sqliteFileLock SQLite 没有会话级的锁，BEGIN IMMEDIATE 会阻塞其他连接上的迁移语句，
+// 因此对数据库文件旁的 .lock 文件加排他锁，不占用连接
+func sqliteFileLock(ctx context.Context, driver Driver, name string) (func(context.Context) error, bool, error) {
+	var path string
+	err := doEach(ctx, driver, func(rows Rows) error {
+		var seq int
+		var schema, file string
+		if err := rows.Scan(&seq, &schema, &file); err != nil {
+			return err
+		}
+		if schema == "main" {
+			path = file
+		}
+		return nil
+	}, "PRAGMA database_list")
+	if err != nil {
+		return nil, false, err
+	}
+	if path == "" {
+		// 内存数据库只能被当前连接访问，无需加锁
+		return func(context.Context) error { return nil }, true, nil
+	}
+	file, acquired, err := tryLockFile(path + "." + name + ".lock")
+	if err != nil || !acquired {
+		return nil, false, err
+	}
+	return func(context.Context) error {
+		return file.Close()
+	}, true, nil
+}
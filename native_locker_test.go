@@ -0,0 +1,212 @@
+package dbfly
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sessionDriver 模拟提供独占连接的驱动，GET_LOCK 与 DBMS_LOCK.REQUEST 依次返回 results 中的值
+type sessionDriver struct {
+	recordDriver
+	mu      sync.Mutex
+	results []int
+	opened  int
+	closed  int
+	queries []string
+}
+
+func (d *sessionDriver) Query(_ context.Context, sql string, args ...interface{}) (Rows, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queries = append(d.queries, sql)
+	result := 1
+	if strings.Contains(sql, "GET_LOCK") && len(d.results) > 0 {
+		result, d.results = d.results[0], d.results[1:]
+	}
+	return &valueRows{values: [][]interface{}{{result}}}, nil
+}
+
+func (d *sessionDriver) Execute(_ context.Context, query string, args ...interface{}) (sql.Result, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queries = append(d.queries, query)
+	result := 0
+	if strings.Contains(query, "DBMS_LOCK.REQUEST") && len(d.results) > 0 {
+		result, d.results = d.results[0], d.results[1:]
+	}
+	for _, arg := range args {
+		if out, ok := arg.(sql.Out); ok {
+			*out.Dest.(*int) = result
+		}
+	}
+	return nil, nil
+}
+
+func (d *sessionDriver) Session(context.Context) (Session, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.opened++
+	return &fakeSession{driver: d}, nil
+}
+
+type fakeSession struct {
+	driver *sessionDriver
+}
+
+func (s *fakeSession) Execute(ctx context.Context, sql string, args ...interface{}) (sql.Result, error) {
+	return s.driver.Execute(ctx, sql, args...)
+}
+
+func (s *fakeSession) Query(ctx context.Context, sql string, args ...interface{}) (Rows, error) {
+	return s.driver.Query(ctx, sql, args...)
+}
+
+func (s *fakeSession) BeginTx(ctx context.Context) (Tx, error) {
+	return s.driver.BeginTx(ctx)
+}
+
+func (s *fakeSession) Close() error {
+	s.driver.mu.Lock()
+	defer s.driver.mu.Unlock()
+	s.driver.closed++
+	return nil
+}
+
+func TestNewNativeLocker(t *testing.T) {
+	tests := []struct {
+		migratory Migratory
+		want      bool
+	}{
+		{NewPostgresMigratory(), true},
+		{NewKingbaseMigratory(), true},
+		{NewOpenGaussMigratory(), true},
+		{NewMysqlMigratory(), true},
+		{NewOracleMigratory(), true},
+		{NewDamengMigratory(), true},
+		{NewSqliteMigratory(), true},
+		{NewSqlServerMigratory(), false},
+		{NewClickHouseMigratory(), false},
+	}
+	for _, tt := range tests {
+		if got := NewNativeLocker(tt.migratory); (got != nil) != tt.want {
+			t.Errorf("NewNativeLocker(%s) = %v, want supported %v", tt.migratory.Name(), got, tt.want)
+		}
+	}
+
+	fly := NewDbfly(NewPostgresMigratory(), &recordDriver{}, nil, WithNativeLocker(WithNativeLockName("app")))
+	if locker, ok := fly.locker.(*NativeLocker); !ok || locker.Name() != "app" {
+		t.Errorf("WithNativeLocker() locker = %#v", fly.locker)
+	}
+	fly = NewDbfly(NewSqlServerMigratory(), &recordDriver{}, nil, WithNativeLocker())
+	if _, ok := fly.locker.(*DbLocker); !ok {
+		t.Errorf("WithNativeLocker() unsupported locker = %#v", fly.locker)
+	}
+}
+
+func TestNativeLocker_Lock(t *testing.T) {
+	driver := &sessionDriver{results: []int{0, 0, 1}}
+	fly := NewDbfly(NewMysqlMigratory(), driver, nil)
+	locker := NewMysqlLocker(WithNativeLockRetryInterval(time.Millisecond), WithNativeLockTimeout(time.Second))
+	unlock, err := locker.Lock(context.Background(), fly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = unlock(context.Background(), fly); err != nil {
+		t.Fatal(err)
+	}
+	if driver.opened != 1 || driver.closed != 1 {
+		t.Errorf("session opened %d closed %d, want 1 and 1", driver.opened, driver.closed)
+	}
+	if len(driver.queries) != 4 || !strings.Contains(driver.queries[3], "RELEASE_LOCK") {
+		t.Errorf("queries = %q", driver.queries)
+	}
+
+	driver = &sessionDriver{results: []int{0, 0, 0, 0, 0}}
+	locker = NewMysqlLocker(WithNativeLockRetryInterval(10*time.Millisecond), WithNativeLockTimeout(30*time.Millisecond))
+	if _, err = locker.Lock(context.Background(), NewDbfly(NewMysqlMigratory(), driver, nil)); !errors.Is(err, ErrLockTimeout) {
+		t.Errorf("busy lock error = %v, want ErrLockTimeout", err)
+	}
+	if driver.closed != 1 {
+		t.Errorf("session closed %d after timeout, want 1", driver.closed)
+	}
+
+	_, err = NewPostgresLocker().Lock(context.Background(), NewDbfly(NewPostgresMigratory(), &recordDriver{}, nil))
+	if !errors.Is(err, ErrSessionUnsupported) {
+		t.Errorf("Lock() error = %v, want ErrSessionUnsupported", err)
+	}
+}
+
+func TestOracleLocker(t *testing.T) {
+	driver := &sessionDriver{results: []int{1, 0}}
+	fly := NewDbfly(NewOracleMigratory(), driver, nil)
+	locker := NewOracleLocker(WithNativeLockRetryInterval(time.Millisecond), WithNativeLockTimeout(time.Second))
+	unlock, err := locker.Lock(context.Background(), fly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = unlock(context.Background(), fly); err != nil {
+		t.Fatal(err)
+	}
+	if len(driver.queries) != 3 {
+		t.Fatalf("queries = %q", driver.queries)
+	}
+	for _, query := range driver.queries {
+		if !strings.HasPrefix(query, "BEGIN ") || !strings.HasSuffix(query, "END;") {
+			t.Errorf("query %q is not a PL/SQL block", query)
+		}
+	}
+	if !strings.Contains(driver.queries[2], "DBMS_LOCK.RELEASE") {
+		t.Errorf("release query = %q", driver.queries[2])
+	}
+
+	driver = &sessionDriver{results: []int{3}}
+	if _, err = locker.Lock(context.Background(), NewDbfly(NewOracleMigratory(), driver, nil)); err == nil {
+		t.Error("Lock() error = nil, want DBMS_LOCK.REQUEST failure")
+	}
+	if driver.closed != 1 {
+		t.Errorf("session closed %d after failure, want 1", driver.closed)
+	}
+}
+
+// pragmaDriver 模拟 PRAGMA database_list 的结果
+type pragmaDriver struct {
+	recordDriver
+	path string
+}
+
+func (d *pragmaDriver) Query(context.Context, string, ...interface{}) (Rows, error) {
+	return &valueRows{values: [][]interface{}{{0, "main", d.path}}}, nil
+}
+
+func TestSqliteLocker(t *testing.T) {
+	driver := &pragmaDriver{path: filepath.Join(t.TempDir(), "app.db")}
+	fly := NewDbfly(NewSqliteMigratory(), driver, nil)
+	locker := NewSqliteLocker(WithNativeLockRetryInterval(10*time.Millisecond), WithNativeLockTimeout(30*time.Millisecond))
+	unlock, err := locker.Lock(context.Background(), fly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = locker.Lock(context.Background(), fly); err == nil {
+		t.Error("second lock should time out while the file is locked")
+	}
+	if err = unlock(context.Background(), fly); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err = locker.Lock(context.Background(), fly)
+	if err != nil {
+		t.Fatalf("lock after release: %v", err)
+	}
+	_ = unlock(context.Background(), fly)
+
+	// 内存数据库不加锁
+	memory := NewDbfly(NewSqliteMigratory(), &pragmaDriver{}, nil)
+	if _, err = locker.Lock(context.Background(), memory); err != nil {
+		t.Errorf("in-memory lock error = %v", err)
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"encoding/xml"
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type DDL interface {
@@ -18,85 +21,246 @@ type Condition interface {
 type ChangeSets []ChangeSet
 
 type ChangeSet struct {
-	Id       string
-	Author   string
-	OnFail   string
-	Filename string
-	DDLs     []DDL
+	Id          string
+	Author      string
+	OnFail      string
+	Filename    string
+	Description string
+	// Checksum changeSet 定义的校验和
+	Checksum string
+	// RunInTransaction 是否在事务中执行：true、false、auto
+	RunInTransaction string
+	DDLs             []DDL
+	// Rollback 回滚操作，未定义时为 nil
+	Rollback *RollbackNode
+}
+
+// ConditionsNode 执行条件，子条件之间关系为或
+type ConditionsNode struct {
+	Conditions []Condition
 }
 
-type ConditionsNode struct {
-	Conditions []*ConditionNode `xml:"condition"`
+func (n *ConditionsNode) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	conditions, err := decodeConditions(decoder, start, true)
+	if err != nil {
+		return err
+	}
+	n.Conditions = conditions
+	return nil
 }
 
 func (n *ConditionsNode) Check(ctx context.Context, fly *Dbfly) (bool, error) {
 	if n == nil || len(n.Conditions) == 0 {
 		return true, nil
 	}
-	for _, node := range n.Conditions {
-		ok, err := node.Check(ctx, fly)
-		if err != nil || ok {
-			return ok, err
-		}
-	}
-	return false, nil
+	return checkAny(ctx, fly, n.Conditions)
 }
 
+// ConditionNode 条件组，组内条件关系为与
 type ConditionNode struct {
+	Not        bool
 	Conditions []Condition
 }
 
 func (n *ConditionNode) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	n.Not = notAttr(start)
+	conditions, err := decodeConditions(decoder, start, false)
+	if err != nil {
+		return err
+	}
+	n.Conditions = conditions
+	return nil
+}
+
+func (n *ConditionNode) Check(ctx context.Context, fly *Dbfly) (bool, error) {
+	if n == nil {
+		return true, nil
+	}
+	pass, err := checkAll(ctx, fly, n.Conditions)
+	return negate(pass, n.Not), err
+}
+
+// AndNode 所有子条件均满足
+type AndNode struct {
+	Not        bool
+	Conditions []Condition
+}
+
+func (n *AndNode) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	n.Not = notAttr(start)
+	conditions, err := decodeConditions(decoder, start, false)
+	if err != nil {
+		return err
+	}
+	n.Conditions = conditions
+	return nil
+}
+
+func (n *AndNode) Check(ctx context.Context, fly *Dbfly) (bool, error) {
+	pass, err := checkAll(ctx, fly, n.Conditions)
+	return negate(pass, n.Not), err
+}
+
+// OrNode 任一子条件满足
+type OrNode struct {
+	Not        bool
+	Conditions []Condition
+}
+
+func (n *OrNode) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	n.Not = notAttr(start)
+	conditions, err := decodeConditions(decoder, start, false)
+	if err != nil {
+		return err
+	}
+	n.Conditions = conditions
+	return nil
+}
+
+func (n *OrNode) Check(ctx context.Context, fly *Dbfly) (bool, error) {
+	pass, err := checkAny(ctx, fly, n.Conditions)
+	return negate(pass, n.Not), err
+}
+
+// NotNode 子条件不满足，多个子条件时对其与的结果取反
+type NotNode struct {
+	Conditions []Condition
+}
+
+func (n *NotNode) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	conditions, err := decodeConditions(decoder, start, false)
+	if err != nil {
+		return err
+	}
+	if len(conditions) == 0 {
+		return New("<not> element requires at least one child condition")
+	}
+	n.Conditions = conditions
+	return nil
+}
+
+func (n *NotNode) Check(ctx context.Context, fly *Dbfly) (bool, error) {
+	pass, err := checkAll(ctx, fly, n.Conditions)
+	return negate(pass, true), err
+}
+
+// decodeConditions 解析子条件元素，and、or、not 可以递归嵌套；
+// conditions 元素下只允许条件组和 and、or、not 容器
+func decodeConditions(decoder *xml.Decoder, start xml.StartElement, groupOnly bool) ([]Condition, error) {
+	var conditions []Condition
 	for {
 		token, err := decoder.Token()
 		if err != nil {
 			break
 		}
-
-		switch ele := token.(type) {
-		case xml.StartElement:
-			name := ele.Name.Local
-			var condition Condition
-			switch name {
-			case "tableExists":
-				condition = &TableExistsNode{}
-			case "columnExists":
-				condition = &ColumnExistsNode{}
-			case "primaryKeyExists":
-				condition = &PrimaryKeyExistsNode{}
-			case "indexExists":
-				condition = &IndexExistsNode{}
-			case "rowCount":
-				condition = &RowCountNode{}
-			case "sqlCheck":
-				condition = &SqlCheckNode{}
-			case "dbms":
-				condition = &DbmsNode{}
-			default:
-				return New("invalid child element <%s> inside condition element", ele.Name.Local)
-			}
-			if err = decoder.DecodeElement(condition, &ele); err != nil {
-				return err
+		ele, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		var condition Condition
+		switch ele.Name.Local {
+		case "condition":
+			condition = &ConditionNode{}
+		case "and":
+			condition = &AndNode{}
+		case "or":
+			condition = &OrNode{}
+		case "not":
+			condition = &NotNode{}
+		default:
+			if !groupOnly {
+				condition = newLeafCondition(ele.Name.Local)
 			}
-			n.Conditions = append(n.Conditions, condition)
 		}
+		if condition == nil {
+			return nil, New("invalid child element <%s> inside %s element", ele.Name.Local, start.Name.Local)
+		}
+		if err = decoder.DecodeElement(condition, &ele); err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+	return conditions, nil
+}
+
+// newLeafCondition 根据元素名称创建检查条件，未知元素返回nil
+func newLeafCondition(name string) Condition {
+	switch name {
+	case "tableExists":
+		return &TableExistsNode{}
+	case "columnExists":
+		return &ColumnExistsNode{}
+	case "primaryKeyExists":
+		return &PrimaryKeyExistsNode{}
+	case "indexExists":
+		return &IndexExistsNode{}
+	case "rowCount":
+		return &RowCountNode{}
+	case "sqlCheck":
+		return &SqlCheckNode{}
+	case "dbms":
+		return &DbmsNode{}
+	case "dbmsVersion":
+		return &DbmsVersionNode{}
+	case "viewExists":
+		return &ViewExistsNode{}
+	case "changeSetExecuted":
+		return &ChangeSetExecutedNode{}
+	case "propertyEquals":
+		return &PropertyEqualsNode{}
+	case "propertyDefined":
+		return &PropertyDefinedNode{}
+	case "context":
+		return &ContextNode{}
+	case "goCheck":
+		return &GoCheckNode{}
 	}
 	return nil
 }
 
-func (n *ConditionNode) Check(ctx context.Context, fly *Dbfly) (bool, error) {
-	if n == nil || len(n.Conditions) == 0 {
-		return true, nil
+// notAttr 读取容器元素的 not 属性
+func notAttr(start xml.StartElement) bool {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "not" {
+			return attr.Value == "true" || attr.Value == "1"
+		}
 	}
-	for _, condition := range n.Conditions {
+	return false
+}
+
+// negate 按 not 属性对检查结果取反
+func negate(pass, not bool) bool {
+	if not {
+		return !pass
+	}
+	return pass
+}
+
+// checkAll 依次检查所有条件，遇到不满足或出错时停止；没有条件时视为满足
+func checkAll(ctx context.Context, fly *Dbfly, conditions []Condition) (bool, error) {
+	for _, condition := range conditions {
 		ok, err := condition.Check(ctx, fly)
 		if err != nil || !ok {
-			return ok, err
+			return false, err
 		}
 	}
 	return true, nil
 }
 
+// checkAny 依次检查所有条件，遇到满足或出错时停止；没有条件时视为满足
+func checkAny(ctx context.Context, fly *Dbfly, conditions []Condition) (bool, error) {
+	if len(conditions) == 0 {
+		return true, nil
+	}
+	for _, condition := range conditions {
+		ok, err := condition.Check(ctx, fly)
+		if err != nil || ok {
+			return ok && err == nil, err
+		}
+	}
+	return false, nil
+}
+
 type TableExistsNode struct {
 	TableName string `xml:"tableName,attr"`
 	Not       bool   `xml:"not,attr"`
@@ -108,10 +272,7 @@ func (n *TableExistsNode) Check(ctx context.Context, fly *Dbfly) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	if n.Not {
-		pass = !pass
-	}
-	return pass, nil
+	return negate(pass, n.Not), nil
 }
 
 type ColumnExistsNode struct {
@@ -126,10 +287,7 @@ func (n *ColumnExistsNode) Check(ctx context.Context, fly *Dbfly) (bool, error)
 	if err != nil {
 		return false, err
 	}
-	if n.Not {
-		pass = !pass
-	}
-	return pass, nil
+	return negate(pass, n.Not), nil
 }
 
 type PrimaryKeyExistsNode struct {
@@ -143,10 +301,7 @@ func (n *PrimaryKeyExistsNode) Check(ctx context.Context, fly *Dbfly) (bool, err
 	if err != nil {
 		return false, err
 	}
-	if n.Not {
-		pass = !pass
-	}
-	return pass, nil
+	return negate(pass, n.Not), nil
 }
 
 type IndexExistsNode struct {
@@ -161,36 +316,42 @@ func (n *IndexExistsNode) Check(ctx context.Context, fly *Dbfly) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	if n.Not {
-		pass = !pass
-	}
-	return pass, nil
+	return negate(pass, n.Not), nil
 }
 
 type RowCountNode struct {
 	TableName    string `xml:"tableName,attr"`
 	ExpectedRows int    `xml:"expectedRows,attr"`
-	Not          bool   `xml:"not,attr"`
+	// Where 过滤条件，不包含 WHERE 关键字
+	Where string `xml:"where,attr,omitempty"`
+	// Operator 比较运算符，默认为 =
+	Operator string `xml:"operator,attr,omitempty"`
+	Not      bool   `xml:"not,attr"`
 }
 
 func (n *RowCountNode) Check(ctx context.Context, fly *Dbfly) (bool, error) {
 	migratory := fly.Migratory()
 	sql := fmt.Sprintf(`SELECT count(*) FROM %s`, migratory.MetaData().Quoter().MustQuote(n.TableName))
+	if where := strings.TrimSpace(n.Where); where != "" {
+		sql += " WHERE " + where
+	}
 	count, err := doGetScalar[int](ctx, fly.Driver(), sql)
 	if err != nil {
 		return false, err
 	}
-	pass := count == n.ExpectedRows
-	if n.Not {
-		pass = !pass
+	pass, err := compareValue(strconv.Itoa(count), n.Operator, strconv.Itoa(n.ExpectedRows))
+	if err != nil {
+		return false, err
 	}
-	return pass, nil
+	return negate(pass, n.Not), nil
 }
 
 type SqlCheckNode struct {
 	Sql            *SqlNode `xml:"sql"`
 	ExpectedResult string   `xml:"expectedResult,attr"`
-	Not            bool     `xml:"not,attr"`
+	// Operator 比较运算符，默认为 =，两侧均为数字时按数值比较
+	Operator string `xml:"operator,attr,omitempty"`
+	Not      bool   `xml:"not,attr"`
 }
 
 func (n *SqlCheckNode) Check(ctx context.Context, fly *Dbfly) (bool, error) {
@@ -204,12 +365,11 @@ func (n *SqlCheckNode) Check(ctx context.Context, fly *Dbfly) (bool, error) {
 		if err != nil {
 			return false, err
 		}
-		pass = value == n.ExpectedResult
-	}
-	if n.Not {
-		pass = !pass
+		if pass, err = compareValue(value, n.Operator, n.ExpectedResult); err != nil {
+			return false, err
+		}
 	}
-	return pass, nil
+	return negate(pass, n.Not), nil
 }
 
 type DbmsNode struct {
@@ -218,17 +378,162 @@ type DbmsNode struct {
 }
 
 func (n *DbmsNode) Check(_ context.Context, fly *Dbfly) (bool, error) {
-	pass := fly.Migratory().MetaData().Dbms() == n.Name
-	if n.Not {
-		pass = !pass
+	pass := matchDbms(fly.Migratory().MetaData(), n.Name)
+	return negate(pass, n.Not), nil
+}
+
+// DbmsVersionNode 判断数据库版本是否在 [min, max] 范围内，设置 name 时同时要求数据库匹配
+type DbmsVersionNode struct {
+	Name string `xml:"name,attr,omitempty"`
+	Min  string `xml:"min,attr,omitempty"`
+	Max  string `xml:"max,attr,omitempty"`
+	Not  bool   `xml:"not,attr"`
+}
+
+func (n *DbmsVersionNode) Check(ctx context.Context, fly *Dbfly) (bool, error) {
+	metaData := fly.Migratory().MetaData()
+	pass := n.Name == "" || matchDbms(metaData, n.Name)
+	if pass {
+		version, err := metaData.Version(ctx, fly.Driver())
+		if err != nil {
+			return false, err
+		}
+		pass = versionInRange(version, n.Min, n.Max)
+	}
+	return negate(pass, n.Not), nil
+}
+
+// ViewExistsNode 判断视图是否存在
+type ViewExistsNode struct {
+	ViewName string `xml:"viewName,attr"`
+	Not      bool   `xml:"not,attr"`
+}
+
+func (n *ViewExistsNode) Check(ctx context.Context, fly *Dbfly) (bool, error) {
+	tables, err := fly.Migratory().MetaData().GetTables(ctx, fly.Driver())
+	if err != nil {
+		return false, err
+	}
+	var pass bool
+	for _, table := range tables {
+		if strings.EqualFold(table.TableType, "VIEW") && strings.EqualFold(table.Name, n.ViewName) {
+			pass = true
+			break
+		}
+	}
+	return negate(pass, n.Not), nil
+}
+
+// ChangeSetExecutedNode 判断指定 changeSet 是否已成功执行
+type ChangeSetExecutedNode struct {
+	Id  string `xml:"id,attr"`
+	Not bool   `xml:"not,attr"`
+}
+
+func (n *ChangeSetExecutedNode) Check(ctx context.Context, fly *Dbfly) (bool, error) {
+	executed, err := fly.recorder.GetExecutedChangeSets(ctx, fly)
+	if err != nil {
+		return false, err
 	}
-	return pass, nil
+	return negate(executed[n.Id], n.Not), nil
+}
+
+// PropertyEqualsNode 判断属性值是否等于指定值，属性未定义时不满足
+type PropertyEqualsNode struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+	Not   bool   `xml:"not,attr"`
+}
+
+func (n *PropertyEqualsNode) Check(_ context.Context, fly *Dbfly) (bool, error) {
+	value, ok := fly.Property(n.Name)
+	return negate(ok && value == n.Value, n.Not), nil
+}
+
+// PropertyDefinedNode 判断属性是否已定义
+type PropertyDefinedNode struct {
+	Name string `xml:"name,attr"`
+	Not  bool   `xml:"not,attr"`
+}
+
+func (n *PropertyDefinedNode) Check(_ context.Context, fly *Dbfly) (bool, error) {
+	_, ok := fly.Property(n.Name)
+	return negate(ok, n.Not), nil
+}
+
+// ContextNode 判断当前运行的上下文是否包含任一指定名称，多个名称以逗号分隔
+type ContextNode struct {
+	Name string `xml:"name,attr"`
+	Not  bool   `xml:"not,attr"`
+}
+
+func (n *ContextNode) Check(_ context.Context, fly *Dbfly) (bool, error) {
+	var pass bool
+	for _, name := range strings.Split(n.Name, ",") {
+		name = strings.TrimSpace(name)
+		for _, current := range fly.Contexts() {
+			if strings.EqualFold(name, current) {
+				pass = true
+			}
+		}
+	}
+	return negate(pass, n.Not), nil
+}
+
+// GoCheckNode 调用通过 WithCheck 注册的检查函数
+type GoCheckNode struct {
+	Name string `xml:"name,attr"`
+	Not  bool   `xml:"not,attr"`
+}
+
+func (n *GoCheckNode) Check(ctx context.Context, fly *Dbfly) (bool, error) {
+	check, ok := fly.checks[n.Name]
+	if !ok {
+		return false, New("go check %q is not registered", n.Name)
+	}
+	pass, err := check(ctx, fly)
+	if err != nil {
+		return false, err
+	}
+	return negate(pass, n.Not), nil
+}
+
+// compareValue 按运算符比较实际值与期望值，两侧均为数字时按数值比较，否则按字符串比较
+func compareValue(actual, operator, expected string) (bool, error) {
+	var result int
+	x, errX := strconv.ParseFloat(strings.TrimSpace(actual), 64)
+	y, errY := strconv.ParseFloat(strings.TrimSpace(expected), 64)
+	if errX == nil && errY == nil {
+		switch {
+		case x < y:
+			result = -1
+		case x > y:
+			result = 1
+		}
+	} else {
+		result = strings.Compare(actual, expected)
+	}
+	switch operator {
+	case "", "=", "==":
+		return result == 0, nil
+	case "!=", "<>":
+		return result != 0, nil
+	case ">":
+		return result > 0, nil
+	case ">=":
+		return result >= 0, nil
+	case "<":
+		return result < 0, nil
+	case "<=":
+		return result <= 0, nil
+	}
+	return false, New("invalid compare operator: %s", operator)
 }
 
 // CreateTableNode 创建表节点
 type CreateTableNode struct {
 	TableName  string          `xml:"tableName,attr"`
-	Comment    string          `xml:"comment,attr"`
+	Comment    string          `xml:"comment,attr,omitempty"`
 	Conditions *ConditionsNode `xml:"conditions"`
 	Columns    []*ColumnNode   `xml:"column"`
 	Attributes *AttributesNode `xml:"dbmsAttributes"`
@@ -245,23 +550,25 @@ func (n *CreateTableNode) Execute(ctx context.Context, fly *Dbfly) error {
 type ColumnNode struct {
 	ColumnName         string            `xml:"columnName,attr"`
 	DataType           string            `xml:"dataType,attr"`
-	MaxLength          int               `xml:"maxLength,attr"`
-	NumericScale       int               `xml:"numericScale,attr"`
-	Notnull            bool              `xml:"notnull,attr"`
-	Unique             bool              `xml:"unique,attr"`
-	PrimaryKey         bool              `xml:"primaryKey,attr"`
-	KeyName            string            `xml:"keyName,attr"`
-	DefaultValue       string            `xml:"defaultValue,attr"`
-	DefaultOriginValue string            `xml:"defaultOriginValue,attr"`
-	Comment            string            `xml:"comment,attr"`
+	MaxLength          int               `xml:"maxLength,attr,omitempty"`
+	NumericScale       int               `xml:"numericScale,attr,omitempty"`
+	Notnull            bool              `xml:"notnull,attr,omitempty"`
+	Unique             bool              `xml:"unique,attr,omitempty"`
+	PrimaryKey         bool              `xml:"primaryKey,attr,omitempty"`
+	KeyName            string            `xml:"keyName,attr,omitempty"`
+	DefaultValue       string            `xml:"defaultValue,attr,omitempty"`
+	DefaultOriginValue string            `xml:"defaultOriginValue,attr,omitempty"`
+	Comment            string            `xml:"comment,attr,omitempty"`
 	ColumnDbms         []*ColumnDbmsNode `xml:"columnDbms"`
 }
 
 type ColumnDbmsNode struct {
-	Dbms               string `xml:"dbms,attr"`
+	Dbms string `xml:"dbms,attr"`
+	// MinVersion 要求的最低数据库版本
+	MinVersion         string `xml:"minVersion,attr,omitempty"`
 	DataType           string `xml:"dataType,attr"`
-	DefaultValue       string `xml:"defaultValue,attr"`
-	DefaultOriginValue string `xml:"defaultOriginValue,attr"`
+	DefaultValue       string `xml:"defaultValue,attr,omitempty"`
+	DefaultOriginValue string `xml:"defaultOriginValue,attr,omitempty"`
 }
 
 type AttributesNode struct {
@@ -278,7 +585,7 @@ type AttributeNode struct {
 type CreateIndexNode struct {
 	TableName  string             `xml:"tableName,attr"`
 	IndexName  string             `xml:"indexName,attr"`
-	Unique     bool               `xml:"unique,attr"`
+	Unique     bool               `xml:"unique,attr,omitempty"`
 	Conditions *ConditionsNode    `xml:"conditions"`
 	Columns    []*IndexColumnNode `xml:"column"`
 	Attributes *AttributesNode    `xml:"attributes"`
@@ -358,13 +665,13 @@ func (n *AddColumnNode) Execute(ctx context.Context, fly *Dbfly) error {
 type AddColumnColumnNode struct {
 	ColumnName         string            `xml:"columnName,attr"`
 	DataType           string            `xml:"dataType,attr"`
-	MaxLength          int               `xml:"maxLength,attr"`
-	NumericScale       int               `xml:"numericScale,attr"`
-	Notnull            bool              `xml:"notnull,attr"`
-	Unique             bool              `xml:"unique,attr"`
-	DefaultValue       string            `xml:"defaultValue,attr"`
-	DefaultOriginValue string            `xml:"defaultOriginValue,attr"`
-	Comment            string            `xml:"comment,attr"`
+	MaxLength          int               `xml:"maxLength,attr,omitempty"`
+	NumericScale       int               `xml:"numericScale,attr,omitempty"`
+	Notnull            bool              `xml:"notnull,attr,omitempty"`
+	Unique             bool              `xml:"unique,attr,omitempty"`
+	DefaultValue       string            `xml:"defaultValue,attr,omitempty"`
+	DefaultOriginValue string            `xml:"defaultOriginValue,attr,omitempty"`
+	Comment            string            `xml:"comment,attr,omitempty"`
 	ColumnDbms         []*ColumnDbmsNode `xml:"columnDbms"`
 }
 
@@ -402,13 +709,13 @@ func (n *AlterColumnNode) Execute(ctx context.Context, fly *Dbfly) error {
 
 type AlterColumnColumnNode struct {
 	DataType           string            `xml:"dataType,attr"`
-	MaxLength          int               `xml:"maxLength,attr"`
-	NumericScale       int               `xml:"numericScale,attr"`
-	Notnull            bool              `xml:"notnull,attr"`
-	Unique             bool              `xml:"unique,attr"`
-	DefaultValue       string            `xml:"defaultValue,attr"`
-	DefaultOriginValue string            `xml:"defaultOriginValue,attr"`
-	Comment            string            `xml:"comment,attr"`
+	MaxLength          int               `xml:"maxLength,attr,omitempty"`
+	NumericScale       int               `xml:"numericScale,attr,omitempty"`
+	Notnull            bool              `xml:"notnull,attr,omitempty"`
+	Unique             bool              `xml:"unique,attr,omitempty"`
+	DefaultValue       string            `xml:"defaultValue,attr,omitempty"`
+	DefaultOriginValue string            `xml:"defaultOriginValue,attr,omitempty"`
+	Comment            string            `xml:"comment,attr,omitempty"`
 	ColumnDbms         []*ColumnDbmsNode `xml:"columnDbms"`
 }
 
@@ -459,7 +766,7 @@ func (n *RenameTableNode) Execute(ctx context.Context, fly *Dbfly) error {
 // AlterTableCommentNode 重命名表说明节点
 type AlterTableCommentNode struct {
 	TableName  string          `xml:"tableName,attr"`
-	Comment    string          `xml:"comment,attr"`
+	Comment    string          `xml:"comment,attr,omitempty"`
 	Conditions *ConditionsNode `xml:"conditions"`
 	Attributes *AttributesNode `xml:"attributes"`
 }
@@ -481,7 +788,9 @@ type SqlFileNode struct {
 // SqlFileDbmsNode SQL脚本方言文件节点
 type SqlFileDbmsNode struct {
 	Dbms string `xml:"dbms,attr"`
-	Path string `xml:"path,attr"`
+	// MinVersion 要求的最低数据库版本
+	MinVersion string `xml:"minVersion,attr,omitempty"`
+	Path       string `xml:"path,attr"`
 }
 
 func (n *SqlFileNode) Execute(ctx context.Context, fly *Dbfly) error {
@@ -489,11 +798,8 @@ func (n *SqlFileNode) Execute(ctx context.Context, fly *Dbfly) error {
 		return err
 	}
 	path := n.Path
-	for _, dbmsNode := range n.SqlFileDbms {
-		if dbmsNode.Dbms == fly.Migratory().MetaData().Dbms() {
-			path = dbmsNode.Path
-			break
-		}
+	if dbmsNode, ok := selectDbms(fly.Migratory().MetaData(), n.SqlFileDbms, func(d *SqlFileDbmsNode) (string, string) { return d.Dbms, d.MinVersion }); ok {
+		path = dbmsNode.Path
 	}
 	content, err := fly.Source().Read(path)
 	if err != nil {
@@ -508,11 +814,34 @@ type SqlNode struct {
 
 // ChangeSetNode 变更集节点
 type ChangeSetNode struct {
-	Id         string
-	Author     string
-	OnFail     string
-	Conditions *ConditionsNode
-	DDLs       []DDL
+	Id          string
+	Author      string
+	OnFail      string
+	Description string
+	// RunInTransaction 是否在事务中执行：true、false（默认）、auto
+	RunInTransaction string
+	Conditions       *ConditionsNode
+	DDLs             []DDL
+	Rollback         *RollbackNode
+}
+
+// maxDescriptionLength 变更记录中说明的最大长度（字符数）
+const maxDescriptionLength = 255
+
+// description 变更集说明，未指定时由包含的操作名称组成
+func (n *ChangeSetNode) description() string {
+	description := n.Description
+	if description == "" {
+		names := make([]string, 0, len(n.DDLs))
+		for _, ddl := range n.DDLs {
+			names = append(names, ddlElementName(ddl))
+		}
+		description = strings.Join(names, ", ")
+	}
+	if runes := []rune(description); len(runes) > maxDescriptionLength {
+		description = string(runes[:maxDescriptionLength])
+	}
+	return description
 }
 
 func (n *ChangeSetNode) Execute(ctx context.Context, fly *Dbfly) error {
@@ -534,6 +863,10 @@ func (n *ChangeSetNode) UnmarshalXML(decoder *xml.Decoder, start xml.StartElemen
 			n.Author = attr.Value
 		case "onFail":
 			n.OnFail = attr.Value
+		case "description":
+			n.Description = attr.Value
+		case "runInTransaction":
+			n.RunInTransaction = attr.Value
 		}
 	}
 	// 然后手动解析子元素
@@ -548,42 +881,15 @@ func (n *ChangeSetNode) UnmarshalXML(decoder *xml.Decoder, start xml.StartElemen
 			name := ele.Name.Local
 			var ddl DDL
 			switch name {
-			case "createTable":
-				ddl = &CreateTableNode{}
-			case "createIndex":
-				ddl = &CreateIndexNode{}
-			case "createPrimaryKey":
-				ddl = &CreatePrimaryKeyNode{}
-			case "dropTable":
-				ddl = &DropTableNode{}
-			case "dropIndex":
-				ddl = &DropIndexNode{}
-			case "addColumn":
-				ddl = &AddColumnNode{}
-			case "renameColumn":
-				ddl = &RenameColumnNode{}
-			case "alterColumn":
-				ddl = &AlterColumnNode{}
-			case "dropColumn":
-				ddl = &DropColumnNode{}
-			case "dropPrimaryKey":
-				ddl = &DropPrimaryKeyNode{}
-			case "renameTable":
-				ddl = &RenameTableNode{}
-			case "alterTableComment":
-				ddl = &AlterTableCommentNode{}
-			case "sqlFile":
-				ddl = &SqlFileNode{}
-			case "insert":
-				ddl = &InsertNode{}
-			case "update":
-				ddl = &UpdateNode{}
-			case "delete":
-				ddl = &DeleteNode{}
-			case "sqlInline":
-				ddl = &SqlInlineNode{}
-			case "transaction":
-				ddl = &TransactionNode{}
+			case "rollback":
+				if n.Rollback != nil {
+					return New("duplicate <rollback> in changeSet %s", n.Id)
+				}
+				n.Rollback = &RollbackNode{}
+				if err = decoder.DecodeElement(n.Rollback, &ele); err != nil {
+					return err
+				}
+				continue
 			case "conditions":
 				n.Conditions = &ConditionsNode{}
 				if err = decoder.DecodeElement(n.Conditions, &ele); err != nil {
@@ -591,7 +897,9 @@ func (n *ChangeSetNode) UnmarshalXML(decoder *xml.Decoder, start xml.StartElemen
 				}
 				continue
 			default:
-				return New("invalid DDL element <%s>", name)
+				if ddl = newDDLNode(name); ddl == nil {
+					return New("invalid DDL element <%s>", name)
+				}
 			}
 			if err = decoder.DecodeElement(ddl, &ele); err != nil {
 				return err
@@ -606,11 +914,260 @@ func (n *ChangeSetNode) UnmarshalXML(decoder *xml.Decoder, start xml.StartElemen
 	return nil
 }
 
+// newDDLNode 根据元素名称创建 DDL 节点，不支持的元素返回 nil
+func newDDLNode(name string) DDL {
+	switch name {
+	case "createTable":
+		return &CreateTableNode{}
+	case "createIndex":
+		return &CreateIndexNode{}
+	case "createPrimaryKey":
+		return &CreatePrimaryKeyNode{}
+	case "dropTable":
+		return &DropTableNode{}
+	case "dropIndex":
+		return &DropIndexNode{}
+	case "addColumn":
+		return &AddColumnNode{}
+	case "renameColumn":
+		return &RenameColumnNode{}
+	case "alterColumn":
+		return &AlterColumnNode{}
+	case "dropColumn":
+		return &DropColumnNode{}
+	case "dropPrimaryKey":
+		return &DropPrimaryKeyNode{}
+	case "renameTable":
+		return &RenameTableNode{}
+	case "alterTableComment":
+		return &AlterTableCommentNode{}
+	case "sqlFile":
+		return &SqlFileNode{}
+	case "insert":
+		return &InsertNode{}
+	case "loadData":
+		return &LoadDataNode{}
+	case "loadUpdateData":
+		return &LoadUpdateDataNode{}
+	case "update":
+		return &UpdateNode{}
+	case "delete":
+		return &DeleteNode{}
+	case "sqlInline":
+		return &SqlInlineNode{}
+	case "transaction":
+		return &TransactionNode{}
+	case "savepoint":
+		return &SavepointNode{}
+	}
+	return nil
+}
+
+func (n *ChangeSetNode) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "id"}, Value: n.Id})
+	if n.Author != "" {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "author"}, Value: n.Author})
+	}
+	if n.OnFail != "" {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "onFail"}, Value: n.OnFail})
+	}
+	if n.RunInTransaction != "" {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "runInTransaction"}, Value: n.RunInTransaction})
+	}
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := encodeDDLs(encoder, n.DDLs); err != nil {
+		return err
+	}
+	if n.Rollback != nil {
+		if err := encoder.EncodeElement(n.Rollback, xml.StartElement{Name: xml.Name{Local: "rollback"}}); err != nil {
+			return err
+		}
+	}
+	return encoder.EncodeToken(start.End())
+}
+
+// RollbackNode 变更集的回滚操作，回滚部署时按定义顺序执行
+type RollbackNode struct {
+	DDLs []DDL
+}
+
+func (n *RollbackNode) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+
+		switch ele := token.(type) {
+		case xml.StartElement:
+			ddl := newDDLNode(ele.Name.Local)
+			if ddl == nil {
+				return New("invalid DDL element <%s> in rollback", ele.Name.Local)
+			}
+			if err = decoder.DecodeElement(ddl, &ele); err != nil {
+				return err
+			}
+			n.DDLs = append(n.DDLs, ddl)
+		case xml.EndElement:
+			if ele.Name.Local == start.Name.Local {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+func (n *RollbackNode) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := encodeDDLs(encoder, n.DDLs); err != nil {
+		return err
+	}
+	return encoder.EncodeToken(start.End())
+}
+
+func (n *RollbackNode) Execute(ctx context.Context, fly *Dbfly) error {
+	for _, ddl := range n.DDLs {
+		if err := ddl.Execute(ctx, fly); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeDDLs 按元素名称输出 DDL 节点
+func encodeDDLs(encoder *xml.Encoder, ddls []DDL) error {
+	for _, ddl := range ddls {
+		name := ddlElementName(ddl)
+		if name == "" {
+			return New("unsupported DDL node %T", ddl)
+		}
+		if err := encoder.EncodeElement(ddl, xml.StartElement{Name: xml.Name{Local: name}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ddlElementName 获取 DDL 节点对应的 XML 元素名称
+func ddlElementName(ddl DDL) string {
+	switch ddl.(type) {
+	case *CreateTableNode:
+		return "createTable"
+	case *CreateIndexNode:
+		return "createIndex"
+	case *CreatePrimaryKeyNode:
+		return "createPrimaryKey"
+	case *DropTableNode:
+		return "dropTable"
+	case *DropIndexNode:
+		return "dropIndex"
+	case *AddColumnNode:
+		return "addColumn"
+	case *RenameColumnNode:
+		return "renameColumn"
+	case *AlterColumnNode:
+		return "alterColumn"
+	case *DropColumnNode:
+		return "dropColumn"
+	case *DropPrimaryKeyNode:
+		return "dropPrimaryKey"
+	case *RenameTableNode:
+		return "renameTable"
+	case *AlterTableCommentNode:
+		return "alterTableComment"
+	case *SqlFileNode:
+		return "sqlFile"
+	case *InsertNode:
+		return "insert"
+	case *LoadDataNode:
+		return "loadData"
+	case *LoadUpdateDataNode:
+		return "loadUpdateData"
+	case *UpdateNode:
+		return "update"
+	case *DeleteNode:
+		return "delete"
+	case *SqlInlineNode:
+		return "sqlInline"
+	case *TransactionNode:
+		return "transaction"
+	case *SavepointNode:
+		return "savepoint"
+	}
+	return ""
+}
+
 // DataColumnNode DML列节点
 type DataColumnNode struct {
 	Name        string `xml:"name,attr"`
-	Value       string `xml:"value,attr"`
-	OriginValue string `xml:"originValue,attr"`
+	Value       string `xml:"value,attr,omitempty"`
+	OriginValue string `xml:"originValue,attr,omitempty"`
+	// 以下类型化的值作为绑定参数传递
+	ValueNumeric  string `xml:"valueNumeric,attr,omitempty"`
+	ValueBoolean  string `xml:"valueBoolean,attr,omitempty"`
+	ValueDate     string `xml:"valueDate,attr,omitempty"`
+	ValueNull     bool   `xml:"valueNull,attr,omitempty"`
+	ValueBlobFile string `xml:"valueBlobFile,attr,omitempty"`
+	ValueClobFile string `xml:"valueClobFile,attr,omitempty"`
+}
+
+// dateLayouts valueDate 支持的格式，未指定时区时使用本地时区
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+	"15:04:05",
+}
+
+// bindValue 解析类型化的值，bind 为 false 表示按字面量写入SQL，
+// 优先级依次为 originValue、valueNull、valueNumeric、valueBoolean、valueDate、valueBlobFile、valueClobFile、value
+func (n *DataColumnNode) bindValue(source Source) (value any, bind bool, err error) {
+	switch {
+	case n.OriginValue != "":
+		return nil, false, nil
+	case n.ValueNull:
+		return nil, true, nil
+	case n.ValueNumeric != "":
+		if i, err := strconv.ParseInt(n.ValueNumeric, 10, 64); err == nil {
+			return i, true, nil
+		}
+		if _, err = strconv.ParseFloat(n.ValueNumeric, 64); err != nil {
+			return nil, false, Wrap(err, "invalid numeric value %q of column %q", n.ValueNumeric, n.Name)
+		}
+		// 小数保持字符串形式，避免浮点数丢失精度
+		return n.ValueNumeric, true, nil
+	case n.ValueBoolean != "":
+		b, err := strconv.ParseBool(n.ValueBoolean)
+		if err != nil {
+			return nil, false, Wrap(err, "invalid boolean value %q of column %q", n.ValueBoolean, n.Name)
+		}
+		return b, true, nil
+	case n.ValueDate != "":
+		for _, layout := range dateLayouts {
+			if t, err := time.ParseInLocation(layout, n.ValueDate, time.Local); err == nil {
+				return t, true, nil
+			}
+		}
+		return nil, false, New("invalid date value %q of column %q", n.ValueDate, n.Name)
+	case n.ValueBlobFile != "":
+		content, err := source.Read(n.ValueBlobFile)
+		if err != nil {
+			return nil, false, Wrap(err, "failed to read blob file %q of column %q", n.ValueBlobFile, n.Name)
+		}
+		return content, true, nil
+	case n.ValueClobFile != "":
+		content, err := source.Read(n.ValueClobFile)
+		if err != nil {
+			return nil, false, Wrap(err, "failed to read clob file %q of column %q", n.ValueClobFile, n.Name)
+		}
+		return string(content), true, nil
+	}
+	return nil, false, nil
 }
 
 // DataRowNode DML行节点（批量插入）
@@ -637,6 +1194,7 @@ func (n *InsertNode) Execute(ctx context.Context, fly *Dbfly) error {
 		// 批量模式
 		columns := n.Rows[0].Columns
 		var builder strings.Builder
+		values := newValueWriter(&builder, fly)
 		builder.WriteString("INSERT INTO ")
 		quoter.MustQuoteTo(&builder, n.TableName)
 		builder.WriteString(" (")
@@ -656,13 +1214,15 @@ func (n *InsertNode) Execute(ctx context.Context, fly *Dbfly) error {
 				if i > 0 {
 					builder.WriteString(", ")
 				}
-				writeColumnValue(&builder, col)
+				if err := values.write(col); err != nil {
+					return err
+				}
 			}
 			builder.WriteString(")")
 		}
 		sql = builder.String()
 		fly.logger.Debug("execute insert into table %q, rows: %d", n.TableName, len(n.Rows))
-		_, err := fly.Execute(ctx, sql)
+		_, err := fly.Execute(ctx, sql, values.args...)
 		return err
 	}
 
@@ -671,6 +1231,7 @@ func (n *InsertNode) Execute(ctx context.Context, fly *Dbfly) error {
 		return nil
 	}
 	var builder strings.Builder
+	values := newValueWriter(&builder, fly)
 	builder.WriteString("INSERT INTO ")
 	quoter.MustQuoteTo(&builder, n.TableName)
 	builder.WriteString(" (")
@@ -685,12 +1246,14 @@ func (n *InsertNode) Execute(ctx context.Context, fly *Dbfly) error {
 		if i > 0 {
 			builder.WriteString(", ")
 		}
-		writeColumnValue(&builder, col)
+		if err := values.write(col); err != nil {
+			return err
+		}
 	}
 	builder.WriteString(")")
 	sql = builder.String()
 	fly.logger.Debug("execute insert into table %q, rows: 1", n.TableName)
-	_, err := fly.Execute(ctx, sql)
+	_, err := fly.Execute(ctx, sql, values.args...)
 	return err
 }
 
@@ -708,6 +1271,7 @@ func (n *UpdateNode) Execute(ctx context.Context, fly *Dbfly) error {
 	}
 	quoter := fly.Migratory().MetaData().Quoter()
 	var builder strings.Builder
+	values := newValueWriter(&builder, fly)
 	builder.WriteString("UPDATE ")
 	quoter.MustQuoteTo(&builder, n.TableName)
 	builder.WriteString(" SET ")
@@ -717,7 +1281,9 @@ func (n *UpdateNode) Execute(ctx context.Context, fly *Dbfly) error {
 		}
 		quoter.MustQuoteTo(&builder, col.Name)
 		builder.WriteString(" = ")
-		writeColumnValue(&builder, col)
+		if err := values.write(col); err != nil {
+			return err
+		}
 	}
 	if n.Where != "" {
 		builder.WriteString(" WHERE ")
@@ -725,7 +1291,7 @@ func (n *UpdateNode) Execute(ctx context.Context, fly *Dbfly) error {
 	}
 	sql := builder.String()
 	fly.logger.Debug("execute update table %q", n.TableName)
-	_, err := fly.Execute(ctx, sql)
+	_, err := fly.Execute(ctx, sql, values.args...)
 	return err
 }
 
@@ -766,11 +1332,8 @@ func (n *SqlInlineNode) Execute(ctx context.Context, fly *Dbfly) error {
 		return err
 	}
 	content := n.Default
-	for _, dbmsNode := range n.SqlDbms {
-		if dbmsNode.Dbms == fly.Migratory().MetaData().Dbms() {
-			content = dbmsNode.Content
-			break
-		}
+	if dbmsNode, ok := selectDbms(fly.Migratory().MetaData(), n.SqlDbms, func(d *SqlDbmsNode) (string, string) { return d.Dbms, d.MinVersion }); ok {
+		content = dbmsNode.Content
 	}
 	fly.logger.Debug("execute inline SQL")
 	return fly.Migratory().Script(ctx, fly.Driver(), content)
@@ -778,16 +1341,26 @@ func (n *SqlInlineNode) Execute(ctx context.Context, fly *Dbfly) error {
 
 // SqlDbmsNode SQL方言节点
 type SqlDbmsNode struct {
-	Dbms    string `xml:"dbms,attr"`
-	Content string `xml:",chardata"`
+	Dbms string `xml:"dbms,attr"`
+	// MinVersion 要求的最低数据库版本
+	MinVersion string `xml:"minVersion,attr,omitempty"`
+	Content    string `xml:",chardata"`
 }
 
-// TransactionNode 事务控制节点
+// TransactionNode 事务控制节点，数据库支持事务 DDL 时可以包含 DDL 操作
 type TransactionNode struct {
 	DMLs []DDL
 }
 
 func (n *TransactionNode) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	ddls, err := decodeTransactionDDLs(decoder, start)
+	n.DMLs = ddls
+	return err
+}
+
+// decodeTransactionDDLs 解析事务及保存点中的操作，嵌套的事务使用 savepoint
+func decodeTransactionDDLs(decoder *xml.Decoder, start xml.StartElement) ([]DDL, error) {
+	var ddls []DDL
 	for {
 		token, err := decoder.Token()
 		if err != nil {
@@ -798,34 +1371,73 @@ func (n *TransactionNode) UnmarshalXML(decoder *xml.Decoder, start xml.StartElem
 		case xml.StartElement:
 			name := ele.Name.Local
 			var ddl DDL
-			switch name {
-			case "insert":
-				ddl = &InsertNode{}
-			case "update":
-				ddl = &UpdateNode{}
-			case "delete":
-				ddl = &DeleteNode{}
-			case "sqlInline":
-				ddl = &SqlInlineNode{}
-			case "sqlFile":
-				ddl = &SqlFileNode{}
-			default:
-				return New("invalid DML element <%s> in transaction", name)
+			if name != "transaction" {
+				ddl = newDDLNode(name)
+			}
+			if ddl == nil {
+				return nil, New("invalid element <%s> in %s", name, start.Name.Local)
 			}
 			if err = decoder.DecodeElement(ddl, &ele); err != nil {
-				return err
+				return nil, err
 			}
-			n.DMLs = append(n.DMLs, ddl)
+			ddls = append(ddls, ddl)
 		case xml.EndElement:
 			if ele.Name.Local == start.Name.Local {
-				return nil
+				return ddls, nil
+			}
+		}
+	}
+	return ddls, nil
+}
+
+// isDMLNode 判断是否为数据操作节点
+func isDMLNode(ddl DDL) bool {
+	switch ddl.(type) {
+	case *InsertNode, *LoadDataNode, *LoadUpdateDataNode, *UpdateNode, *DeleteNode, *SqlInlineNode, *SqlFileNode:
+		return true
+	}
+	return false
+}
+
+// checkTransactionalDDL 事务中包含 DDL 时要求数据库支持事务 DDL，否则 DDL 会隐式提交事务
+func checkTransactionalDDL(fly *Dbfly, ddls []DDL) error {
+	migratory := fly.Migratory()
+	if migratory.SupportsTransactionalDDL() {
+		return nil
+	}
+	for _, ddl := range ddls {
+		if savepoint, ok := ddl.(*SavepointNode); ok {
+			if err := checkTransactionalDDL(fly, savepoint.DDLs); err != nil {
+				return err
 			}
+			continue
+		}
+		if !isDMLNode(ddl) {
+			return New("<%s> in transaction is not supported by %s, which does not support transactional DDL", ddlElementName(ddl), migratory.Name())
 		}
 	}
 	return nil
 }
 
+func (n *TransactionNode) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := encodeDDLs(encoder, n.DMLs); err != nil {
+		return err
+	}
+	return encoder.EncodeToken(start.End())
+}
+
 func (n *TransactionNode) Execute(ctx context.Context, fly *Dbfly) error {
+	if err := checkTransactionalDDL(fly, n.DMLs); err != nil {
+		return err
+	}
+	if fly.tx != nil {
+		// 已处于事务中（如 runInTransaction 的变更集），使用保存点
+		savepoint := &SavepointNode{DDLs: n.DMLs}
+		return savepoint.Execute(ctx, fly)
+	}
 	fly.logger.Debug("transaction begin, dml count: %d", len(n.DMLs))
 	tx, err := fly.driver.BeginTx(ctx)
 	if err != nil {
@@ -854,6 +1466,92 @@ func (n *TransactionNode) Execute(ctx context.Context, fly *Dbfly) error {
 	return nil
 }
 
+// SavepointNode 事务中的保存点，内部操作失败时回滚到保存点，可以嵌套
+type SavepointNode struct {
+	// Name 保存点名称，未指定时自动生成
+	Name string
+	// OnFail 失败处理策略：HALT（默认）回滚到保存点后返回错误，SKIP 回滚到保存点后继续执行
+	OnFail string
+	DDLs   []DDL
+}
+
+func (n *SavepointNode) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "name":
+			n.Name = attr.Value
+		case "onFail":
+			n.OnFail = attr.Value
+		}
+	}
+	if n.Name != "" && !isValidSavepointName(n.Name) {
+		return New("invalid savepoint name: %s (allowed: [a-zA-Z_][a-zA-Z0-9_]*)", n.Name)
+	}
+	if n.OnFail != "" && n.OnFail != "HALT" && n.OnFail != "SKIP" {
+		return New("invalid onFail of savepoint: %s (allowed: HALT, SKIP)", n.OnFail)
+	}
+	ddls, err := decodeTransactionDDLs(decoder, start)
+	n.DDLs = ddls
+	return err
+}
+
+// savepointNamePattern 保存点名称直接拼接在 SQL 中，仅允许普通标识符
+var savepointNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+func isValidSavepointName(name string) bool {
+	return savepointNamePattern.MatchString(name)
+}
+
+func (n *SavepointNode) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	if n.Name != "" {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "name"}, Value: n.Name})
+	}
+	if n.OnFail != "" {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "onFail"}, Value: n.OnFail})
+	}
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := encodeDDLs(encoder, n.DDLs); err != nil {
+		return err
+	}
+	return encoder.EncodeToken(start.End())
+}
+
+func (n *SavepointNode) Execute(ctx context.Context, fly *Dbfly) error {
+	parent := fly.tx
+	if parent == nil {
+		return New("savepoint must be inside a transaction")
+	}
+	name := n.Name
+	if name == "" {
+		name = nextSavepointName()
+	}
+	tx, err := newSavepoint(ctx, fly.migratory, parent, name)
+	if err != nil {
+		return err
+	}
+	fly.tx = tx
+	defer func() {
+		fly.tx = parent
+	}()
+	for _, ddl := range n.DDLs {
+		if err = ddl.Execute(ctx, fly); err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				fly.logger.Error("operation failed: %+v, rollback to savepoint %s also failed: %+v", err, name, rbErr)
+				return New("operation failed: %w, rollback to savepoint also failed: %w", err, rbErr)
+			}
+			if n.OnFail == "SKIP" {
+				fly.logger.Warn("rolled back to savepoint %s and continue, error: %+v", name, err)
+				return nil
+			}
+			fly.logger.Debug("rolled back to savepoint %s, error: %+v", name, err)
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
 // writeColumnValue 写入列值
 func writeColumnValue(builder *strings.Builder, col *DataColumnNode) {
 	if col.OriginValue != "" {
@@ -865,7 +1563,39 @@ func writeColumnValue(builder *strings.Builder, col *DataColumnNode) {
 	}
 }
 
+// valueWriter 写入列值并收集绑定参数，占位符统一使用 ?，由驱动按数据库改写
+type valueWriter struct {
+	builder *strings.Builder
+	source  Source
+	args    []any
+}
+
+func newValueWriter(builder *strings.Builder, fly *Dbfly) *valueWriter {
+	return &valueWriter{builder: builder, source: fly.Source()}
+}
+
+// write 类型化的值写入占位符并追加绑定参数，其余按字面量写入
+func (w *valueWriter) write(col *DataColumnNode) error {
+	value, bind, err := col.bindValue(w.source)
+	if err != nil {
+		return err
+	}
+	if !bind {
+		writeColumnValue(w.builder, col)
+		return nil
+	}
+	w.args = append(w.args, value)
+	w.builder.WriteString("?")
+	return nil
+}
+
 // IncludeNode 引用节点
 type IncludeNode struct {
 	File string `xml:"file,attr"`
 }
+
+// PropertyNode changelog 中定义的属性
+type PropertyNode struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
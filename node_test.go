@@ -1,8 +1,12 @@
 package dbfly
 
 import (
+	"context"
+	"encoding/xml"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 )
 
 func TestInsertNode_BatchMode_NoRedundantCheck(t *testing.T) {
@@ -79,3 +83,244 @@ func TestWriteColumnValue(t *testing.T) {
 		})
 	}
 }
+
+func TestInsertNode_TypedValues(t *testing.T) {
+	driver := &recordDriver{}
+	source := NewFSSource(fstest.MapFS{
+		"data/logo.png":  {Data: []byte{0x89, 0x50}},
+		"data/intro.txt": {Data: []byte("hello")},
+	})
+	fly := NewDbfly(NewPostgresMigratory(), NewPlaceholderDriver(driver, PlaceholderDollar), source)
+	node := &InsertNode{
+		TableName: "t_item",
+		Columns: []*DataColumnNode{
+			{Name: "id", ValueNumeric: "1"},
+			{Name: "price", ValueNumeric: "12.50"},
+			{Name: "enabled", ValueBoolean: "true"},
+			{Name: "created_at", ValueDate: "2024-05-01 10:30:00"},
+			{Name: "remark", ValueNull: true},
+			{Name: "logo", ValueBlobFile: "data/logo.png"},
+			{Name: "intro", ValueClobFile: "data/intro.txt"},
+			{Name: "name", Value: "it's"},
+			{Name: "updated_at", OriginValue: "NOW()"},
+		},
+	}
+	if err := node.Execute(context.Background(), fly); err != nil {
+		t.Fatal(err)
+	}
+	want := `INSERT INTO "t_item" ("id", "price", "enabled", "created_at", "remark", "logo", "intro", "name", "updated_at") ` +
+		`VALUES ($1, $2, $3, $4, $5, $6, $7, 'it''s', NOW())`
+	if len(driver.sqls) != 1 || driver.sqls[0] != want {
+		t.Fatalf("Execute() sql = %q, want %q", driver.sqls, want)
+	}
+	args := driver.args[0]
+	createdAt := time.Date(2024, 5, 1, 10, 30, 0, 0, time.Local)
+	if len(args) != 7 || args[0] != int64(1) || args[1] != "12.50" || args[2] != true ||
+		!args[3].(time.Time).Equal(createdAt) || args[4] != nil || string(args[5].([]byte)) != "\x89P" || args[6] != "hello" {
+		t.Errorf("Execute() args = %#v", args)
+	}
+}
+
+func TestUpdateNode_TypedValuesPlaceholder(t *testing.T) {
+	tests := []struct {
+		migratory Migratory
+		want      string
+	}{
+		{NewMysqlMigratory(), "UPDATE `t_item` SET `price` = ?, `enabled` = ? WHERE id = 1"},
+		{NewOracleMigratory(), `UPDATE "t_item" SET "price" = :1, "enabled" = :2 WHERE id = 1`},
+		{NewSqlServerMigratory(), "UPDATE [t_item] SET [price] = @p1, [enabled] = @p2 WHERE id = 1"},
+	}
+	for _, tt := range tests {
+		driver := &recordDriver{}
+		node := &UpdateNode{
+			TableName: "t_item",
+			Columns:   []*DataColumnNode{{Name: "price", ValueNumeric: "3"}, {Name: "enabled", ValueBoolean: "0"}},
+			Where:     "id = 1",
+		}
+		placeholderDriver := NewPlaceholderDriver(driver, tt.migratory.MetaData().Placeholder())
+		if err := node.Execute(context.Background(), NewDbfly(tt.migratory, placeholderDriver, nil)); err != nil {
+			t.Fatal(err)
+		}
+		if len(driver.sqls) != 1 || driver.sqls[0] != tt.want {
+			t.Errorf("Execute() sql = %q, want %q", driver.sqls, tt.want)
+		}
+	}
+
+	node := &UpdateNode{TableName: "t_item", Columns: []*DataColumnNode{{Name: "price", ValueNumeric: "abc"}}}
+	if err := node.Execute(context.Background(), NewDbfly(NewMysqlMigratory(), &recordDriver{}, nil)); err == nil {
+		t.Error("invalid numeric value should fail")
+	}
+}
+
+func TestDbmsVersionNode_Check(t *testing.T) {
+	metaData := &mockMetaData{dbms: "Oracle", version: "19.3.0.0.0"}
+	migratory := NewDefaultMigratory("mock", metaData)
+	fly := NewDbfly(&migratory, &SqlDriver{}, nil)
+	tests := []struct {
+		node *DbmsVersionNode
+		want bool
+	}{
+		{&DbmsVersionNode{Min: "12.1"}, true},
+		{&DbmsVersionNode{Name: "Oracle", Min: "12.1"}, true},
+		{&DbmsVersionNode{Name: "MySQL", Min: "12.1"}, false},
+		{&DbmsVersionNode{Max: "18"}, false},
+		{&DbmsVersionNode{Max: "18", Not: true}, true},
+	}
+	for _, tt := range tests {
+		got, err := tt.node.Check(context.Background(), fly)
+		if err != nil || got != tt.want {
+			t.Errorf("%+v Check() = %v, %v, want %v", *tt.node, got, err, tt.want)
+		}
+	}
+}
+
+func TestConditionsNode_Nested(t *testing.T) {
+	migratory := NewDefaultMigratory("mock", newMockMetaData())
+	fly := NewDbfly(&migratory, &SqlDriver{}, nil)
+	tests := []struct {
+		name, xml string
+		want      bool
+	}{
+		{"and/or/not", `<conditions>
+    <and>
+        <tableExists tableName="users"/>
+        <or>
+            <dbms name="MySQL"/>
+            <dbms name="Mock"/>
+        </or>
+        <not>
+            <columnExists tableName="users" columnName="email"/>
+        </not>
+    </and>
+</conditions>`, true},
+		{"not fails", `<conditions>
+    <and>
+        <tableExists tableName="users"/>
+        <not><columnExists tableName="users" columnName="name"/></not>
+    </and>
+</conditions>`, false},
+		{"or of groups", `<conditions>
+    <condition><dbms name="MySQL"/></condition>
+    <or not="true"><tableExists tableName="missing"/></or>
+</conditions>`, true},
+		{"nested in group", `<conditions>
+    <condition not="true">
+        <tableExists tableName="users"/>
+        <or><dbms name="MySQL"/><dbms name="Oracle"/></or>
+    </condition>
+</conditions>`, true},
+	}
+	for _, tt := range tests {
+		node := &ConditionsNode{}
+		if err := xml.Unmarshal([]byte(tt.xml), node); err != nil {
+			t.Fatalf("%s: unmarshal error = %v", tt.name, err)
+		}
+		got, err := node.Check(context.Background(), fly)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: Check() = %v, %v, want %v", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestConditionsNode_InvalidElement(t *testing.T) {
+	for _, str := range []string{
+		`<conditions><tableExists tableName="users"/></conditions>`,
+		`<conditions><and><unknown/></and></conditions>`,
+		`<conditions><not></not></conditions>`,
+	} {
+		if err := xml.Unmarshal([]byte(str), &ConditionsNode{}); err == nil {
+			t.Errorf("unmarshal %s should fail", str)
+		}
+	}
+}
+
+func TestCompareValue(t *testing.T) {
+	tests := []struct {
+		actual, operator, expected string
+		want                       bool
+	}{
+		{"10", ">", "9", true},
+		{"10", "", "10.0", true},
+		{"3", ">=", "3", true},
+		{"3", "!=", "3", false},
+		{"abc", "=", "abc", true},
+		{"abc", "<", "abd", true},
+	}
+	for _, tt := range tests {
+		got, err := compareValue(tt.actual, tt.operator, tt.expected)
+		if err != nil || got != tt.want {
+			t.Errorf("compareValue(%q, %q, %q) = %v, %v, want %v", tt.actual, tt.operator, tt.expected, got, err, tt.want)
+		}
+	}
+	if _, err := compareValue("1", "~", "1"); err == nil {
+		t.Error("invalid operator should fail")
+	}
+}
+
+func TestConditions_PropertyAndCheck(t *testing.T) {
+	t.Setenv("DBFLY_TEST_ENV", "prod")
+	migratory := NewDefaultMigratory("mock", newMockMetaData())
+	fly := NewDbfly(&migratory, &SqlDriver{}, nil,
+		WithRecorder(&mockRecorder{executed: map[string]bool{"init": true}}),
+		WithProperty("region", "cn"),
+		WithContexts("test", "dev"),
+		WithCheck("always", func(context.Context, *Dbfly) (bool, error) { return true, nil }),
+	)
+	fly.changelogProperties = map[string]string{"region": "us", "tenant": "a"}
+	tests := []struct {
+		condition Condition
+		want      bool
+	}{
+		{&PropertyEqualsNode{Name: "region", Value: "cn"}, true},
+		{&PropertyEqualsNode{Name: "tenant", Value: "a"}, true},
+		{&PropertyEqualsNode{Name: "DBFLY_TEST_ENV", Value: "prod"}, true},
+		{&PropertyEqualsNode{Name: "missing", Value: ""}, false},
+		{&PropertyDefinedNode{Name: "missing", Not: true}, true},
+		{&ChangeSetExecutedNode{Id: "init"}, true},
+		{&ChangeSetExecutedNode{Id: "other"}, false},
+		{&ContextNode{Name: "prod, dev"}, true},
+		{&ContextNode{Name: "prod"}, false},
+		{&ViewExistsNode{ViewName: "V_USERS"}, true},
+		{&ViewExistsNode{ViewName: "users"}, false},
+		{&GoCheckNode{Name: "always", Not: true}, false},
+	}
+	for _, tt := range tests {
+		got, err := tt.condition.Check(context.Background(), fly)
+		if err != nil || got != tt.want {
+			t.Errorf("%T%+v Check() = %v, %v, want %v", tt.condition, tt.condition, got, err, tt.want)
+		}
+	}
+	if _, err := (&GoCheckNode{Name: "missing"}).Check(context.Background(), fly); err == nil {
+		t.Error("unregistered go check should fail")
+	}
+}
+
+func TestParseChangelogProperty(t *testing.T) {
+	fly := &Dbfly{}
+	content := `<dbfly><property name="region" value="cn"/><property name="region" value="us"/></dbfly>`
+	if _, err := fly.parseXmlContent("dbfly.xml", []byte(content), map[string]bool{}); err != nil {
+		t.Fatal(err)
+	}
+	if value, ok := fly.Property("region"); !ok || value != "cn" {
+		t.Errorf("Property(region) = %q, %v, want cn", value, ok)
+	}
+}
+
+func TestParseEntrypoint_ResetProperty(t *testing.T) {
+	files := fstest.MapFS{defaultEntrypoint: {Data: []byte(`<dbfly><property name="region" value="cn"/></dbfly>`)}}
+	fly := NewDbfly(NewSqliteMigratory(), &recordDriver{}, NewFSSource(files))
+	if _, err := fly.parseEntrypoint(); err != nil {
+		t.Fatal(err)
+	}
+	// changelog 修改后重新解析，属性取新的定义
+	files[defaultEntrypoint] = &fstest.MapFile{Data: []byte(`<dbfly><property name="tenant" value="a"/></dbfly>`)}
+	if _, err := fly.parseEntrypoint(); err != nil {
+		t.Fatal(err)
+	}
+	if value, ok := fly.Property("region"); ok {
+		t.Errorf("Property(region) = %q after reparse, want undefined", value)
+	}
+	if value, ok := fly.Property("tenant"); !ok || value != "a" {
+		t.Errorf("Property(tenant) = %q, %v, want a", value, ok)
+	}
+}
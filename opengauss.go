@@ -0,0 +1,195 @@
+package dbfly
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// OpenGaussDatabaseMetaData openGauss元数据实现，系统表与 PostgreSQL 兼容
+type OpenGaussDatabaseMetaData struct {
+	*PostgresDatabaseMetaData
+	dbms string
+}
+
+func NewOpenGaussDatabaseMetaData() *OpenGaussDatabaseMetaData {
+	return &OpenGaussDatabaseMetaData{
+		PostgresDatabaseMetaData: NewPostgresDatabaseMetaData(),
+		dbms:                     "openGauss",
+	}
+}
+
+// NewGaussDBDatabaseMetaData 创建GaussDB元数据实现，GaussDB 基于 openGauss 内核
+func NewGaussDBDatabaseMetaData() *OpenGaussDatabaseMetaData {
+	return &OpenGaussDatabaseMetaData{
+		PostgresDatabaseMetaData: NewPostgresDatabaseMetaData(),
+		dbms:                     "GaussDB",
+	}
+}
+
+func (m *OpenGaussDatabaseMetaData) Dbms() string {
+	return m.dbms
+}
+
+func (m *OpenGaussDatabaseMetaData) DataType(str string) string {
+	switch str {
+	case Clob:
+		return "CLOB"
+	case Blob:
+		return "BLOB"
+	}
+	return m.PostgresDatabaseMetaData.DataType(str)
+}
+
+// Version openGauss 的 server_version 为兼容的 PostgreSQL 版本，使用 opengauss_version() 查询内核版本
+func (m *OpenGaussDatabaseMetaData) Version(ctx context.Context, driver Driver) (string, error) {
+	if m.dbms != "openGauss" {
+		return m.PostgresDatabaseMetaData.Version(ctx, driver)
+	}
+	return m.queryVersion(ctx, driver, "SELECT opengauss_version()", nil)
+}
+
+// GetForeignKeys openGauss 基于 PostgreSQL 9.2，不支持 LATERAL 和 WITH ORDINALITY，使用 generate_series 展开外键列
+func (m *OpenGaussDatabaseMetaData) GetForeignKeys(ctx context.Context, driver Driver, tableName string) ([]*ForeignKey, error) {
+	schema, err := m.getSchema(ctx, driver)
+	if err != nil {
+		return nil, err
+	}
+	sql := `SELECT con.conname AS "FK_NAME",
+       a.attname    AS "COLUMN_NAME",
+       rc.relname   AS "REF_TABLE_NAME",
+       ra.attname   AS "REF_COLUMN_NAME",
+       s.i          AS "ORDINAL_POSITION"
+FROM pg_catalog.pg_constraint con
+         JOIN pg_catalog.pg_class c ON (c.oid = con.conrelid)
+         JOIN pg_catalog.pg_namespace n ON (n.oid = c.relnamespace)
+         JOIN pg_catalog.pg_class rc ON (rc.oid = con.confrelid)
+         JOIN generate_series(1, 32) AS s(i) ON (s.i <= array_length(con.conkey, 1))
+         JOIN pg_catalog.pg_attribute a ON (a.attrelid = con.conrelid AND a.attnum = con.conkey[s.i])
+         JOIN pg_catalog.pg_attribute ra ON (ra.attrelid = con.confrelid AND ra.attnum = con.confkey[s.i])
+WHERE con.contype = 'f'
+  AND n.nspname = ?
+  AND c.relname = ?
+ORDER BY "FK_NAME", "ORDINAL_POSITION"`
+	return doGetSlices[ForeignKey](ctx, driver, func(rows Rows, t *ForeignKey) error {
+		return rows.Scan(&t.Name, &t.ColumnName, &t.RefTableName, &t.RefColumnName, &t.Ordinal)
+	}, sql, schema, tableName)
+}
+
+// OpenGaussMigratory openGauss迁移实现
+type OpenGaussMigratory struct {
+	DefaultMigratory
+}
+
+// NewOpenGaussMigratory 创建一个openGauss迁移实现实例
+func NewOpenGaussMigratory() Migratory {
+	return &OpenGaussMigratory{
+		DefaultMigratory: NewDefaultMigratory("opengauss", NewOpenGaussDatabaseMetaData()),
+	}
+}
+
+// NewGaussDBMigratory 创建一个GaussDB迁移实现实例
+func NewGaussDBMigratory() Migratory {
+	return &OpenGaussMigratory{
+		DefaultMigratory: NewDefaultMigratory("gaussdb", NewGaussDBDatabaseMetaData()),
+	}
+}
+
+// SupportsTransactionalDDL openGauss、GaussDB 的 DDL 可以在事务中执行并回滚
+func (m *OpenGaussMigratory) SupportsTransactionalDDL() bool {
+	return true
+}
+
+func (m *OpenGaussMigratory) AlterColumn(ctx context.Context, driver Driver, tableName string, columnName string, column *AlterColumnColumnNode, _ *AttributesNode) error {
+	return alterPostgresStyleColumn(ctx, &m.DefaultMigratory, driver, tableName, columnName, column)
+}
+
+func (m *OpenGaussMigratory) DropPrimaryKey(ctx context.Context, driver Driver, tableName string, _ *AttributesNode) error {
+	return dropPrimaryKeyConstraint(ctx, &m.DefaultMigratory, driver, tableName)
+}
+
+// alterPostgresStyleColumn 以 PostgreSQL 的 ALTER COLUMN 子句分别修改类型、默认值、非空约束和说明
+func alterPostgresStyleColumn(ctx context.Context, m *DefaultMigratory, driver Driver, tableName string, columnName string, column *AlterColumnColumnNode) error {
+	dbmsNode := selectColumnDbms(m.MetaData(), column.ColumnDbms)
+	var dataType, defaultValue string
+	if dbmsNode != nil {
+		dataType = dbmsNode.DataType
+		if dbmsNode.DefaultOriginValue != "" {
+			defaultValue = dbmsNode.DefaultOriginValue
+		} else if dbmsNode.DefaultValue != "" {
+			defaultValue = fmt.Sprintf("'%s'", strings.ReplaceAll(dbmsNode.DefaultValue, "'", "''"))
+		}
+	} else {
+		dataType = columnType(column.DataType, m.DataType(column.DataType), column.MaxLength, column.NumericScale)
+		if column.DefaultOriginValue != "" {
+			defaultValue = column.DefaultOriginValue
+		} else if column.DefaultValue != "" {
+			defaultValue = fmt.Sprintf("'%s'", strings.ReplaceAll(column.DefaultValue, "'", "''"))
+		}
+	}
+
+	prefix := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s", m.Quote(tableName), m.Quote(columnName))
+	sqls := []string{fmt.Sprintf("%s TYPE %s", prefix, dataType)}
+	if defaultValue != "" {
+		sqls = append(sqls, fmt.Sprintf("%s SET DEFAULT %s", prefix, defaultValue))
+	} else {
+		sqls = append(sqls, prefix+" DROP DEFAULT")
+	}
+	if column.Notnull {
+		sqls = append(sqls, prefix+" SET NOT NULL")
+	} else {
+		sqls = append(sqls, prefix+" DROP NOT NULL")
+	}
+	if column.Unique {
+		// 已存在唯一约束时重复添加会产生多余的约束
+		exists, err := existsUniqueIndex(ctx, m.MetaData(), driver, tableName, columnName)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s ADD UNIQUE (%s)", m.Quote(tableName), m.Quote(columnName)))
+		}
+	}
+	if column.Comment != "" {
+		sqls = append(sqls, fmt.Sprintf("COMMENT ON COLUMN %s.%s IS '%s'", m.Quote(tableName), m.Quote(columnName), ReplaceComment(column.Comment)))
+	}
+	for _, sql := range sqls {
+		if _, err := driver.Execute(ctx, sql); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// existsUniqueIndex 列上是否已存在仅包含该列的唯一索引
+func existsUniqueIndex(ctx context.Context, metaData DatabaseMetaData, driver Driver, tableName string, columnName string) (bool, error) {
+	indexes, err := metaData.GetIndexes(ctx, driver, tableName)
+	if err != nil {
+		return false, err
+	}
+	columns := make(map[string][]string)
+	for _, index := range indexes {
+		if index.Unique {
+			columns[index.Name] = append(columns[index.Name], index.ColumnName)
+		}
+	}
+	for _, names := range columns {
+		if len(names) == 1 && names[0] == columnName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// dropPrimaryKeyConstraint 查询主键约束名称后通过 DROP CONSTRAINT 删除主键
+func dropPrimaryKeyConstraint(ctx context.Context, m *DefaultMigratory, driver Driver, tableName string) error {
+	primaryKeys, err := m.MetaData().GetPrimaryKeys(ctx, driver, tableName)
+	if err != nil {
+		return err
+	}
+	if len(primaryKeys) == 0 {
+		return New("primary key of table %s not found", tableName)
+	}
+	_, err = driver.Execute(ctx, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", m.Quote(tableName), m.Quote(primaryKeys[0].Name)))
+	return err
+}
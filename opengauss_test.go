@@ -0,0 +1,27 @@
+package dbfly
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAlterPostgresStyleColumn_Unique(t *testing.T) {
+	metaData := newMockMetaData()
+	metaData.indexes["users"] = append(metaData.indexes["users"],
+		&Index{Name: "uk_name_id", ColumnName: "name", Unique: true, Ordinal: 1},
+		&Index{Name: "uk_name_id", ColumnName: "id", Unique: true, Ordinal: 2})
+	m := NewDefaultMigratory("mock", metaData)
+	column := &AlterColumnColumnNode{DataType: Varchar, MaxLength: 100, Unique: true}
+	// id 已有单列唯一索引，name 仅在联合唯一索引中
+	for columnName, want := range map[string]bool{"id": false, "name": true} {
+		driver := &recordDriver{}
+		if err := alterPostgresStyleColumn(context.Background(), &m, driver, "users", columnName, column); err != nil {
+			t.Fatal(err)
+		}
+		added := strings.Contains(strings.Join(driver.sqls, "\n"), "ADD UNIQUE")
+		if added != want {
+			t.Errorf("column %s: added = %v, want %v: %q", columnName, added, want, driver.sqls)
+		}
+	}
+}
@@ -2,9 +2,12 @@ package dbfly
 
 import (
 	"context"
+	sql2 "database/sql"
+	"strings"
 )
 
 type OracleDatabaseMetaData struct {
+	versionHolder
 	quoter *Quoter
 }
 
@@ -18,6 +21,10 @@ func (m *OracleDatabaseMetaData) Dbms() string {
 	return "Oracle"
 }
 
+func (m *OracleDatabaseMetaData) Placeholder() PlaceholderStyle {
+	return PlaceholderColon
+}
+
 func (m *OracleDatabaseMetaData) DataType(str string) string {
 	switch str {
 	case Varchar:
@@ -54,12 +61,19 @@ func (m *OracleDatabaseMetaData) DataType(str string) string {
 
 func (m *OracleDatabaseMetaData) GetTables(ctx context.Context, driver Driver) ([]*Table, error) {
 	sql := `SELECT o.object_name AS table_name,
-       o.object_type AS table_type
+       o.object_type AS table_type,
+       c.comments    AS table_comment
 FROM USER_OBJECTS o
+         LEFT JOIN USER_TAB_COMMENTS c ON c.table_name = o.object_name
 WHERE o.object_type IN ('TABLE', 'VIEW')
 ORDER BY table_type, table_name`
 	return doGetSlices[Table](ctx, driver, func(rows Rows, t *Table) error {
-		return rows.Scan(&t.Name, &t.TableType)
+		var comment sql2.NullString
+		if err := rows.Scan(&t.Name, &t.TableType, &comment); err != nil {
+			return err
+		}
+		t.Comment = comment.String
+		return nil
 	}, sql)
 }
 
@@ -68,17 +82,62 @@ func (m *OracleDatabaseMetaData) GetColumns(ctx context.Context, driver Driver,
 	return doGetScalars[string](ctx, driver, sql, tableName)
 }
 
+func (m *OracleDatabaseMetaData) GetColumnDetails(ctx context.Context, driver Driver, tableName string) ([]*Column, error) {
+	sql := `SELECT t.column_name,
+       t.data_type,
+       t.char_length,
+       t.data_precision,
+       t.data_scale,
+       t.nullable,
+       t.data_default,
+       c.comments,
+       t.column_id
+FROM USER_TAB_COLUMNS t
+         LEFT JOIN USER_COL_COMMENTS c
+                   ON c.table_name = t.table_name AND c.column_name = t.column_name
+WHERE t.table_name = ?
+ORDER BY t.column_id`
+	return doGetSlices[Column](ctx, driver, func(rows Rows, t *Column) error {
+		var (
+			maxLength    sql2.NullInt64
+			precision    sql2.NullInt64
+			scale        sql2.NullInt64
+			nullable     string
+			defaultValue sql2.NullString
+			comment      sql2.NullString
+		)
+		if err := rows.Scan(&t.Name, &t.DataType, &maxLength, &precision, &scale, &nullable, &defaultValue, &comment, &t.Ordinal); err != nil {
+			return err
+		}
+		t.DataType = strings.ToUpper(t.DataType)
+		t.MaxLength = int(maxLength.Int64)
+		t.NumericPrecision = int(precision.Int64)
+		t.NumericScale = int(scale.Int64)
+		t.Nullable = strings.ToUpper(nullable) == "Y"
+		t.DefaultValue = strings.TrimSpace(defaultValue.String)
+		t.Comment = comment.String
+		return nil
+	}, sql, tableName)
+}
+
 func (m *OracleDatabaseMetaData) GetIndexes(ctx context.Context, driver Driver, tableName string) ([]*Index, error) {
 	sql := `select i.index_name,
-       c.column_name
+       c.column_name,
+       i.uniqueness,
+       c.column_position
 from USER_INDEXES i,
      USER_IND_COLUMNS c
 where i.table_name = ?
   and i.index_name = c.index_name
   and i.table_name = c.table_name
-order by index_name`
+order by index_name, column_position`
 	return doGetSlices[Index](ctx, driver, func(rows Rows, t *Index) error {
-		return rows.Scan(&t.Name, &t.ColumnName)
+		var uniqueness string
+		if err := rows.Scan(&t.Name, &t.ColumnName, &uniqueness, &t.Ordinal); err != nil {
+			return err
+		}
+		t.Unique = strings.ToUpper(uniqueness) == "UNIQUE"
+		return nil
 	}, sql, tableName)
 }
 
@@ -95,6 +154,27 @@ WHERE c.constraint_type = 'P'
 	}, sql, tableName)
 }
 
+func (m *OracleDatabaseMetaData) GetForeignKeys(ctx context.Context, driver Driver, tableName string) ([]*ForeignKey, error) {
+	sql := `SELECT c.constraint_name AS FK_NAME,
+       cc.column_name    AS COLUMN_NAME,
+       rc.table_name     AS REF_TABLE_NAME,
+       rcc.column_name   AS REF_COLUMN_NAME,
+       cc.position       AS ORDINAL_POSITION
+FROM USER_CONSTRAINTS c
+         JOIN USER_CONS_COLUMNS cc
+              ON c.constraint_name = cc.constraint_name
+         JOIN USER_CONSTRAINTS rc
+              ON c.r_constraint_name = rc.constraint_name
+         JOIN USER_CONS_COLUMNS rcc
+              ON rc.constraint_name = rcc.constraint_name AND cc.position = rcc.position
+WHERE c.constraint_type = 'R'
+  AND c.table_name = ?
+ORDER BY FK_NAME, ORDINAL_POSITION`
+	return doGetSlices[ForeignKey](ctx, driver, func(rows Rows, t *ForeignKey) error {
+		return rows.Scan(&t.Name, &t.ColumnName, &t.RefTableName, &t.RefColumnName, &t.Ordinal)
+	}, sql, tableName)
+}
+
 func (m *OracleDatabaseMetaData) ExistsTable(ctx context.Context, driver Driver, tableName string) (bool, string, error) {
 	return ExistsTable(m.GetTables, ctx, driver, tableName)
 }
@@ -111,6 +191,10 @@ func (m *OracleDatabaseMetaData) ExistsPrimaryKey(ctx context.Context, driver Dr
 	return ExistsPrimaryKey(m.GetTables, m.GetPrimaryKeys, ctx, driver, tableName)
 }
 
+func (m *OracleDatabaseMetaData) Version(ctx context.Context, driver Driver) (string, error) {
+	return m.queryVersion(ctx, driver, "SELECT VERSION FROM PRODUCT_COMPONENT_VERSION WHERE PRODUCT LIKE 'Oracle%' AND ROWNUM = 1", nil)
+}
+
 func (m *OracleDatabaseMetaData) Quoter() *Quoter {
 	return m.quoter
 }
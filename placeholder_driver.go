@@ -0,0 +1,185 @@
+package dbfly
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// PlaceholderStyle 绑定参数占位符风格
+type PlaceholderStyle int
+
+const (
+	// PlaceholderQuestion ?，MySQL、SQLite、ClickHouse、达梦等
+	PlaceholderQuestion PlaceholderStyle = iota
+	// PlaceholderDollar $1，lib/pq、pgx 等 PostgreSQL 系驱动
+	PlaceholderDollar
+	// PlaceholderColon :1，godror 等 Oracle 驱动
+	PlaceholderColon
+	// PlaceholderAtP @p1，go-mssqldb
+	PlaceholderAtP
+)
+
+// Placeholder 返回第 index 个（从 1 开始）绑定参数的占位符
+func (s PlaceholderStyle) Placeholder(index int) string {
+	switch s {
+	case PlaceholderDollar:
+		return "$" + strconv.Itoa(index)
+	case PlaceholderColon:
+		return ":" + strconv.Itoa(index)
+	case PlaceholderAtP:
+		return "@p" + strconv.Itoa(index)
+	}
+	return "?"
+}
+
+// RewritePlaceholders 将SQL中的 ? 占位符改写为指定风格，跳过字符串、带引号的标识符和注释中的 ?
+func RewritePlaceholders(sql string, style PlaceholderStyle) string {
+	if style == PlaceholderQuestion || !strings.Contains(sql, "?") {
+		return sql
+	}
+	var builder strings.Builder
+	builder.Grow(len(sql) + 8)
+	index := 0
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := skipQuoted(sql, i, c)
+			builder.WriteString(sql[i:end])
+			i = end - 1
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i
+			}
+			builder.WriteString(sql[i : i+end])
+			i += end - 1
+		case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				end = len(sql)
+			} else {
+				end += i + 4
+			}
+			builder.WriteString(sql[i:end])
+			i = end - 1
+		case c == '?':
+			index++
+			builder.WriteString(style.Placeholder(index))
+		default:
+			builder.WriteByte(c)
+		}
+	}
+	return builder.String()
+}
+
+// skipQuoted 返回从 start 开始的引号内容结束后的位置，连续两个引号视为转义
+func skipQuoted(sql string, start int, quote byte) int {
+	for i := start + 1; i < len(sql); i++ {
+		if sql[i] != quote {
+			continue
+		}
+		if i+1 < len(sql) && sql[i+1] == quote {
+			i++
+			continue
+		}
+		return i + 1
+	}
+	return len(sql)
+}
+
+// PlaceholderDriver 包装 Driver，将 ? 占位符改写为数据库驱动要求的风格，
+// 仅在带有绑定参数时改写，避免影响脚本中的 ? 运算符
+type PlaceholderDriver struct {
+	driver Driver
+	style  PlaceholderStyle
+}
+
+// NewPlaceholderDriver 创建改写占位符的 Driver 包装器
+func NewPlaceholderDriver(driver Driver, style PlaceholderStyle) *PlaceholderDriver {
+	return &PlaceholderDriver{driver: driver, style: style}
+}
+
+func (d *PlaceholderDriver) Execute(ctx context.Context, sql string, args ...any) (sql.Result, error) {
+	return d.driver.Execute(ctx, d.rewrite(sql, args), args...)
+}
+
+func (d *PlaceholderDriver) Query(ctx context.Context, sql string, args ...any) (Rows, error) {
+	return d.driver.Query(ctx, d.rewrite(sql, args), args...)
+}
+
+func (d *PlaceholderDriver) BeginTx(ctx context.Context) (Tx, error) {
+	tx, err := d.driver.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &placeholderTx{tx: tx, style: d.style}, nil
+}
+
+// Session 获取独占连接，连接上执行的SQL同样改写占位符
+func (d *PlaceholderDriver) Session(ctx context.Context) (Session, error) {
+	session, err := openSession(ctx, d.driver)
+	if err != nil {
+		return nil, err
+	}
+	return &placeholderSession{PlaceholderDriver: NewPlaceholderDriver(session, d.style), session: session}, nil
+}
+
+// placeholderSession 包装 Session，改写独占连接上SQL的占位符
+type placeholderSession struct {
+	*PlaceholderDriver
+	session Session
+}
+
+func (s *placeholderSession) Close() error {
+	return s.session.Close()
+}
+
+func (d *PlaceholderDriver) rewrite(sql string, args []any) string {
+	if len(args) == 0 {
+		return sql
+	}
+	return RewritePlaceholders(sql, d.style)
+}
+
+// placeholderTx 包装 Tx，改写事务内SQL的占位符
+type placeholderTx struct {
+	tx    Tx
+	style PlaceholderStyle
+}
+
+func (t *placeholderTx) Execute(ctx context.Context, sql string, args ...any) (sql.Result, error) {
+	if len(args) > 0 {
+		sql = RewritePlaceholders(sql, t.style)
+	}
+	return t.tx.Execute(ctx, sql, args...)
+}
+
+func (t *placeholderTx) Query(ctx context.Context, sql string, args ...any) (Rows, error) {
+	if len(args) > 0 {
+		sql = RewritePlaceholders(sql, t.style)
+	}
+	return t.tx.Query(ctx, sql, args...)
+}
+
+func (t *placeholderTx) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *placeholderTx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+// bindDriver 原生sql驱动直接把SQL交给数据库驱动，按元数据的占位符风格包装；
+// 其他框架的驱动自行处理占位符，保持不变
+func bindDriver(driver Driver, metaData DatabaseMetaData) Driver {
+	if _, ok := driver.(*SqlDriver); !ok {
+		return driver
+	}
+	if style := metaData.Placeholder(); style != PlaceholderQuestion {
+		return NewPlaceholderDriver(driver, style)
+	}
+	return driver
+}
@@ -0,0 +1,55 @@
+package dbfly
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestRewritePlaceholders(t *testing.T) {
+	tests := []struct {
+		sql   string
+		style PlaceholderStyle
+		want  string
+	}{
+		{"SELECT * FROM t WHERE a = ? AND b = ?", PlaceholderQuestion, "SELECT * FROM t WHERE a = ? AND b = ?"},
+		{"SELECT * FROM t WHERE a = ? AND b = ?", PlaceholderDollar, "SELECT * FROM t WHERE a = $1 AND b = $2"},
+		{"UPDATE t SET a = ? WHERE b = ?", PlaceholderColon, "UPDATE t SET a = :1 WHERE b = :2"},
+		{"DELETE FROM t WHERE a = ?", PlaceholderAtP, "DELETE FROM t WHERE a = @p1"},
+		{"SELECT 'a?''?' AS \"b?\", `c?` FROM t WHERE d = ?", PlaceholderDollar, "SELECT 'a?''?' AS \"b?\", `c?` FROM t WHERE d = $1"},
+		{"SELECT a -- why?\nFROM t /* what? */ WHERE b = ?", PlaceholderDollar, "SELECT a -- why?\nFROM t /* what? */ WHERE b = $1"},
+		{"SELECT 'unterminated ?", PlaceholderDollar, "SELECT 'unterminated ?"},
+	}
+	for _, tt := range tests {
+		if got := RewritePlaceholders(tt.sql, tt.style); got != tt.want {
+			t.Errorf("RewritePlaceholders(%q, %d) = %q, want %q", tt.sql, tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestPlaceholderDriver(t *testing.T) {
+	driver := &recordDriver{}
+	placeholderDriver := NewPlaceholderDriver(driver, PlaceholderDollar)
+	ctx := context.Background()
+	_, _ = placeholderDriver.Execute(ctx, "UPDATE t SET a = ? WHERE b = ?", 1, 2)
+	// 没有绑定参数时不改写，避免影响 PostgreSQL 的 ? 运算符
+	_, _ = placeholderDriver.Execute(ctx, "SELECT '{}'::jsonb ? 'a'")
+	want := []string{"UPDATE t SET a = $1 WHERE b = $2", "SELECT '{}'::jsonb ? 'a'"}
+	if len(driver.sqls) != len(want) || driver.sqls[0] != want[0] || driver.sqls[1] != want[1] {
+		t.Errorf("Execute() = %q, want %q", driver.sqls, want)
+	}
+}
+
+func TestBindDriver(t *testing.T) {
+	sqlDriver := NewSqlDriver(&sql.DB{})
+	if _, ok := bindDriver(sqlDriver, NewPostgresDatabaseMetaData()).(*PlaceholderDriver); !ok {
+		t.Error("SqlDriver should be wrapped for PostgreSQL")
+	}
+	if bindDriver(sqlDriver, NewMysqlDatabaseMetaData()) != Driver(sqlDriver) {
+		t.Error("SqlDriver should not be wrapped for MySQL")
+	}
+	driver := &recordDriver{}
+	if bindDriver(driver, NewOracleDatabaseMetaData()) != Driver(driver) {
+		t.Error("other drivers should not be wrapped")
+	}
+}
@@ -2,10 +2,13 @@ package dbfly
 
 import (
 	"context"
+	sql2 "database/sql"
 	"errors"
+	"strings"
 )
 
 type PostgresDatabaseMetaData struct {
+	versionHolder
 	quoter *Quoter
 	schema string
 }
@@ -20,6 +23,10 @@ func (m *PostgresDatabaseMetaData) Dbms() string {
 	return "PostgreSQL"
 }
 
+func (m *PostgresDatabaseMetaData) Placeholder() PlaceholderStyle {
+	return PlaceholderDollar
+}
+
 func (m *PostgresDatabaseMetaData) DataType(str string) string {
 	switch str {
 	case Varchar:
@@ -75,11 +82,20 @@ func (m *PostgresDatabaseMetaData) GetTables(ctx context.Context, driver Driver)
 	if err != nil {
 		return nil, err
 	}
-	sql := `select tablename AS "TABLE_NAME", 'TABLE' AS "TABLE_TYPE" from pg_tables  WHERE schemaname = ?
+	sql := `select tablename AS "TABLE_NAME", 'TABLE' AS "TABLE_TYPE",
+       obj_description((quote_ident(schemaname) || '.' || quote_ident(tablename))::regclass, 'pg_class') AS "TABLE_COMMENT"
+from pg_tables  WHERE schemaname = ?
 union
-select viewname AS "TABLE_NAME", 'VIEW' AS "TABLE_TYPE" from pg_views  WHERE schemaname = ?`
+select viewname AS "TABLE_NAME", 'VIEW' AS "TABLE_TYPE",
+       obj_description((quote_ident(schemaname) || '.' || quote_ident(viewname))::regclass, 'pg_class') AS "TABLE_COMMENT"
+from pg_views  WHERE schemaname = ?`
 	return doGetSlices[Table](ctx, driver, func(rows Rows, t *Table) error {
-		return rows.Scan(&t.Name, &t.TableType)
+		var comment sql2.NullString
+		if err := rows.Scan(&t.Name, &t.TableType, &comment); err != nil {
+			return err
+		}
+		t.Comment = comment.String
+		return nil
 	}, sql, schema, schema)
 }
 
@@ -100,16 +116,62 @@ WHERE c.relkind in ('r', 'p', 'v', 'f', 'm')
 	return doGetScalars[string](ctx, driver, sql, schema, tableName)
 }
 
+func (m *PostgresDatabaseMetaData) GetColumnDetails(ctx context.Context, driver Driver, tableName string) ([]*Column, error) {
+	schema, err := m.getSchema(ctx, driver)
+	if err != nil {
+		return nil, err
+	}
+	sql := `SELECT c.column_name,
+       c.udt_name,
+       c.character_maximum_length,
+       c.numeric_precision,
+       c.numeric_scale,
+       c.is_nullable,
+       c.column_default,
+       pg_catalog.col_description(cls.oid, c.ordinal_position::int),
+       c.ordinal_position
+FROM information_schema.columns c
+         JOIN pg_catalog.pg_namespace n ON (n.nspname = c.table_schema)
+         JOIN pg_catalog.pg_class cls ON (cls.relnamespace = n.oid AND cls.relname = c.table_name)
+WHERE c.table_schema = ?
+  AND c.table_name = ?
+ORDER BY c.ordinal_position`
+	return doGetSlices[Column](ctx, driver, func(rows Rows, t *Column) error {
+		var (
+			maxLength    sql2.NullInt64
+			precision    sql2.NullInt64
+			scale        sql2.NullInt64
+			nullable     string
+			defaultValue sql2.NullString
+			comment      sql2.NullString
+		)
+		if err := rows.Scan(&t.Name, &t.DataType, &maxLength, &precision, &scale, &nullable, &defaultValue, &comment, &t.Ordinal); err != nil {
+			return err
+		}
+		t.DataType = strings.ToUpper(t.DataType)
+		t.MaxLength = int(maxLength.Int64)
+		t.NumericPrecision = int(precision.Int64)
+		t.NumericScale = int(scale.Int64)
+		t.Nullable = strings.ToUpper(nullable) == "YES"
+		t.DefaultValue = defaultValue.String
+		t.Comment = comment.String
+		return nil
+	}, sql, schema, tableName)
+}
+
 func (m *PostgresDatabaseMetaData) GetIndexes(ctx context.Context, driver Driver, tableName string) ([]*Index, error) {
 	schema, err := m.getSchema(ctx, driver)
 	if err != nil {
 		return nil, err
 	}
 	sql := `SELECT tmp.INDEX_NAME                                                                          AS "INDEX_NAME",
-       trim(both '"' from pg_catalog.pg_get_indexdef(tmp.CI_OID, tmp.ORDINAL_POSITION, false)) AS "COLUMN_NAME"
+       trim(both '"' from pg_catalog.pg_get_indexdef(tmp.CI_OID, tmp.ORDINAL_POSITION, false)) AS "COLUMN_NAME",
+       tmp.IS_UNIQUE                                                                           AS "IS_UNIQUE",
+       tmp.ORDINAL_POSITION                                                                    AS "ORDINAL_POSITION"
 FROM (SELECT ci.relname                                       AS INDEX_NAME,
              (information_schema._pg_expandarray(i.indkey)).n AS ORDINAL_POSITION,
-             ci.oid                                           AS CI_OID
+             ci.oid                                           AS CI_OID,
+             i.indisunique                                    AS IS_UNIQUE
       FROM pg_catalog.pg_class ct
                JOIN pg_catalog.pg_namespace n ON (ct.relnamespace = n.oid)
                JOIN pg_catalog.pg_index i ON (ct.oid = i.indrelid)
@@ -117,9 +179,9 @@ FROM (SELECT ci.relname                                       AS INDEX_NAME,
       WHERE true
         AND n.nspname = ?
         AND ct.relname = ?) AS tmp
-ORDER BY "INDEX_NAME"`
+ORDER BY "INDEX_NAME", "ORDINAL_POSITION"`
 	return doGetSlices[Index](ctx, driver, func(rows Rows, t *Index) error {
-		return rows.Scan(&t.Name, &t.ColumnName)
+		return rows.Scan(&t.Name, &t.ColumnName, &t.Unique, &t.Ordinal)
 	}, sql, schema, tableName)
 }
 
@@ -150,6 +212,32 @@ where result.A_ATTNUM = (result.KEYS).x
 		return rows.Scan(&t.ColumnName, &t.Name)
 	}, sql, schema, tableName)
 }
+func (m *PostgresDatabaseMetaData) GetForeignKeys(ctx context.Context, driver Driver, tableName string) ([]*ForeignKey, error) {
+	schema, err := m.getSchema(ctx, driver)
+	if err != nil {
+		return nil, err
+	}
+	sql := `SELECT con.conname AS "FK_NAME",
+       a.attname    AS "COLUMN_NAME",
+       rc.relname   AS "REF_TABLE_NAME",
+       ra.attname   AS "REF_COLUMN_NAME",
+       k.n          AS "ORDINAL_POSITION"
+FROM pg_catalog.pg_constraint con
+         JOIN pg_catalog.pg_class c ON (c.oid = con.conrelid)
+         JOIN pg_catalog.pg_namespace n ON (n.oid = c.relnamespace)
+         JOIN pg_catalog.pg_class rc ON (rc.oid = con.confrelid)
+         CROSS JOIN LATERAL unnest(con.conkey, con.confkey) WITH ORDINALITY AS k(attnum, refattnum, n)
+         JOIN pg_catalog.pg_attribute a ON (a.attrelid = con.conrelid AND a.attnum = k.attnum)
+         JOIN pg_catalog.pg_attribute ra ON (ra.attrelid = con.confrelid AND ra.attnum = k.refattnum)
+WHERE con.contype = 'f'
+  AND n.nspname = ?
+  AND c.relname = ?
+ORDER BY "FK_NAME", "ORDINAL_POSITION"`
+	return doGetSlices[ForeignKey](ctx, driver, func(rows Rows, t *ForeignKey) error {
+		return rows.Scan(&t.Name, &t.ColumnName, &t.RefTableName, &t.RefColumnName, &t.Ordinal)
+	}, sql, schema, tableName)
+}
+
 func (m *PostgresDatabaseMetaData) ExistsTable(ctx context.Context, driver Driver, tableName string) (bool, string, error) {
 	return ExistsTable(m.GetTables, ctx, driver, tableName)
 }
@@ -166,6 +254,10 @@ func (m *PostgresDatabaseMetaData) ExistsPrimaryKey(ctx context.Context, driver
 	return ExistsPrimaryKey(m.GetTables, m.GetPrimaryKeys, ctx, driver, tableName)
 }
 
+func (m *PostgresDatabaseMetaData) Version(ctx context.Context, driver Driver) (string, error) {
+	return m.queryVersion(ctx, driver, "SHOW server_version", nil)
+}
+
 func (m *PostgresDatabaseMetaData) Quoter() *Quoter {
 	return m.quoter
 }
@@ -181,3 +273,8 @@ func NewPostgresMigratory() Migratory {
 		DefaultMigratory: NewDefaultMigratory("postgres", NewPostgresDatabaseMetaData()),
 	}
 }
+
+// SupportsTransactionalDDL Postgres 的 DDL 可以在事务中执行并回滚
+func (m *PostgresMigratory) SupportsTransactionalDDL() bool {
+	return true
+}
@@ -2,7 +2,9 @@ package dbfly
 
 import (
 	"context"
+	sql2 "database/sql"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -12,21 +14,84 @@ const (
 	COLUMN_CHANGESET_ID   = "CHANGESET_ID"
 	COLUMN_AUTHOR         = "AUTHOR"
 	COLUMN_FILENAME       = "FILENAME"
+	COLUMN_DESCRIPTION    = "DESCRIPTION"
 	COLUMN_ORDER_EXECUTED = "ORDER_EXECUTED"
 	COLUMN_IS_SUCCESS     = "IS_SUCCESS"
+	COLUMN_EXEC_TYPE      = "EXEC_TYPE"
+	COLUMN_CHECKSUM       = "CHECKSUM"
+	COLUMN_DURATION_MS    = "DURATION_MS"
+	COLUMN_DEPLOYMENT_ID  = "DEPLOYMENT_ID"
+	COLUMN_TAG            = "TAG"
+	COLUMN_CONTEXTS       = "CONTEXTS"
+	COLUMN_DBFLY_VERSION  = "DBFLY_VERSION"
+	COLUMN_ERROR_MESSAGE  = "ERROR_MESSAGE"
 	COLUMN_CREATED_AT     = "CREATED_AT"
 	COLUMN_UPDATED_AT     = "UPDATED_AT"
 )
 
+// maxErrorMessageLength 记录的错误信息最大长度（字符数）
+const maxErrorMessageLength = 4000
+
+// ExecType 变更集的执行状态
+type ExecType string
+
+const (
+	ExecTypeRunning  ExecType = "RUNNING"
+	ExecTypeExecuted ExecType = "EXECUTED"
+	ExecTypeFailed   ExecType = "FAILED"
+	// ExecTypeRolledBack 已通过回滚部署撤销，下次迁移时重新执行
+	ExecTypeRolledBack ExecType = "ROLLED_BACK"
+)
+
+// ChangeLogEntry 一条变更记录
+type ChangeLogEntry struct {
+	ChangeSetId   string
+	Author        string
+	Filename      string
+	Description   string
+	OrderExecuted int
+	Success       bool
+	ExecType      ExecType
+	// Checksum changeSet 定义的校验和
+	Checksum string
+	// Duration 执行耗时，以毫秒精度记录
+	Duration     time.Duration
+	DeploymentId string
+	Tag          string
+	// Contexts 执行时的上下文，逗号分隔
+	Contexts     string
+	DbflyVersion string
+	ErrorMessage string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// fail 记录失败信息，错误信息过长时截断
+func (e *ChangeLogEntry) fail(err error) {
+	e.Success = false
+	e.ExecType = ExecTypeFailed
+	message := []rune(err.Error())
+	if len(message) > maxErrorMessageLength {
+		message = message[:maxErrorMessageLength]
+	}
+	e.ErrorMessage = string(message)
+}
+
 type Recorder interface {
 	// InitChangeLogTable 初始化记录变更记录表
 	InitChangeLogTable(context.Context, *Dbfly) error
 	// GetExecutedChangeSets 获取已执行的变更集ID集合
 	GetExecutedChangeSets(context.Context, *Dbfly) (map[string]bool, error)
-	// NewChangeLog 创建一条新的变更记录
-	NewChangeLog(context.Context, *Dbfly, string, string, string, int) error
-	// CompleteChangeLog 完成一条变更记录
-	CompleteChangeLog(context.Context, *Dbfly, string) error
+	// History 按执行顺序返回全部变更记录
+	History(context.Context, *Dbfly) ([]ChangeLogEntry, error)
+	// NewChangeLog 创建一条执行中的变更记录
+	NewChangeLog(context.Context, *Dbfly, *ChangeLogEntry) error
+	// CompleteChangeLog 将变更记录标记为成功
+	CompleteChangeLog(context.Context, *Dbfly, *ChangeLogEntry) error
+	// FailChangeLog 将变更记录标记为失败并记录错误信息
+	FailChangeLog(context.Context, *Dbfly, *ChangeLogEntry) error
+	// RollbackChangeLog 将成功的变更记录标记为已回滚
+	RollbackChangeLog(context.Context, *Dbfly, *ChangeLogEntry) error
 }
 
 type DbRecorder struct {
@@ -51,6 +116,11 @@ func NewDbRecorder(opts ...RecorderOption) *DbRecorder {
 	return r
 }
 
+// TableName 变更记录表名称
+func (r *DbRecorder) TableName() string {
+	return r.tableName
+}
+
 func (r *DbRecorder) InitChangeLogTable(ctx context.Context, fly *Dbfly) error {
 	migratory := fly.Migratory()
 	driver := fly.Driver()
@@ -62,17 +132,27 @@ func (r *DbRecorder) InitChangeLogTable(ctx context.Context, fly *Dbfly) error {
 		return err
 	}
 	if exists {
-		return nil
+		return upgradeTable(ctx, fly, r.tableName, changeLogSchema)
 	}
 
 	quoter := metaData.Quoter()
-	sql := fmt.Sprintf("CREATE TABLE %s(%s %s(255) PRIMARY KEY, %s %s(255), %s %s(255), %s %s NOT NULL, %s %s DEFAULT 0 NOT NULL, %s %s, %s %s)",
+	sql := fmt.Sprintf("CREATE TABLE %s(%s %s(255) PRIMARY KEY, %s %s(255), %s %s(255), %s %s(255), %s %s NOT NULL, %s %s DEFAULT 0 NOT NULL, "+
+		"%s %s(20), %s %s(64), %s %s, %s %s(64), %s %s(255), %s %s(255), %s %s(32), %s %s, %s %s, %s %s)",
 		quoter.MustQuote(r.tableName),
 		quoter.MustQuote(COLUMN_CHANGESET_ID), metaData.DataType(Varchar),
 		quoter.MustQuote(COLUMN_AUTHOR), metaData.DataType(Varchar),
 		quoter.MustQuote(COLUMN_FILENAME), metaData.DataType(Varchar),
+		quoter.MustQuote(COLUMN_DESCRIPTION), metaData.DataType(Varchar),
 		quoter.MustQuote(COLUMN_ORDER_EXECUTED), metaData.DataType(Int),
 		quoter.MustQuote(COLUMN_IS_SUCCESS), metaData.DataType(Tinyint),
+		quoter.MustQuote(COLUMN_EXEC_TYPE), metaData.DataType(Varchar),
+		quoter.MustQuote(COLUMN_CHECKSUM), metaData.DataType(Varchar),
+		quoter.MustQuote(COLUMN_DURATION_MS), metaData.DataType(Bigint),
+		quoter.MustQuote(COLUMN_DEPLOYMENT_ID), metaData.DataType(Varchar),
+		quoter.MustQuote(COLUMN_TAG), metaData.DataType(Varchar),
+		quoter.MustQuote(COLUMN_CONTEXTS), metaData.DataType(Varchar),
+		quoter.MustQuote(COLUMN_DBFLY_VERSION), metaData.DataType(Varchar),
+		quoter.MustQuote(COLUMN_ERROR_MESSAGE), metaData.DataType(Text),
 		quoter.MustQuote(COLUMN_CREATED_AT), metaData.DataType(Timestamp),
 		quoter.MustQuote(COLUMN_UPDATED_AT), metaData.DataType(Timestamp),
 	)
@@ -80,7 +160,7 @@ func (r *DbRecorder) InitChangeLogTable(ctx context.Context, fly *Dbfly) error {
 		return err
 	}
 	fly.logger.Debug("change log table initialized")
-	return nil
+	return setSchemaVersion(ctx, fly, r.tableName, changeLogSchema.version)
 }
 
 func (r *DbRecorder) GetExecutedChangeSets(ctx context.Context, fly *Dbfly) (map[string]bool, error) {
@@ -105,7 +185,86 @@ func (r *DbRecorder) GetExecutedChangeSets(ctx context.Context, fly *Dbfly) (map
 	return result, nil
 }
 
-func (r *DbRecorder) NewChangeLog(ctx context.Context, fly *Dbfly, changeSetId, author, filename string, orderExecuted int) error {
+// changeLogColumns History 查询的列，与 scanChangeLogEntry 的顺序一致
+var changeLogColumns = []string{
+	COLUMN_CHANGESET_ID, COLUMN_AUTHOR, COLUMN_FILENAME, COLUMN_DESCRIPTION, COLUMN_ORDER_EXECUTED,
+	COLUMN_IS_SUCCESS, COLUMN_EXEC_TYPE, COLUMN_CHECKSUM, COLUMN_DURATION_MS, COLUMN_DEPLOYMENT_ID,
+	COLUMN_TAG, COLUMN_CONTEXTS, COLUMN_DBFLY_VERSION, COLUMN_ERROR_MESSAGE, COLUMN_CREATED_AT, COLUMN_UPDATED_AT,
+}
+
+// quoteColumns 引用列名并以逗号连接
+func quoteColumns(quoter *Quoter, columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = quoter.MustQuote(column)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// scanChangeLogEntry 扫描一条变更记录，可空列兼容 NULL 值
+func scanChangeLogEntry(rows Rows, entry *ChangeLogEntry) error {
+	var (
+		author, filename, description, execType, checksum  sql2.NullString
+		deploymentId, tag, contexts, version, errorMessage sql2.NullString
+		orderExecuted, success, duration                   sql2.NullInt64
+		createdAt, updatedAt                               lockTime
+	)
+	if err := rows.Scan(&entry.ChangeSetId, &author, &filename, &description, &orderExecuted,
+		&success, &execType, &checksum, &duration, &deploymentId,
+		&tag, &contexts, &version, &errorMessage, &createdAt, &updatedAt); err != nil {
+		return err
+	}
+	entry.Author = author.String
+	entry.Filename = filename.String
+	entry.Description = description.String
+	entry.OrderExecuted = int(orderExecuted.Int64)
+	entry.Success = success.Int64 == 1
+	entry.ExecType = ExecType(execType.String)
+	if entry.ExecType == "" {
+		// 早期版本的记录没有执行状态
+		entry.ExecType = ExecTypeFailed
+		if entry.Success {
+			entry.ExecType = ExecTypeExecuted
+		}
+	}
+	entry.Checksum = checksum.String
+	entry.Duration = time.Duration(duration.Int64) * time.Millisecond
+	entry.DeploymentId = deploymentId.String
+	entry.Tag = tag.String
+	entry.Contexts = contexts.String
+	entry.DbflyVersion = version.String
+	entry.ErrorMessage = errorMessage.String
+	entry.CreatedAt = createdAt.Time
+	entry.UpdatedAt = updatedAt.Time
+	return nil
+}
+
+func (r *DbRecorder) History(ctx context.Context, fly *Dbfly) ([]ChangeLogEntry, error) {
+	metaData := fly.Migratory().MetaData()
+	// 尚未迁移时变更记录表不存在
+	exists, _, err := metaData.ExistsTable(ctx, fly.Driver(), r.tableName)
+	if err != nil || !exists {
+		return nil, err
+	}
+	quoter := metaData.Quoter()
+	var entries []ChangeLogEntry
+	err = doEach(ctx, fly.Driver(), func(rows Rows) error {
+		var entry ChangeLogEntry
+		if err := scanChangeLogEntry(rows, &entry); err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+		return nil
+	}, fmt.Sprintf("SELECT %s FROM %s ORDER BY %s, %s",
+		quoteColumns(quoter, changeLogColumns), quoter.MustQuote(r.tableName),
+		quoter.MustQuote(COLUMN_ORDER_EXECUTED), quoter.MustQuote(COLUMN_CREATED_AT)))
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *DbRecorder) NewChangeLog(ctx context.Context, fly *Dbfly, entry *ChangeLogEntry) error {
 	migratory := fly.Migratory()
 	driver := fly.Driver()
 	metaData := migratory.MetaData()
@@ -116,34 +275,72 @@ func (r *DbRecorder) NewChangeLog(ctx context.Context, fly *Dbfly, changeSetId,
 			quoter.MustQuote(r.tableName),
 			quoter.MustQuote(COLUMN_CHANGESET_ID),
 			quoter.MustQuote(COLUMN_IS_SUCCESS)),
-		changeSetId); err != nil {
+		entry.ChangeSetId); err != nil {
 		return err
 	}
+	now := time.Now()
+	entry.Success, entry.ExecType, entry.CreatedAt, entry.UpdatedAt = false, ExecTypeRunning, now, now
+	columns := []string{
+		COLUMN_CHANGESET_ID, COLUMN_AUTHOR, COLUMN_FILENAME, COLUMN_DESCRIPTION, COLUMN_ORDER_EXECUTED,
+		COLUMN_IS_SUCCESS, COLUMN_EXEC_TYPE, COLUMN_CHECKSUM, COLUMN_DEPLOYMENT_ID, COLUMN_TAG,
+		COLUMN_CONTEXTS, COLUMN_DBFLY_VERSION, COLUMN_CREATED_AT, COLUMN_UPDATED_AT,
+	}
 	if _, err := driver.Execute(ctx,
-		fmt.Sprintf("INSERT INTO %s(%s, %s, %s, %s, %s, %s, %s) VALUES(?, ?, ?, ?, 0, ?, ?)",
-			quoter.MustQuote(r.tableName),
-			quoter.MustQuote(COLUMN_CHANGESET_ID), quoter.MustQuote(COLUMN_AUTHOR),
-			quoter.MustQuote(COLUMN_FILENAME), quoter.MustQuote(COLUMN_ORDER_EXECUTED),
-			quoter.MustQuote(COLUMN_IS_SUCCESS), quoter.MustQuote(COLUMN_CREATED_AT), quoter.MustQuote(COLUMN_UPDATED_AT)),
-		changeSetId, author, filename, orderExecuted, time.Now(), time.Now()); err != nil {
+		fmt.Sprintf("INSERT INTO %s(%s) VALUES(?, ?, ?, ?, ?, 0, ?, ?, ?, ?, ?, ?, ?, ?)",
+			quoter.MustQuote(r.tableName), quoteColumns(quoter, columns)),
+		entry.ChangeSetId, entry.Author, entry.Filename, entry.Description, entry.OrderExecuted,
+		string(entry.ExecType), entry.Checksum, entry.DeploymentId, entry.Tag,
+		entry.Contexts, entry.DbflyVersion, entry.CreatedAt, entry.UpdatedAt); err != nil {
 		return err
 	}
-	fly.logger.Debug("change log created, changeSetId: %q", changeSetId)
+	fly.logger.Debug("change log created, changeSetId: %q", entry.ChangeSetId)
 	return nil
 }
 
-func (r *DbRecorder) CompleteChangeLog(ctx context.Context, fly *Dbfly, changeSetId string) error {
-	migratory := fly.Migratory()
-	driver := fly.Driver()
-	metaData := migratory.MetaData()
-	quoter := metaData.Quoter()
-	if _, err := driver.Execute(ctx,
-		fmt.Sprintf("UPDATE %s SET %s = 1, %s = ? WHERE %s = ? AND %s = 0",
-			quoter.MustQuote(r.tableName), quoter.MustQuote(COLUMN_IS_SUCCESS),
+func (r *DbRecorder) CompleteChangeLog(ctx context.Context, fly *Dbfly, entry *ChangeLogEntry) error {
+	entry.Success, entry.ExecType = true, ExecTypeExecuted
+	if err := r.finishChangeLog(ctx, fly, entry); err != nil {
+		return err
+	}
+	fly.logger.Debug("change log completed, changeSetId: %q", entry.ChangeSetId)
+	return nil
+}
+
+func (r *DbRecorder) FailChangeLog(ctx context.Context, fly *Dbfly, entry *ChangeLogEntry) error {
+	if err := r.finishChangeLog(ctx, fly, entry); err != nil {
+		return err
+	}
+	fly.logger.Debug("change log failed, changeSetId: %q", entry.ChangeSetId)
+	return nil
+}
+
+func (r *DbRecorder) RollbackChangeLog(ctx context.Context, fly *Dbfly, entry *ChangeLogEntry) error {
+	quoter := fly.Migratory().MetaData().Quoter()
+	entry.Success, entry.ExecType, entry.UpdatedAt = false, ExecTypeRolledBack, time.Now()
+	if _, err := fly.Driver().Execute(ctx,
+		fmt.Sprintf("UPDATE %s SET %s = 0, %s = ?, %s = ? WHERE %s = ? AND %s = 1",
+			quoter.MustQuote(r.tableName), quoter.MustQuote(COLUMN_IS_SUCCESS), quoter.MustQuote(COLUMN_EXEC_TYPE),
 			quoter.MustQuote(COLUMN_UPDATED_AT), quoter.MustQuote(COLUMN_CHANGESET_ID), quoter.MustQuote(COLUMN_IS_SUCCESS)),
-		time.Now(), changeSetId); err != nil {
+		string(entry.ExecType), entry.UpdatedAt, entry.ChangeSetId); err != nil {
 		return err
 	}
-	fly.logger.Debug("change log completed, changeSetId: %q", changeSetId)
+	fly.logger.Debug("change log rolled back, changeSetId: %q", entry.ChangeSetId)
 	return nil
 }
+
+// finishChangeLog 更新执行中记录的执行结果
+func (r *DbRecorder) finishChangeLog(ctx context.Context, fly *Dbfly, entry *ChangeLogEntry) error {
+	quoter := fly.Migratory().MetaData().Quoter()
+	entry.UpdatedAt = time.Now()
+	success := 0
+	if entry.Success {
+		success = 1
+	}
+	_, err := fly.Driver().Execute(ctx,
+		fmt.Sprintf("UPDATE %s SET %s = ?, %s = ?, %s = ?, %s = ?, %s = ? WHERE %s = ? AND %s = 0",
+			quoter.MustQuote(r.tableName), quoter.MustQuote(COLUMN_IS_SUCCESS), quoter.MustQuote(COLUMN_EXEC_TYPE),
+			quoter.MustQuote(COLUMN_DURATION_MS), quoter.MustQuote(COLUMN_ERROR_MESSAGE), quoter.MustQuote(COLUMN_UPDATED_AT),
+			quoter.MustQuote(COLUMN_CHANGESET_ID), quoter.MustQuote(COLUMN_IS_SUCCESS)),
+		success, string(entry.ExecType), entry.Duration.Milliseconds(), entry.ErrorMessage, entry.UpdatedAt, entry.ChangeSetId)
+	return err
+}
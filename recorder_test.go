@@ -0,0 +1,112 @@
+package dbfly
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// failDriver 执行包含 FAIL 的SQL时返回错误
+type failDriver struct {
+	recordDriver
+}
+
+func (d *failDriver) Execute(ctx context.Context, sql string, args ...interface{}) (sql.Result, error) {
+	if strings.Contains(sql, "FAIL") {
+		return nil, New("syntax error near FAIL")
+	}
+	return d.recordDriver.Execute(ctx, sql, args...)
+}
+
+func TestMigrate_ChangeLogEntries(t *testing.T) {
+	source := NewFSSource(fstest.MapFS{"dbfly.xml": {Data: []byte(`<dbfly>
+    <changeSet id="a"><sqlInline><default>CREATE TABLE a(id INT)</default></sqlInline></changeSet>
+    <changeSet id="b" author="dev" description="create b">
+        <sqlInline><default>CREATE TABLE b(id INT)</default></sqlInline>
+    </changeSet>
+    <changeSet id="c" onFail="SKIP"><sqlInline><default>FAIL</default></sqlInline></changeSet>
+</dbfly>`)}})
+	recorder := &mockRecorder{
+		executed: map[string]bool{"a": true},
+		entries:  []ChangeLogEntry{{ChangeSetId: "a", OrderExecuted: 7, Success: true, ExecType: ExecTypeExecuted}},
+	}
+	migratory := NewDefaultMigratory("mock", &mockMetaData{dbms: "Mock"})
+	fly := NewDbfly(&migratory, &failDriver{}, source, WithRecorder(recorder), WithLocker(NewMutexLocker()),
+		WithTag("v1.2.0"), WithContexts("prod", "cn"))
+	if err := fly.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := fly.History(context.Background())
+	if err != nil || len(history) != 3 {
+		t.Fatalf("History() = %+v, %v", history, err)
+	}
+	b, c := history[1], history[2]
+	if b.ChangeSetId != "b" || b.OrderExecuted != 8 || !b.Success || b.ExecType != ExecTypeExecuted ||
+		b.Description != "create b" || b.Tag != "v1.2.0" || b.Contexts != "prod,cn" || b.DbflyVersion != Version || len(b.Checksum) != 64 {
+		t.Errorf("entry b = %+v", b)
+	}
+	if c.ChangeSetId != "c" || c.OrderExecuted != 9 || c.Success || c.ExecType != ExecTypeFailed ||
+		c.Description != "sqlInline" || !strings.Contains(c.ErrorMessage, "syntax error near FAIL") {
+		t.Errorf("entry c = %+v", c)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	lf := checksum([]byte("<changeSet id=\"a\">\n  <sqlInline/>\n</changeSet>"))
+	crlf := checksum([]byte("\r\n<changeSet id=\"a\">\r\n  <sqlInline/>\r\n</changeSet>\r\n"))
+	if lf != crlf {
+		t.Errorf("checksum should ignore line endings: %s != %s", lf, crlf)
+	}
+	if lf == checksum([]byte("<changeSet id=\"a\">\n  <sqlInline/>\n</changeSet><!-- -->")) {
+		t.Error("checksum should change with content")
+	}
+}
+
+// historyDriver 返回固定的变更记录
+type historyDriver struct {
+	recordDriver
+	rows [][]interface{}
+}
+
+func (d *historyDriver) Query(_ context.Context, sql string, _ ...interface{}) (Rows, error) {
+	d.sqls = append(d.sqls, sql)
+	return &valueRows{values: d.rows}, nil
+}
+
+func TestDbRecorder_History(t *testing.T) {
+	created := time.Date(2024, 5, 1, 8, 0, 0, 0, time.Local)
+	driver := &historyDriver{rows: [][]interface{}{
+		{"a", "dev", "dbfly.xml", "createTable", int64(1), int64(1), "EXECUTED", "abc", int64(1500), "d1", "v1", "prod", "1.0.0", nil, created, created},
+		// 早期版本的记录，新增列为 NULL
+		{"b", "dev", "dbfly.xml", nil, int64(2), int64(0), nil, nil, nil, nil, nil, nil, nil, nil, created, nil},
+	}}
+	migratory := NewDefaultMigratory("mock", &mockMetaData{dbms: "Mock", tables: []*Table{{Name: defaultChangeLogTableName, TableType: "TABLE"}}})
+	fly := NewDbfly(&migratory, driver, nil)
+	history, err := NewDbRecorder().History(context.Background(), fly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ChangeLogEntry{
+		{ChangeSetId: "a", Author: "dev", Filename: "dbfly.xml", Description: "createTable", OrderExecuted: 1, Success: true,
+			ExecType: ExecTypeExecuted, Checksum: "abc", Duration: 1500 * time.Millisecond, DeploymentId: "d1", Tag: "v1",
+			Contexts: "prod", DbflyVersion: "1.0.0", CreatedAt: created, UpdatedAt: created},
+		{ChangeSetId: "b", Author: "dev", Filename: "dbfly.xml", OrderExecuted: 2, ExecType: ExecTypeFailed, CreatedAt: created},
+	}
+	if !reflect.DeepEqual(history, want) {
+		t.Errorf("History() = %+v, want %+v", history, want)
+	}
+	if !strings.Contains(driver.sqls[0], `ORDER BY "ORDER_EXECUTED", "CREATED_AT"`) {
+		t.Errorf("History() sql = %s", driver.sqls[0])
+	}
+
+	// 变更记录表不存在时返回空
+	migratory = NewDefaultMigratory("mock", &mockMetaData{dbms: "Mock"})
+	if history, err = NewDbRecorder().History(context.Background(), NewDbfly(&migratory, driver, nil)); err != nil || history != nil {
+		t.Errorf("History() without table = %+v, %v", history, err)
+	}
+}
@@ -0,0 +1,252 @@
+package dbfly
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// SchemaSnapshot 数据库结构快照，作为结构对比、漂移检测和文档生成的基础
+type SchemaSnapshot struct {
+	Dbms   string           `json:"dbms" yaml:"dbms"`
+	Tables []*TableSnapshot `json:"tables" yaml:"tables"`
+	Views  []*ViewSnapshot  `json:"views,omitempty" yaml:"views,omitempty"`
+}
+
+// TableSnapshot 表结构快照
+type TableSnapshot struct {
+	Name        string                `json:"name" yaml:"name"`
+	Comment     string                `json:"comment,omitempty" yaml:"comment,omitempty"`
+	Columns     []*ColumnSnapshot     `json:"columns" yaml:"columns"`
+	PrimaryKey  *PrimaryKeySnapshot   `json:"primaryKey,omitempty" yaml:"primaryKey,omitempty"`
+	Indexes     []*IndexSnapshot      `json:"indexes,omitempty" yaml:"indexes,omitempty"`
+	ForeignKeys []*ForeignKeySnapshot `json:"foreignKeys,omitempty" yaml:"foreignKeys,omitempty"`
+}
+
+// ViewSnapshot 视图结构快照
+type ViewSnapshot struct {
+	Name    string            `json:"name" yaml:"name"`
+	Columns []*ColumnSnapshot `json:"columns" yaml:"columns"`
+}
+
+// ColumnSnapshot 列结构快照
+type ColumnSnapshot struct {
+	Name             string `json:"name" yaml:"name"`
+	DataType         string `json:"dataType" yaml:"dataType"`
+	MaxLength        int    `json:"maxLength,omitempty" yaml:"maxLength,omitempty"`
+	NumericPrecision int    `json:"numericPrecision,omitempty" yaml:"numericPrecision,omitempty"`
+	NumericScale     int    `json:"numericScale,omitempty" yaml:"numericScale,omitempty"`
+	Nullable         bool   `json:"nullable" yaml:"nullable"`
+	DefaultValue     string `json:"defaultValue,omitempty" yaml:"defaultValue,omitempty"`
+	Comment          string `json:"comment,omitempty" yaml:"comment,omitempty"`
+}
+
+// PrimaryKeySnapshot 主键结构快照
+type PrimaryKeySnapshot struct {
+	Name    string   `json:"name,omitempty" yaml:"name,omitempty"`
+	Columns []string `json:"columns" yaml:"columns"`
+}
+
+// IndexSnapshot 索引结构快照，列按索引中的顺序排列
+type IndexSnapshot struct {
+	Name    string   `json:"name" yaml:"name"`
+	Unique  bool     `json:"unique" yaml:"unique"`
+	Columns []string `json:"columns" yaml:"columns"`
+}
+
+// ForeignKeySnapshot 外键结构快照，列按外键中的顺序排列
+type ForeignKeySnapshot struct {
+	Name       string   `json:"name" yaml:"name"`
+	Columns    []string `json:"columns" yaml:"columns"`
+	RefTable   string   `json:"refTable" yaml:"refTable"`
+	RefColumns []string `json:"refColumns" yaml:"refColumns"`
+}
+
+// Snapshot 读取当前数据库的表、视图、列、索引、主键和外键，生成结构快照
+func Snapshot(ctx context.Context, driver Driver, metaData DatabaseMetaData) (*SchemaSnapshot, error) {
+	driver = bindDriver(driver, metaData)
+	if err := detectServer(ctx, driver, metaData); err != nil {
+		return nil, err
+	}
+	tables, err := metaData.GetTables(ctx, driver)
+	if err != nil {
+		return nil, Wrap(err, "get tables failed")
+	}
+	snapshot := &SchemaSnapshot{Dbms: metaData.Dbms()}
+	for _, table := range tables {
+		columns, err := snapshotColumns(ctx, driver, metaData, table.Name)
+		if err != nil {
+			return nil, err
+		}
+		if strings.ToUpper(table.TableType) == "VIEW" {
+			snapshot.Views = append(snapshot.Views, &ViewSnapshot{Name: table.Name, Columns: columns})
+			continue
+		}
+		tableSnapshot := &TableSnapshot{Name: table.Name, Comment: table.Comment, Columns: columns}
+
+		primaryKeys, err := metaData.GetPrimaryKeys(ctx, driver, table.Name)
+		if err != nil {
+			return nil, Wrap(err, "get primary keys of table %s failed", table.Name)
+		}
+		if len(primaryKeys) > 0 {
+			tableSnapshot.PrimaryKey = &PrimaryKeySnapshot{Name: primaryKeys[0].Name}
+			for _, pk := range primaryKeys {
+				tableSnapshot.PrimaryKey.Columns = append(tableSnapshot.PrimaryKey.Columns, pk.ColumnName)
+			}
+		}
+
+		indexes, err := metaData.GetIndexes(ctx, driver, table.Name)
+		if err != nil {
+			return nil, Wrap(err, "get indexes of table %s failed", table.Name)
+		}
+		tableSnapshot.Indexes = groupIndexes(indexes, tableSnapshot.PrimaryKey)
+
+		foreignKeys, err := metaData.GetForeignKeys(ctx, driver, table.Name)
+		if err != nil {
+			return nil, Wrap(err, "get foreign keys of table %s failed", table.Name)
+		}
+		tableSnapshot.ForeignKeys = groupForeignKeys(foreignKeys)
+
+		snapshot.Tables = append(snapshot.Tables, tableSnapshot)
+	}
+	snapshot.sort()
+	return snapshot, nil
+}
+
+func snapshotColumns(ctx context.Context, driver Driver, metaData DatabaseMetaData, tableName string) ([]*ColumnSnapshot, error) {
+	columns, err := metaData.GetColumnDetails(ctx, driver, tableName)
+	if err != nil {
+		return nil, Wrap(err, "get columns of table %s failed", tableName)
+	}
+	sort.SliceStable(columns, func(i, j int) bool {
+		return columns[i].Ordinal < columns[j].Ordinal
+	})
+	list := make([]*ColumnSnapshot, 0, len(columns))
+	for _, column := range columns {
+		list = append(list, &ColumnSnapshot{
+			Name:             column.Name,
+			DataType:         column.DataType,
+			MaxLength:        column.MaxLength,
+			NumericPrecision: column.NumericPrecision,
+			NumericScale:     column.NumericScale,
+			Nullable:         column.Nullable,
+			DefaultValue:     column.DefaultValue,
+			Comment:          column.Comment,
+		})
+	}
+	return list, nil
+}
+
+// groupIndexes 将按列展开的索引信息合并为索引快照，排除主键对应的索引
+func groupIndexes(indexes []*Index, pk *PrimaryKeySnapshot) []*IndexSnapshot {
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return indexes[i].Ordinal < indexes[j].Ordinal
+	})
+	var list []*IndexSnapshot
+	byName := make(map[string]*IndexSnapshot)
+	for _, index := range indexes {
+		item, ok := byName[index.Name]
+		if !ok {
+			item = &IndexSnapshot{Name: index.Name, Unique: index.Unique}
+			byName[index.Name] = item
+			list = append(list, item)
+		}
+		item.Columns = append(item.Columns, index.ColumnName)
+	}
+	result := list[:0]
+	for _, item := range list {
+		if pk != nil && (strings.EqualFold(item.Name, pk.Name) || (item.Unique && equalFoldSlice(item.Columns, pk.Columns))) {
+			continue
+		}
+		result = append(result, item)
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// groupForeignKeys 将按列展开的外键信息合并为外键快照
+func groupForeignKeys(foreignKeys []*ForeignKey) []*ForeignKeySnapshot {
+	sort.SliceStable(foreignKeys, func(i, j int) bool {
+		return foreignKeys[i].Ordinal < foreignKeys[j].Ordinal
+	})
+	var list []*ForeignKeySnapshot
+	byName := make(map[string]*ForeignKeySnapshot)
+	for _, fk := range foreignKeys {
+		item, ok := byName[fk.Name]
+		if !ok {
+			item = &ForeignKeySnapshot{Name: fk.Name, RefTable: fk.RefTableName}
+			byName[fk.Name] = item
+			list = append(list, item)
+		}
+		item.Columns = append(item.Columns, fk.ColumnName)
+		item.RefColumns = append(item.RefColumns, fk.RefColumnName)
+	}
+	return list
+}
+
+func (s *SchemaSnapshot) sort() {
+	sort.Slice(s.Tables, func(i, j int) bool {
+		return strings.ToUpper(s.Tables[i].Name) < strings.ToUpper(s.Tables[j].Name)
+	})
+	sort.Slice(s.Views, func(i, j int) bool {
+		return strings.ToUpper(s.Views[i].Name) < strings.ToUpper(s.Views[j].Name)
+	})
+	for _, table := range s.Tables {
+		sort.Slice(table.Indexes, func(i, j int) bool {
+			return strings.ToUpper(table.Indexes[i].Name) < strings.ToUpper(table.Indexes[j].Name)
+		})
+		sort.Slice(table.ForeignKeys, func(i, j int) bool {
+			return strings.ToUpper(table.ForeignKeys[i].Name) < strings.ToUpper(table.ForeignKeys[j].Name)
+		})
+	}
+}
+
+// Table 按名称查找表快照（大小写不敏感）
+func (s *SchemaSnapshot) Table(name string) *TableSnapshot {
+	for _, table := range s.Tables {
+		if strings.EqualFold(table.Name, name) {
+			return table
+		}
+	}
+	return nil
+}
+
+// Column 按名称查找列快照（大小写不敏感）
+func (t *TableSnapshot) Column(name string) *ColumnSnapshot {
+	for _, column := range t.Columns {
+		if strings.EqualFold(column.Name, name) {
+			return column
+		}
+	}
+	return nil
+}
+
+// Index 按名称查找索引快照（大小写不敏感）
+func (t *TableSnapshot) Index(name string) *IndexSnapshot {
+	for _, index := range t.Indexes {
+		if strings.EqualFold(index.Name, name) {
+			return index
+		}
+	}
+	return nil
+}
+
+// MarshalIndentJSON 以缩进格式输出 JSON
+func (s *SchemaSnapshot) MarshalIndentJSON() ([]byte, error) {
+	return json.MarshalIndent(s, "", "  ")
+}
+
+func equalFoldSlice(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,187 @@
+package dbfly
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+// mockMetaData 基于内存数据的元数据实现
+type mockMetaData struct {
+	dbms        string
+	version     string
+	tables      []*Table
+	columns     map[string][]*Column
+	indexes     map[string][]*Index
+	primaryKeys map[string][]*PrimaryKey
+	foreignKeys map[string][]*ForeignKey
+}
+
+func (m *mockMetaData) Dbms() string {
+	return m.dbms
+}
+
+func (m *mockMetaData) Placeholder() PlaceholderStyle {
+	return PlaceholderQuestion
+}
+
+func (m *mockMetaData) DataType(str string) string {
+	return str
+}
+
+func (m *mockMetaData) GetTables(context.Context, Driver) ([]*Table, error) {
+	return m.tables, nil
+}
+
+func (m *mockMetaData) GetColumns(_ context.Context, _ Driver, tableName string) ([]string, error) {
+	var list []string
+	for _, column := range m.columns[tableName] {
+		list = append(list, column.Name)
+	}
+	return list, nil
+}
+
+func (m *mockMetaData) GetColumnDetails(_ context.Context, _ Driver, tableName string) ([]*Column, error) {
+	return m.columns[tableName], nil
+}
+
+func (m *mockMetaData) GetIndexes(_ context.Context, _ Driver, tableName string) ([]*Index, error) {
+	return m.indexes[tableName], nil
+}
+
+func (m *mockMetaData) GetPrimaryKeys(_ context.Context, _ Driver, tableName string) ([]*PrimaryKey, error) {
+	return m.primaryKeys[tableName], nil
+}
+
+func (m *mockMetaData) GetForeignKeys(_ context.Context, _ Driver, tableName string) ([]*ForeignKey, error) {
+	return m.foreignKeys[tableName], nil
+}
+
+func (m *mockMetaData) ExistsTable(ctx context.Context, driver Driver, tableName string) (bool, string, error) {
+	return ExistsTable(m.GetTables, ctx, driver, tableName)
+}
+
+func (m *mockMetaData) ExistsColumn(ctx context.Context, driver Driver, tableName, columnName string) (bool, string, string, error) {
+	return ExistsColumn(m.GetTables, m.GetColumns, ctx, driver, tableName, columnName)
+}
+
+func (m *mockMetaData) ExistsIndex(ctx context.Context, driver Driver, tableName, indexName string) (bool, string, string, error) {
+	return ExistsIndex(m.GetTables, m.GetIndexes, ctx, driver, tableName, indexName)
+}
+
+func (m *mockMetaData) ExistsPrimaryKey(ctx context.Context, driver Driver, tableName string) (bool, string, error) {
+	return ExistsPrimaryKey(m.GetTables, m.GetPrimaryKeys, ctx, driver, tableName)
+}
+
+func (m *mockMetaData) Version(context.Context, Driver) (string, error) {
+	return m.version, nil
+}
+
+func (m *mockMetaData) CachedVersion() string {
+	return m.version
+}
+
+func (m *mockMetaData) Quoter() *Quoter {
+	return NewQuoter('"', '"', AlwaysReserve)
+}
+
+func newMockMetaData() *mockMetaData {
+	return &mockMetaData{
+		dbms: "Mock",
+		tables: []*Table{
+			{Name: "users", TableType: "TABLE"},
+			{Name: "orders", TableType: "TABLE"},
+			{Name: "v_users", TableType: "VIEW"},
+		},
+		columns: map[string][]*Column{
+			"users": {
+				{Name: "name", DataType: "VARCHAR", MaxLength: 100, Nullable: true, Ordinal: 2},
+				{Name: "id", DataType: "BIGINT", Ordinal: 1},
+			},
+			"orders": {
+				{Name: "id", DataType: "BIGINT", Ordinal: 1},
+				{Name: "user_id", DataType: "BIGINT", Ordinal: 2},
+			},
+			"v_users": {
+				{Name: "id", DataType: "BIGINT", Ordinal: 1},
+			},
+		},
+		indexes: map[string][]*Index{
+			"users": {
+				{Name: "pk_users", ColumnName: "id", Unique: true, Ordinal: 1},
+				{Name: "idx_name", ColumnName: "name", Ordinal: 1},
+			},
+			"orders": {
+				{Name: "idx_user", ColumnName: "id", Ordinal: 2},
+				{Name: "idx_user", ColumnName: "user_id", Ordinal: 1},
+			},
+		},
+		primaryKeys: map[string][]*PrimaryKey{
+			"users":  {{Name: "pk_users", ColumnName: "id"}},
+			"orders": {{Name: "pk_orders", ColumnName: "id"}},
+		},
+		foreignKeys: map[string][]*ForeignKey{
+			"orders": {{Name: "fk_orders_user", ColumnName: "user_id", RefTableName: "users", RefColumnName: "id", Ordinal: 1}},
+		},
+	}
+}
+
+// withDbflyTables 添加记录器、锁初始化后创建的内部表
+func withDbflyTables(metaData *mockMetaData) *mockMetaData {
+	for _, name := range []string{defaultChangeLogTableName, defaultChangeLockTableName, defaultSchemaVersionTableName} {
+		metaData.tables = append(metaData.tables, &Table{Name: name, TableType: "TABLE"})
+		metaData.columns[name] = []*Column{{Name: "ID", DataType: "INT", Ordinal: 1}}
+	}
+	return metaData
+}
+
+func TestSnapshot(t *testing.T) {
+	snapshot, err := Snapshot(context.Background(), &SqlDriver{}, newMockMetaData())
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	if len(snapshot.Tables) != 2 || snapshot.Tables[0].Name != "orders" || snapshot.Tables[1].Name != "users" {
+		t.Fatalf("tables not sorted by name: %+v", snapshot.Tables)
+	}
+	if len(snapshot.Views) != 1 || snapshot.Views[0].Name != "v_users" {
+		t.Errorf("views = %+v, want [v_users]", snapshot.Views)
+	}
+
+	users := snapshot.Table("USERS")
+	if users == nil {
+		t.Fatal("Table(USERS) = nil")
+	}
+	if users.Columns[0].Name != "id" || users.Columns[1].Name != "name" {
+		t.Errorf("columns not ordered by ordinal: %+v", users.Columns)
+	}
+	if len(users.Indexes) != 1 || users.Indexes[0].Name != "idx_name" {
+		t.Errorf("primary key index should be excluded, got %+v", users.Indexes)
+	}
+
+	orders := snapshot.Table("orders")
+	if index := orders.Index("idx_user"); index == nil || !equalFoldSlice(index.Columns, []string{"user_id", "id"}) {
+		t.Errorf("index columns not ordered by ordinal: %+v", index)
+	}
+	if len(orders.ForeignKeys) != 1 || orders.ForeignKeys[0].RefTable != "users" {
+		t.Errorf("foreign keys = %+v", orders.ForeignKeys)
+	}
+}
+
+func TestSnapshot_DeterministicJSON(t *testing.T) {
+	first, err := Snapshot(context.Background(), &SqlDriver{}, newMockMetaData())
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	metaData := newMockMetaData()
+	// 打乱表顺序后结果应保持一致
+	metaData.tables[0], metaData.tables[1] = metaData.tables[1], metaData.tables[0]
+	second, err := Snapshot(context.Background(), &SqlDriver{}, metaData)
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	a, _ := json.Marshal(first)
+	b, _ := json.Marshal(second)
+	if string(a) != string(b) {
+		t.Errorf("snapshot JSON is not deterministic:\n%s\n%s", a, b)
+	}
+}
@@ -19,6 +19,7 @@ func sqliteTmpTableName(tableName string) string {
 }
 
 type SqliteDatabaseMetaData struct {
+	versionHolder
 	quoter *Quoter
 }
 
@@ -32,6 +33,10 @@ func (m *SqliteDatabaseMetaData) Dbms() string {
 	return "SQLite"
 }
 
+func (m *SqliteDatabaseMetaData) Placeholder() PlaceholderStyle {
+	return PlaceholderQuestion
+}
+
 func (m *SqliteDatabaseMetaData) DataType(str string) string {
 	switch str {
 	case Varchar:
@@ -131,16 +136,67 @@ func (m *SqliteDatabaseMetaData) GetColumns(ctx context.Context, driver Driver,
 	return list, err
 }
 
+func (m *SqliteDatabaseMetaData) GetColumnDetails(ctx context.Context, driver Driver, tableName string) ([]*Column, error) {
+	sql := "PRAGMA table_info (?)"
+	var plan *scanPlan
+	var (
+		cid       sql2.NullInt64
+		name      sql2.NullString
+		Type      sql2.NullString
+		notnull   sql2.NullInt64
+		dfltValue sql2.NullString
+	)
+	binders := columnBinders{
+		"CID":        &cid,
+		"NAME":       &name,
+		"TYPE":       &Type,
+		"NOTNULL":    &notnull,
+		"DFLT_VALUE": &dfltValue,
+	}
+	var list []*Column
+	err := doEach(ctx, driver, func(rows Rows) error {
+		var err error
+		if plan == nil {
+			if plan, err = newScanPlan(rows, binders); err != nil {
+				return err
+			}
+		}
+		if err = plan.Scan(rows); err != nil {
+			return err
+		}
+		column := &Column{
+			Name:         sqliteUnquoteIdentifier(name.String),
+			Nullable:     notnull.Int64 == 0,
+			DefaultValue: dfltValue.String,
+			Ordinal:      int(cid.Int64) + 1,
+		}
+		var length, scale int
+		column.DataType, length, scale = parseColumnType(Type.String)
+		if strings.Contains(column.DataType, "DECIMAL") || strings.Contains(column.DataType, "NUMERIC") {
+			column.NumericPrecision = length
+			column.NumericScale = scale
+		} else {
+			column.MaxLength = length
+		}
+		list = append(list, column)
+		return nil
+	}, sql, tableName)
+	return list, err
+}
+
 func (m *SqliteDatabaseMetaData) GetIndexes(ctx context.Context, driver Driver, tableName string) ([]*Index, error) {
 	sql := "PRAGMA index_list (?)"
 	var plan *scanPlan
 	var (
-		name sql2.NullString
+		name   sql2.NullString
+		unique sql2.NullInt64
 	)
 	binders := columnBinders{
-		"NAME": &name,
+		"NAME":   &name,
+		"UNIQUE": &unique,
 	}
 	var list []string
+	uniques := make(map[string]bool)
 	if err := doEach(ctx, driver, func(rows Rows) error {
 		var err error
 		if plan == nil {
@@ -152,6 +208,7 @@ func (m *SqliteDatabaseMetaData) GetIndexes(ctx context.Context, driver Driver,
 			return err
 		}
 		list = append(list, name.String)
+		uniques[name.String] = unique.Int64 == 1
 		return nil
 	}, sql, tableName); err != nil {
 		return nil, err
@@ -162,10 +219,12 @@ func (m *SqliteDatabaseMetaData) GetIndexes(ctx context.Context, driver Driver,
 		if err != nil {
 			return nil, err
 		}
-		for _, column := range columns {
+		for i, column := range columns {
 			indexes = append(indexes, &Index{
 				Name:       index,
 				ColumnName: column,
+				Unique:     uniques[index],
+				Ordinal:    i + 1,
 			})
 		}
 	}
@@ -298,6 +357,47 @@ func (m *SqliteDatabaseMetaData) GetPrimaryKeys(ctx context.Context, driver Driv
 	return primaryKeys, err
 }
 
+func (m *SqliteDatabaseMetaData) GetForeignKeys(ctx context.Context, driver Driver, tableName string) ([]*ForeignKey, error) {
+	sql := "PRAGMA foreign_key_list (?)"
+	var plan *scanPlan
+	var (
+		id    sql2.NullInt64
+		seq   sql2.NullInt64
+		table sql2.NullString
+		from  sql2.NullString
+		to    sql2.NullString
+	)
+	binders := columnBinders{
+		"ID":    &id,
+		"SEQ":   &seq,
+		"TABLE": &table,
+		"FROM":  &from,
+		"TO":    &to,
+	}
+	var list []*ForeignKey
+	err := doEach(ctx, driver, func(rows Rows) error {
+		var err error
+		if plan == nil {
+			if plan, err = newScanPlan(rows, binders); err != nil {
+				return err
+			}
+		}
+		if err = plan.Scan(rows); err != nil {
+			return err
+		}
+		// SQLite 不保存外键约束名称，使用外键编号区分
+		list = append(list, &ForeignKey{
+			Name:          fmt.Sprintf("fk_%s_%d", tableName, id.Int64),
+			ColumnName:    sqliteUnquoteIdentifier(from.String),
+			RefTableName:  sqliteUnquoteIdentifier(table.String),
+			RefColumnName: sqliteUnquoteIdentifier(to.String),
+			Ordinal:       int(seq.Int64) + 1,
+		})
+		return nil
+	}, sql, tableName)
+	return list, err
+}
+
 func (m *SqliteDatabaseMetaData) ExistsTable(ctx context.Context, driver Driver, tableName string) (bool, string, error) {
 	return ExistsTable(m.GetTables, ctx, driver, tableName)
 }
@@ -314,6 +414,10 @@ func (m *SqliteDatabaseMetaData) ExistsPrimaryKey(ctx context.Context, driver Dr
 	return ExistsPrimaryKey(m.GetTables, m.GetPrimaryKeys, ctx, driver, tableName)
 }
 
+func (m *SqliteDatabaseMetaData) Version(ctx context.Context, driver Driver) (string, error) {
+	return m.queryVersion(ctx, driver, "SELECT sqlite_version()", nil)
+}
+
 func (m *SqliteDatabaseMetaData) Quoter() *Quoter {
 	return m.quoter
 }
@@ -330,6 +434,11 @@ func NewSqliteMigratory() Migratory {
 	}
 }
 
+// SupportsTransactionalDDL Sqlite 的 DDL 可以在事务中执行并回滚
+func (m *SqliteMigratory) SupportsTransactionalDDL() bool {
+	return true
+}
+
 func (m *SqliteMigratory) CreateTable(ctx context.Context, driver Driver, tableName string, comment string, columns []*ColumnNode, _ *AttributesNode) error {
 	var builder strings.Builder
 	builder.WriteString("CREATE TABLE ")
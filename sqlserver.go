@@ -0,0 +1,527 @@
+package dbfly
+
+import (
+	"context"
+	sql2 "database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type SqlServerDatabaseMetaData struct {
+	versionHolder
+	quoter *Quoter
+}
+
+func NewSqlServerDatabaseMetaData() *SqlServerDatabaseMetaData {
+	return &SqlServerDatabaseMetaData{
+		quoter: NewQuoter('[', ']', AlwaysReserve),
+	}
+}
+
+func (m *SqlServerDatabaseMetaData) Dbms() string {
+	return "Microsoft SQL Server"
+}
+
+func (m *SqlServerDatabaseMetaData) Placeholder() PlaceholderStyle {
+	return PlaceholderAtP
+}
+
+func (m *SqlServerDatabaseMetaData) DataType(str string) string {
+	switch str {
+	case Varchar:
+		return "NVARCHAR"
+	case Char:
+		return "NCHAR"
+	case Text:
+		return "NVARCHAR(MAX)"
+	case Clob:
+		return "NVARCHAR(MAX)"
+	case Boolean:
+		return "BIT"
+	case Tinyint:
+		return "TINYINT"
+	case Smallint:
+		return "SMALLINT"
+	case Int:
+		return "INT"
+	case Bigint:
+		return "BIGINT"
+	case Decimal:
+		return "DECIMAL"
+	case Date:
+		return "DATE"
+	case Time:
+		return "TIME"
+	case Timestamp:
+		return "DATETIME2"
+	case Blob:
+		return "VARBINARY(MAX)"
+	}
+	return str
+}
+
+func (m *SqlServerDatabaseMetaData) GetTables(ctx context.Context, driver Driver) ([]*Table, error) {
+	sql := `SELECT o.name                              AS TABLE_NAME,
+       CASE o.type WHEN 'V' THEN 'VIEW' ELSE 'TABLE' END AS TABLE_TYPE,
+       CAST(ep.value AS NVARCHAR(4000))    AS TABLE_COMMENT
+FROM sys.objects o
+         LEFT JOIN sys.extended_properties ep
+                   ON ep.class = 1 AND ep.major_id = o.object_id AND ep.minor_id = 0 AND ep.name = 'MS_Description'
+WHERE o.type IN ('U', 'V')
+  AND o.schema_id = SCHEMA_ID()
+ORDER BY TABLE_TYPE, TABLE_NAME`
+	return doGetSlices[Table](ctx, driver, func(rows Rows, t *Table) error {
+		var comment sql2.NullString
+		if err := rows.Scan(&t.Name, &t.TableType, &comment); err != nil {
+			return err
+		}
+		t.Comment = comment.String
+		return nil
+	}, sql)
+}
+
+func (m *SqlServerDatabaseMetaData) GetColumns(ctx context.Context, driver Driver, tableName string) ([]string, error) {
+	sql := `SELECT c.name
+FROM sys.columns c
+         JOIN sys.objects o ON o.object_id = c.object_id
+WHERE o.schema_id = SCHEMA_ID()
+  AND o.name = ?
+ORDER BY c.column_id`
+	return doGetScalars[string](ctx, driver, sql, tableName)
+}
+
+func (m *SqlServerDatabaseMetaData) GetColumnDetails(ctx context.Context, driver Driver, tableName string) ([]*Column, error) {
+	sql := `SELECT c.name,
+       ty.name,
+       c.max_length,
+       c.precision,
+       c.scale,
+       c.is_nullable,
+       dc.definition,
+       CAST(ep.value AS NVARCHAR(4000)),
+       c.column_id
+FROM sys.columns c
+         JOIN sys.objects o ON o.object_id = c.object_id
+         JOIN sys.types ty ON ty.user_type_id = c.user_type_id
+         LEFT JOIN sys.default_constraints dc ON dc.object_id = c.default_object_id
+         LEFT JOIN sys.extended_properties ep
+                   ON ep.class = 1 AND ep.major_id = c.object_id AND ep.minor_id = c.column_id AND ep.name = 'MS_Description'
+WHERE o.schema_id = SCHEMA_ID()
+  AND o.name = ?
+ORDER BY c.column_id`
+	return doGetSlices[Column](ctx, driver, func(rows Rows, t *Column) error {
+		var (
+			maxLength    int
+			precision    int
+			scale        int
+			defaultValue sql2.NullString
+			comment      sql2.NullString
+		)
+		if err := rows.Scan(&t.Name, &t.DataType, &maxLength, &precision, &scale, &t.Nullable, &defaultValue, &comment, &t.Ordinal); err != nil {
+			return err
+		}
+		t.DataType = strings.ToUpper(t.DataType)
+		switch t.DataType {
+		case "NVARCHAR", "NCHAR", "VARCHAR", "CHAR", "VARBINARY", "BINARY":
+			if maxLength == -1 {
+				// MAX 类型没有长度，作为类型的一部分
+				t.DataType += "(MAX)"
+			} else if t.DataType == "NVARCHAR" || t.DataType == "NCHAR" {
+				// max_length 为字节数，Unicode 字符占两个字节
+				t.MaxLength = maxLength / 2
+			} else {
+				t.MaxLength = maxLength
+			}
+		case "DECIMAL", "NUMERIC":
+			t.NumericPrecision = precision
+			t.NumericScale = scale
+		}
+		t.DefaultValue = sqlServerUnwrapDefault(defaultValue.String)
+		t.Comment = comment.String
+		return nil
+	}, sql, tableName)
+}
+
+// sqlServerUnwrapDefault 去除默认值定义外层的括号和 Unicode 字符串前缀，如 ((0))、(N'abc')
+func sqlServerUnwrapDefault(definition string) string {
+	definition = strings.TrimSpace(definition)
+	for len(definition) >= 2 && definition[0] == '(' && definition[len(definition)-1] == ')' {
+		// 仅在首尾括号相互匹配时去除，避免 (a)+(b) 被错误处理
+		depth := 0
+		matched := true
+		for i := 0; i < len(definition)-1; i++ {
+			if definition[i] == '(' {
+				depth++
+			} else if definition[i] == ')' {
+				depth--
+			}
+			if depth == 0 {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			break
+		}
+		definition = strings.TrimSpace(definition[1 : len(definition)-1])
+	}
+	if strings.HasPrefix(definition, "N'") {
+		definition = definition[1:]
+	}
+	return definition
+}
+
+func (m *SqlServerDatabaseMetaData) GetIndexes(ctx context.Context, driver Driver, tableName string) ([]*Index, error) {
+	sql := `SELECT i.name, c.name, i.is_unique, ic.key_ordinal
+FROM sys.indexes i
+         JOIN sys.objects o ON o.object_id = i.object_id
+         JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+         JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+WHERE o.schema_id = SCHEMA_ID()
+  AND o.name = ?
+  AND i.name IS NOT NULL
+  AND ic.is_included_column = 0
+ORDER BY i.name, ic.key_ordinal`
+	return doGetSlices[Index](ctx, driver, func(rows Rows, t *Index) error {
+		return rows.Scan(&t.Name, &t.ColumnName, &t.Unique, &t.Ordinal)
+	}, sql, tableName)
+}
+
+func (m *SqlServerDatabaseMetaData) GetPrimaryKeys(ctx context.Context, driver Driver, tableName string) ([]*PrimaryKey, error) {
+	sql := `SELECT c.name, i.name
+FROM sys.indexes i
+         JOIN sys.objects o ON o.object_id = i.object_id
+         JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+         JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+WHERE o.schema_id = SCHEMA_ID()
+  AND o.name = ?
+  AND i.is_primary_key = 1
+ORDER BY ic.key_ordinal`
+	return doGetSlices[PrimaryKey](ctx, driver, func(rows Rows, t *PrimaryKey) error {
+		return rows.Scan(&t.ColumnName, &t.Name)
+	}, sql, tableName)
+}
+
+func (m *SqlServerDatabaseMetaData) GetForeignKeys(ctx context.Context, driver Driver, tableName string) ([]*ForeignKey, error) {
+	sql := `SELECT fk.name, c.name, rt.name, rc.name, fkc.constraint_column_id
+FROM sys.foreign_keys fk
+         JOIN sys.tables t ON t.object_id = fk.parent_object_id
+         JOIN sys.foreign_key_columns fkc ON fkc.constraint_object_id = fk.object_id
+         JOIN sys.columns c ON c.object_id = fkc.parent_object_id AND c.column_id = fkc.parent_column_id
+         JOIN sys.tables rt ON rt.object_id = fkc.referenced_object_id
+         JOIN sys.columns rc ON rc.object_id = fkc.referenced_object_id AND rc.column_id = fkc.referenced_column_id
+WHERE t.schema_id = SCHEMA_ID()
+  AND t.name = ?
+ORDER BY fk.name, fkc.constraint_column_id`
+	return doGetSlices[ForeignKey](ctx, driver, func(rows Rows, t *ForeignKey) error {
+		return rows.Scan(&t.Name, &t.ColumnName, &t.RefTableName, &t.RefColumnName, &t.Ordinal)
+	}, sql, tableName)
+}
+
+func (m *SqlServerDatabaseMetaData) ExistsTable(ctx context.Context, driver Driver, tableName string) (bool, string, error) {
+	return ExistsTable(m.GetTables, ctx, driver, tableName)
+}
+
+func (m *SqlServerDatabaseMetaData) ExistsColumn(ctx context.Context, driver Driver, tableName, columnName string) (bool, string, string, error) {
+	return ExistsColumn(m.GetTables, m.GetColumns, ctx, driver, tableName, columnName)
+}
+
+func (m *SqlServerDatabaseMetaData) ExistsIndex(ctx context.Context, driver Driver, tableName, indexName string) (bool, string, string, error) {
+	return ExistsIndex(m.GetTables, m.GetIndexes, ctx, driver, tableName, indexName)
+}
+
+func (m *SqlServerDatabaseMetaData) ExistsPrimaryKey(ctx context.Context, driver Driver, tableName string) (bool, string, error) {
+	return ExistsPrimaryKey(m.GetTables, m.GetPrimaryKeys, ctx, driver, tableName)
+}
+
+func (m *SqlServerDatabaseMetaData) Version(ctx context.Context, driver Driver) (string, error) {
+	return m.queryVersion(ctx, driver, "SELECT CAST(SERVERPROPERTY('ProductVersion') AS NVARCHAR(128))", nil)
+}
+
+func (m *SqlServerDatabaseMetaData) Quoter() *Quoter {
+	return m.quoter
+}
+
+// SqlServerMigratory SQL Server迁移实现
+type SqlServerMigratory struct {
+	DefaultMigratory
+}
+
+// NewSqlServerMigratory 创建一个SQL Server迁移实现实例
+func NewSqlServerMigratory() Migratory {
+	return &SqlServerMigratory{
+		DefaultMigratory: NewDefaultMigratory("sqlserver", NewSqlServerDatabaseMetaData()),
+	}
+}
+
+// SupportsTransactionalDDL SQL Server 的 DDL 可以在事务中执行并回滚
+func (m *SqlServerMigratory) SupportsTransactionalDDL() bool {
+	return true
+}
+
+// SavepointSQL SQL Server 使用 SAVE TRANSACTION 创建保存点
+func (m *SqlServerMigratory) SavepointSQL(name string) (string, string, error) {
+	return "SAVE TRANSACTION " + name, "ROLLBACK TRANSACTION " + name, nil
+}
+
+func (m *SqlServerMigratory) CreateTable(ctx context.Context, driver Driver, tableName string, comment string, columns []*ColumnNode, _ *AttributesNode) error {
+	var builder strings.Builder
+	builder.WriteString("CREATE TABLE ")
+	m.QuoteTo(&builder, tableName)
+	builder.WriteString("\n(\n")
+	size := len(columns)
+	var pkColumn *ColumnNode
+	for index, column := range columns {
+		builder.WriteString("  ")
+		if pk := m.CreateTableColumn(column, &builder); pk {
+			if pkColumn != nil {
+				return New("multiple primary key columns are not allowed in table %s", tableName)
+			}
+			if column.KeyName == "" {
+				builder.WriteString(" PRIMARY KEY")
+			}
+			pkColumn = column
+		}
+		if index < size-1 {
+			builder.WriteString(",\n")
+		}
+	}
+	if pkColumn != nil && pkColumn.KeyName != "" {
+		builder.WriteString(",\n  CONSTRAINT ")
+		m.QuoteTo(&builder, pkColumn.KeyName)
+		builder.WriteString(" PRIMARY KEY (")
+		m.QuoteTo(&builder, pkColumn.ColumnName)
+		builder.WriteString(")")
+	}
+	builder.WriteString("\n)")
+	if _, err := driver.Execute(ctx, builder.String()); err != nil {
+		return err
+	}
+
+	if comment != "" {
+		if err := m.setComment(ctx, driver, tableName, "", comment); err != nil {
+			return err
+		}
+	}
+	for _, column := range columns {
+		if column.Comment != "" {
+			if err := m.setComment(ctx, driver, tableName, column.ColumnName, column.Comment); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *SqlServerMigratory) DropIndex(ctx context.Context, driver Driver, tableName, indexName string, _ *AttributesNode) error {
+	_, err := driver.Execute(ctx, fmt.Sprintf("DROP INDEX %s ON %s", m.Quote(indexName), m.Quote(tableName)))
+	return err
+}
+
+func (m *SqlServerMigratory) AddColumn(ctx context.Context, driver Driver, tableName string, columns []*AddColumnColumnNode, _ *AttributesNode) error {
+	for _, column := range columns {
+		var builder strings.Builder
+		builder.WriteString("ALTER TABLE ")
+		m.QuoteTo(&builder, tableName)
+		builder.WriteString(" ADD ")
+		m.CreateAddTableColumn(column, &builder)
+		if _, err := driver.Execute(ctx, builder.String()); err != nil {
+			return err
+		}
+		if column.Comment != "" {
+			if err := m.setComment(ctx, driver, tableName, column.ColumnName, column.Comment); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *SqlServerMigratory) RenameColumn(ctx context.Context, driver Driver, tableName string, columnName string, newColumnName string, _ *AttributesNode) error {
+	sql := fmt.Sprintf("EXEC sp_rename N'%s.%s', N'%s', N'COLUMN'",
+		ReplaceComment(m.Quote(tableName)), ReplaceComment(m.Quote(columnName)), ReplaceComment(newColumnName))
+	_, err := driver.Execute(ctx, sql)
+	return err
+}
+
+// AlterColumn SQL Server 的 ALTER COLUMN 不支持默认值，需要先删除已有的默认值约束，修改列后重新添加
+func (m *SqlServerMigratory) AlterColumn(ctx context.Context, driver Driver, tableName string, columnName string, column *AlterColumnColumnNode, _ *AttributesNode) error {
+	if err := m.dropDefaultConstraints(ctx, driver, tableName, columnName); err != nil {
+		return err
+	}
+
+	dbmsNode := selectColumnDbms(m.MetaData(), column.ColumnDbms)
+	var dataType, defaultValue string
+	if dbmsNode != nil {
+		dataType = dbmsNode.DataType
+		if dbmsNode.DefaultOriginValue != "" {
+			defaultValue = dbmsNode.DefaultOriginValue
+		} else if dbmsNode.DefaultValue != "" {
+			defaultValue = fmt.Sprintf("N'%s'", strings.ReplaceAll(dbmsNode.DefaultValue, "'", "''"))
+		}
+	} else {
+		dataType = columnType(column.DataType, m.DataType(column.DataType), column.MaxLength, column.NumericScale)
+		if column.DefaultOriginValue != "" {
+			defaultValue = column.DefaultOriginValue
+		} else if column.DefaultValue != "" {
+			defaultValue = fmt.Sprintf("N'%s'", strings.ReplaceAll(column.DefaultValue, "'", "''"))
+		}
+	}
+
+	nullable := "NULL"
+	if column.Notnull {
+		nullable = "NOT NULL"
+	}
+	sql := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s %s", m.Quote(tableName), m.Quote(columnName), dataType, nullable)
+	if _, err := driver.Execute(ctx, sql); err != nil {
+		return err
+	}
+	if defaultValue != "" {
+		sql = fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s DEFAULT %s FOR %s",
+			m.Quote(tableName), m.Quote(fmt.Sprintf("DF_%s_%s", tableName, columnName)), defaultValue, m.Quote(columnName))
+		if _, err := driver.Execute(ctx, sql); err != nil {
+			return err
+		}
+	}
+	if column.Unique {
+		// 列上已有唯一约束时不再重复添加
+		exists, err := m.existsUniqueConstraint(ctx, driver, tableName, columnName)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			sql = fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s UNIQUE (%s)",
+				m.Quote(tableName), m.Quote(fmt.Sprintf("UQ_%s_%s", tableName, columnName)), m.Quote(columnName))
+			if _, err = driver.Execute(ctx, sql); err != nil {
+				return err
+			}
+		}
+	}
+	if column.Comment != "" {
+		return m.setComment(ctx, driver, tableName, columnName, column.Comment)
+	}
+	return nil
+}
+
+// existsUniqueConstraint 列上是否已存在仅包含该列的唯一约束
+func (m *SqlServerMigratory) existsUniqueConstraint(ctx context.Context, driver Driver, tableName string, columnName string) (bool, error) {
+	sql := `SELECT COUNT(*)
+FROM sys.key_constraints kc
+         JOIN sys.tables t ON t.object_id = kc.parent_object_id
+         JOIN sys.index_columns ic ON ic.object_id = kc.parent_object_id AND ic.index_id = kc.unique_index_id
+         JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+WHERE kc.type = 'UQ'
+  AND t.schema_id = SCHEMA_ID()
+  AND t.name = ?
+  AND c.name = ?
+  AND (SELECT COUNT(*)
+       FROM sys.index_columns x
+       WHERE x.object_id = kc.parent_object_id
+         AND x.index_id = kc.unique_index_id) = 1`
+	count, err := doGetScalar[int](ctx, driver, sql, tableName, columnName)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// DropColumn 删除列前需要先删除列上的默认值约束
+func (m *SqlServerMigratory) DropColumn(ctx context.Context, driver Driver, tableName string, columnName string, attributes *AttributesNode) error {
+	if err := m.dropDefaultConstraints(ctx, driver, tableName, columnName); err != nil {
+		return err
+	}
+	return m.DefaultMigratory.DropColumn(ctx, driver, tableName, columnName, attributes)
+}
+
+// dropDefaultConstraints 删除列上的默认值约束（包括数据库自动命名的约束）
+func (m *SqlServerMigratory) dropDefaultConstraints(ctx context.Context, driver Driver, tableName string, columnName string) error {
+	sql := `SELECT dc.name
+FROM sys.default_constraints dc
+         JOIN sys.tables t ON t.object_id = dc.parent_object_id
+         JOIN sys.columns c ON c.object_id = dc.parent_object_id AND c.column_id = dc.parent_column_id
+WHERE t.schema_id = SCHEMA_ID()
+  AND t.name = ?
+  AND c.name = ?`
+	names, err := doGetScalars[string](ctx, driver, sql, tableName, columnName)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if _, err = driver.Execute(ctx, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", m.Quote(tableName), m.Quote(name))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *SqlServerMigratory) DropPrimaryKey(ctx context.Context, driver Driver, tableName string, _ *AttributesNode) error {
+	return dropPrimaryKeyConstraint(ctx, &m.DefaultMigratory, driver, tableName)
+}
+
+func (m *SqlServerMigratory) RenameTable(ctx context.Context, driver Driver, tableName string, newTableName string, _ *AttributesNode) error {
+	sql := fmt.Sprintf("EXEC sp_rename N'%s', N'%s'", ReplaceComment(m.Quote(tableName)), ReplaceComment(newTableName))
+	_, err := driver.Execute(ctx, sql)
+	return err
+}
+
+func (m *SqlServerMigratory) AlterTableComment(ctx context.Context, driver Driver, tableName string, comment string, _ *AttributesNode) error {
+	return m.setComment(ctx, driver, tableName, "", comment)
+}
+
+// setComment 通过扩展属性 MS_Description 设置表或列说明，columnName 为空时设置表说明
+func (m *SqlServerMigratory) setComment(ctx context.Context, driver Driver, tableName string, columnName string, comment string) error {
+	level2 := ""
+	minorId := "0"
+	if columnName != "" {
+		level2 = fmt.Sprintf(", @level2type = N'COLUMN', @level2name = N'%s'", ReplaceComment(columnName))
+		minorId = fmt.Sprintf("COLUMNPROPERTY(OBJECT_ID(@table), N'%s', 'ColumnId')", ReplaceComment(columnName))
+	}
+	params := fmt.Sprintf("@name = N'MS_Description', @value = N'%s', @level0type = N'SCHEMA', @level0name = @schema, @level1type = N'TABLE', @level1name = N'%s'%s",
+		ReplaceComment(comment), ReplaceComment(tableName), level2)
+	sql := fmt.Sprintf(`DECLARE @schema SYSNAME = SCHEMA_NAME();
+DECLARE @table NVARCHAR(776) = QUOTENAME(@schema) + N'.' + QUOTENAME(N'%s');
+IF EXISTS (SELECT 1 FROM sys.extended_properties WHERE class = 1 AND name = N'MS_Description' AND major_id = OBJECT_ID(@table) AND minor_id = %s)
+    EXEC sp_updateextendedproperty %s
+ELSE
+    EXEC sp_addextendedproperty %s`, ReplaceComment(tableName), minorId, params, params)
+	_, err := driver.Execute(ctx, sql)
+	return err
+}
+
+// sqlServerBatchSeparator 匹配单独成行的 GO 批处理分隔符，可带重复次数
+var sqlServerBatchSeparator = regexp.MustCompile(`(?i)^\s*GO(\s+\d+)?\s*;?\s*$`)
+
+// SplitSQLStatements 按 GO 分隔符拆分批处理，每个批处理作为一个执行单元
+func (m *SqlServerMigratory) SplitSQLStatements(script string) []string {
+	return splitSqlServerBatches(script)
+}
+
+func splitSqlServerBatches(script string) []string {
+	var batches []string
+	var current strings.Builder
+	inComment := false
+	addBatch := func() {
+		batch := strings.TrimSpace(current.String())
+		if batch != "" {
+			batches = append(batches, batch)
+		}
+		current.Reset()
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(script, "\r\n", "\n"), "\n") {
+		if !inComment && sqlServerBatchSeparator.MatchString(line) {
+			addBatch()
+			continue
+		}
+		// 跟踪块注释，注释中的 GO 不作为分隔符
+		if strings.LastIndex(line, "/*") > strings.LastIndex(line, "*/") {
+			inComment = true
+		} else if strings.Contains(line, "*/") {
+			inComment = false
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+	}
+	addBatch()
+	return batches
+}
@@ -0,0 +1,78 @@
+package dbfly
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitSqlServerBatches(t *testing.T) {
+	script := `CREATE TABLE t (id INT);
+INSERT INTO t VALUES (1);
+GO
+CREATE PROCEDURE p AS
+BEGIN
+    SELECT 1;
+END
+go 2
+/*
+GO
+*/
+SELECT 2`
+	want := []string{
+		"CREATE TABLE t (id INT);\nINSERT INTO t VALUES (1);",
+		"CREATE PROCEDURE p AS\nBEGIN\n    SELECT 1;\nEND",
+		"/*\nGO\n*/\nSELECT 2",
+	}
+	if got := splitSqlServerBatches(script); !reflect.DeepEqual(got, want) {
+		t.Errorf("splitSqlServerBatches() = %q, want %q", got, want)
+	}
+}
+
+func TestSqlServerUnwrapDefault(t *testing.T) {
+	tests := map[string]string{
+		"((0))":       "0",
+		"(N'abc')":    "'abc'",
+		"(getdate())": "getdate()",
+		"((1)+(2))":   "(1)+(2)",
+		"('it''s')":   "'it''s'",
+		"":            "",
+	}
+	for input, want := range tests {
+		if got := sqlServerUnwrapDefault(input); got != want {
+			t.Errorf("sqlServerUnwrapDefault(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+// sqlServerDriver 查询唯一约束时返回指定数量，其他查询返回空
+type sqlServerDriver struct {
+	recordDriver
+	uniqueCount int
+}
+
+func (d *sqlServerDriver) Query(_ context.Context, sql string, _ ...interface{}) (Rows, error) {
+	if strings.Contains(sql, "sys.key_constraints") {
+		return &valueRows{values: [][]interface{}{{d.uniqueCount}}}, nil
+	}
+	return &valueRows{}, nil
+}
+
+func TestSqlServerMigratory_AlterColumnUnique(t *testing.T) {
+	m := NewSqlServerMigratory()
+	column := &AlterColumnColumnNode{DataType: Varchar, MaxLength: 100, Unique: true}
+	for _, tt := range []struct {
+		uniqueCount int
+		want        bool
+	}{{0, true}, {1, false}} {
+		driver := &sqlServerDriver{uniqueCount: tt.uniqueCount}
+		if err := m.AlterColumn(context.Background(), driver, "users", "email", column, nil); err != nil {
+			t.Fatal(err)
+		}
+		added := strings.Contains(strings.Join(driver.sqls, "\n"), "ADD CONSTRAINT [UQ_users_email] UNIQUE")
+		if added != tt.want {
+			t.Errorf("unique constraints %d: added = %v, want %v: %q", tt.uniqueCount, added, tt.want, driver.sqls)
+		}
+	}
+}
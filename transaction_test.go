@@ -0,0 +1,283 @@
+package dbfly
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// txRecordDriver 记录事务边界，事务中执行的 SQL 以 tx: 为前缀，执行包含 FAIL 的 SQL 时返回错误
+type txRecordDriver struct {
+	recordDriver
+}
+
+func (d *txRecordDriver) Execute(_ context.Context, sql string, args ...interface{}) (sql.Result, error) {
+	d.sqls = append(d.sqls, sql)
+	d.args = append(d.args, args)
+	if strings.Contains(sql, "FAIL") {
+		return nil, New("syntax error near FAIL")
+	}
+	return nil, nil
+}
+
+func (d *txRecordDriver) Query(_ context.Context, sql string, _ ...interface{}) (Rows, error) {
+	if strings.Contains(sql, defaultSchemaVersionTableName) {
+		return &valueRows{values: [][]interface{}{{changeLogSchema.version}}}, nil
+	}
+	return &valueRows{}, nil
+}
+
+func (d *txRecordDriver) BeginTx(context.Context) (Tx, error) {
+	d.sqls = append(d.sqls, "BEGIN")
+	d.args = append(d.args, nil)
+	return &recordTx{driver: d}, nil
+}
+
+type recordTx struct {
+	driver *txRecordDriver
+}
+
+func (t *recordTx) Execute(ctx context.Context, sql string, args ...interface{}) (sql.Result, error) {
+	return t.driver.Execute(ctx, "tx: "+sql, args...)
+}
+
+func (t *recordTx) Query(ctx context.Context, sql string, args ...interface{}) (Rows, error) {
+	return t.driver.Query(ctx, sql, args...)
+}
+
+func (t *recordTx) Commit() error {
+	t.driver.sqls = append(t.driver.sqls, "COMMIT")
+	t.driver.args = append(t.driver.args, nil)
+	return nil
+}
+
+func (t *recordTx) Rollback() error {
+	t.driver.sqls = append(t.driver.sqls, "ROLLBACK")
+	t.driver.args = append(t.driver.args, nil)
+	return nil
+}
+
+// transactionalMigratory 支持事务 DDL 的迁移器
+type transactionalMigratory struct {
+	DefaultMigratory
+}
+
+func (m *transactionalMigratory) SupportsTransactionalDDL() bool {
+	return true
+}
+
+func newTxFly(migratory Migratory, driver Driver, changelog string) *Dbfly {
+	source := NewFSSource(fstest.MapFS{"dbfly.xml": {Data: []byte(changelog)}})
+	return NewDbfly(migratory, driver, source, WithLocker(NewMutexLocker()))
+}
+
+func txMetaData() *mockMetaData {
+	return &mockMetaData{dbms: "Mock", tables: []*Table{
+		{Name: defaultChangeLogTableName, TableType: "TABLE"},
+		{Name: defaultSchemaVersionTableName, TableType: "TABLE"},
+	}}
+}
+
+func TestMigrate_RunInTransaction(t *testing.T) {
+	changelog := `<dbfly>
+    <changeSet id="a" runInTransaction="auto">
+        <sqlInline><default>CREATE TABLE a(id INT)</default></sqlInline>
+        <transaction><sqlInline><default>INSERT INTO a VALUES(1)</default></sqlInline></transaction>
+    </changeSet>
+    <changeSet id="b" runInTransaction="true" onFail="SKIP">
+        <sqlInline><default>CREATE TABLE b(id INT)</default></sqlInline>
+        <sqlInline><default>FAIL</default></sqlInline>
+    </changeSet>
+    <changeSet id="c"><sqlInline><default>CREATE TABLE c(id INT)</default></sqlInline></changeSet>
+</dbfly>`
+	driver := &txRecordDriver{}
+	migratory := &transactionalMigratory{DefaultMigratory: NewDefaultMigratory("mock", txMetaData())}
+	if err := newTxFly(migratory, driver, changelog).Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	var begins, commits, rollbacks int
+	for _, sql := range driver.sqls {
+		switch sql {
+		case "BEGIN":
+			begins++
+		case "COMMIT":
+			commits++
+		case "ROLLBACK":
+			rollbacks++
+		}
+	}
+	if begins != 2 || commits != 1 || rollbacks != 1 {
+		t.Errorf("begin %d commit %d rollback %d, want 2 1 1: %q", begins, commits, rollbacks, driver.sqls)
+	}
+
+	// 变更集 a 的操作、内层事务及变更记录均在同一事务中
+	joined := strings.Join(driver.sqls, "\n")
+	for _, want := range []string{`tx: INSERT INTO "DBFLY_CHANGE_LOG"`, "tx: CREATE TABLE a(id INT)", "tx: INSERT INTO a VALUES(1)", "tx: CREATE TABLE b(id INT)", "CREATE TABLE c(id INT)"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("missing %q in %q", want, driver.sqls)
+		}
+	}
+	if strings.Contains(joined, "tx: CREATE TABLE c") {
+		t.Error("changeSet c should not run in transaction")
+	}
+
+	// 回滚后在事务外记录失败
+	var recorded bool
+	for i, sql := range driver.sqls {
+		if strings.HasPrefix(sql, "UPDATE") {
+			for _, arg := range driver.args[i] {
+				if arg == string(ExecTypeFailed) {
+					recorded = true
+				}
+			}
+		}
+	}
+	if !recorded {
+		t.Errorf("failure of changeSet b not recorded outside transaction: %q", driver.sqls)
+	}
+}
+
+func TestMigrate_RunInTransactionUnsupported(t *testing.T) {
+	changelog := `<dbfly>
+    <changeSet id="a" runInTransaction="auto"><sqlInline><default>CREATE TABLE a(id INT)</default></sqlInline></changeSet>
+</dbfly>`
+	driver := &txRecordDriver{}
+	migratory := NewDefaultMigratory("mock", txMetaData())
+	if err := newTxFly(&migratory, driver, changelog).Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, sql := range driver.sqls {
+		if sql == "BEGIN" {
+			t.Fatalf("auto should not use transaction without transactional DDL: %q", driver.sqls)
+		}
+	}
+
+	changelog = `<dbfly><changeSet id="a" runInTransaction="yes"><sqlInline><default>SELECT 1</default></sqlInline></changeSet></dbfly>`
+	if err := newTxFly(&migratory, driver, changelog).Migrate(); err == nil || !strings.Contains(err.Error(), "runInTransaction") {
+		t.Errorf("invalid runInTransaction error = %v", err)
+	}
+}
+
+func TestSupportsTransactionalDDL(t *testing.T) {
+	tests := []struct {
+		migratory Migratory
+		want      bool
+	}{
+		{NewPostgresMigratory(), true},
+		{NewVastbaseMigratory(), true},
+		{NewOpenGaussMigratory(), true},
+		{NewSqliteMigratory(), true},
+		{NewSqlServerMigratory(), true},
+		{NewMysqlMigratory(), false},
+		{NewOracleMigratory(), false},
+		{NewClickHouseMigratory(), false},
+	}
+	for _, tt := range tests {
+		if got := tt.migratory.SupportsTransactionalDDL(); got != tt.want {
+			t.Errorf("%s SupportsTransactionalDDL() = %v, want %v", tt.migratory.Name(), got, tt.want)
+		}
+	}
+}
+
+func TestTransaction_DDL(t *testing.T) {
+	changelog := `<dbfly>
+    <changeSet id="a">
+        <transaction>
+            <dropTable tableName="t"/>
+            <sqlInline><default>INSERT INTO a VALUES(1)</default></sqlInline>
+        </transaction>
+    </changeSet>
+</dbfly>`
+	driver := &txRecordDriver{}
+	migratory := NewDefaultMigratory("mock", txMetaData())
+	if err := newTxFly(&migratory, driver, changelog).Migrate(); err == nil || !strings.Contains(err.Error(), "transactional DDL") {
+		t.Errorf("DDL in transaction error = %v", err)
+	}
+
+	driver = &txRecordDriver{}
+	transactional := &transactionalMigratory{DefaultMigratory: NewDefaultMigratory("mock", txMetaData())}
+	if err := newTxFly(transactional, driver, changelog).Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	joined := strings.Join(driver.sqls, "\n")
+	if !strings.Contains(joined, "tx: DROP TABLE") || !strings.Contains(joined, "tx: INSERT INTO a VALUES(1)") {
+		t.Errorf("DDL not executed in transaction: %q", driver.sqls)
+	}
+}
+
+func TestTransaction_Savepoint(t *testing.T) {
+	changelog := `<dbfly>
+    <changeSet id="a">
+        <transaction>
+            <sqlInline><default>INSERT INTO a VALUES(1)</default></sqlInline>
+            <savepoint name="sp1" onFail="SKIP">
+                <sqlInline><default>INSERT INTO a VALUES(2)</default></sqlInline>
+                <savepoint><sqlInline><default>FAIL</default></sqlInline></savepoint>
+            </savepoint>
+            <savepoint name="sp2"><sqlInline><default>INSERT INTO a VALUES(3)</default></sqlInline></savepoint>
+        </transaction>
+    </changeSet>
+</dbfly>`
+	driver := &txRecordDriver{}
+	migratory := NewDefaultMigratory("mock", txMetaData())
+	if err := newTxFly(&migratory, driver, changelog).Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 内层保存点失败后回滚到内层保存点，sp1 再回滚到 sp1 并继续执行
+	var got []string
+	for _, sql := range driver.sqls {
+		if strings.Contains(sql, "SAVEPOINT") || sql == "BEGIN" || sql == "COMMIT" || sql == "ROLLBACK" {
+			got = append(got, sql)
+		}
+	}
+	if len(got) != 7 || got[0] != "BEGIN" || got[1] != "tx: SAVEPOINT sp1" || !strings.HasPrefix(got[2], "tx: SAVEPOINT dbfly_sp_") ||
+		got[3] != "tx: ROLLBACK TO SAVEPOINT "+strings.TrimPrefix(got[2], "tx: SAVEPOINT ") ||
+		got[4] != "tx: ROLLBACK TO SAVEPOINT sp1" || got[5] != "tx: SAVEPOINT sp2" || got[6] != "COMMIT" {
+		t.Errorf("unexpected transaction control: %q", got)
+	}
+
+	changelog = `<dbfly><changeSet id="a"><savepoint><sqlInline><default>SELECT 1</default></sqlInline></savepoint></changeSet></dbfly>`
+	if err := newTxFly(&migratory, &txRecordDriver{}, changelog).Migrate(); err == nil || !strings.Contains(err.Error(), "inside a transaction") {
+		t.Errorf("savepoint outside transaction error = %v", err)
+	}
+
+	changelog = `<dbfly><changeSet id="a"><transaction><savepoint name="sp-1"/></transaction></changeSet></dbfly>`
+	if err := newTxFly(&migratory, &txRecordDriver{}, changelog).Migrate(); err == nil || !strings.Contains(err.Error(), "savepoint name") {
+		t.Errorf("invalid savepoint name error = %v", err)
+	}
+}
+
+func TestSavepointSQL(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		migratory           Migratory
+		savepoint, rollback string
+	}{
+		{NewPostgresMigratory(), "tx: SAVEPOINT sp", "tx: ROLLBACK TO SAVEPOINT sp"},
+		{NewOracleMigratory(), "tx: SAVEPOINT sp", "tx: ROLLBACK TO SAVEPOINT sp"},
+		{NewSqlServerMigratory(), "tx: SAVE TRANSACTION sp", "tx: ROLLBACK TRANSACTION sp"},
+	}
+	for _, tt := range tests {
+		driver := &txRecordDriver{}
+		tx, err := newSavepoint(ctx, tt.migratory, &recordTx{driver: driver}, "sp")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = tx.Rollback(); err != nil {
+			t.Fatal(err)
+		}
+		if len(driver.sqls) != 2 || driver.sqls[0] != tt.savepoint || driver.sqls[1] != tt.rollback {
+			t.Errorf("%s savepoint sqls = %q", tt.migratory.Name(), driver.sqls)
+		}
+	}
+
+	driver := &txRecordDriver{}
+	if _, err := newSavepoint(ctx, NewClickHouseMigratory(), &recordTx{driver: driver}, "sp"); !errors.Is(err, ErrSavepointUnsupported) || len(driver.sqls) != 0 {
+		t.Errorf("clickhouse savepoint error = %v, sqls = %q", err, driver.sqls)
+	}
+}